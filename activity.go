@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -92,6 +93,11 @@ func (act *Activity) Error() error {
 //
 // See example: examples/activity/recent.go
 func (act *Activity) Next() bool {
+	return act.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (act *Activity) NextCtx(ctx context.Context) bool {
 	if act.err != nil {
 		return false
 	}
@@ -112,6 +118,7 @@ func (act *Activity) Next() bool {
 			Endpoint: urlActivityRecent,
 			Query:    query,
 			IsPost:   false,
+			Context:  ctx,
 		},
 	)
 	if err == nil {
@@ -1,17 +1,28 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/UliSotschok/goinsta/utilities"
 )
 
+// maxChallengeSteps is the max number of steps Challenge.Resolve goes through,
+//   before it gives up.
+const maxChallengeSteps = 10
+
 type ChallengeStepData struct {
 	Choice           string      `json:"choice"`
 	FbAccessToken    string      `json:"fb_access_token"`
 	BigBlueToken     string      `json:"big_blue_token"`
 	GoogleOauthToken string      `json:"google_oauth_token"`
 	Email            string      `json:"email"`
+	PhoneNumber      string      `json:"phone_number"`
 	SecurityCode     string      `json:"security_code"`
 	ResendDelay      interface{} `json:"resend_delay"`
 	ContactPoint     string      `json:"contact_point"`
@@ -34,6 +45,14 @@ type Challenge struct {
 	NativeFlow        bool              `json:"native_flow"`
 	URL               string            `json:"url"`
 
+	// StepName and StepData describe the current step of the challenge. They
+	//   are copied from Context, if Instagram only sent them there.
+	StepName    string            `json:"step_name"`
+	StepData    ChallengeStepData `json:"step_data"`
+	NonceCode   string            `json:"nonce_code"`
+	Action      string            `json:"action"`
+	BloksAction string            `json:"bloks_action"`
+
 	TwoFactorRequired bool
 	TwoFactorInfo     TwoFactorInfo
 }
@@ -48,6 +67,20 @@ type ChallengeContext struct {
 	UserID      int64             `json:"user_id"`
 }
 
+// UnmarshalJSON decodes the challenge context, which is sometimes sent as
+//   JSON encoded string instead of an object.
+func (c *ChallengeContext) UnmarshalJSON(b []byte) error {
+	type context ChallengeContext
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = []byte(s)
+	}
+	return json.Unmarshal(b, (*context)(c))
+}
+
 type TwoFactorInfo struct {
 	insta *Instagram
 
@@ -74,6 +107,46 @@ type phoneVerificationSettings struct {
 	RobocallCountDownSec int  `json:"robocall_count_down_time_sec"`
 }
 
+// ChallengeResolver supplies the input needed to solve a challenge, e.g. a
+//   security code read from an email inbox or received by an SMS gateway.
+//
+// All methods get the challenge, of which StepName and StepData describe the
+//   current step, e.g. the email address the code has been sent to.
+type ChallengeResolver interface {
+	// SelectMethod returns how the security code should be sent, as offered
+	//   by Instagram in StepData ("0" for phone, "1" for email). If empty,
+	//   the method preselected by Instagram is used.
+	SelectMethod(ctx context.Context, c *Challenge) (string, error)
+	// SecurityCode returns the security code Instagram has sent
+	SecurityCode(ctx context.Context, c *Challenge) (string, error)
+	// PhoneNumber returns the phone number to verify the account with
+	PhoneNumber(ctx context.Context, c *Challenge) (string, error)
+	// NewPassword returns a new password, if Instagram requires to change it
+	NewPassword(ctx context.Context, c *Challenge) (string, error)
+}
+
+// ChallengeManualError is returned if a challenge step can not be solved
+//   through the API, e.g. a captcha or an escalation, which has to be solved
+//   in the app or the browser at URL. It matches ErrChallengeRequired with
+//   errors.Is.
+type ChallengeManualError struct {
+	StepName string
+	URL      string
+}
+
+func (e ChallengeManualError) Error() string {
+	step := e.StepName
+	if step == "" {
+		step = "bloks"
+	}
+	return fmt.Sprintf("Challenge step %s can not be solved automatically, please solve it at %s", step, e.URL)
+}
+
+// Is allows to compare the error with ErrChallengeRequired using errors.Is
+func (e ChallengeManualError) Is(target error) bool {
+	return target == ErrChallengeRequired
+}
+
 type challengeResp struct {
 	*Challenge
 }
@@ -84,87 +157,197 @@ func newChallenge(insta *Instagram) *Challenge {
 	}
 }
 
-// updateState updates current data from challenge url
-func (c *Challenge) updateState() error {
+// SetChallengeResolver sets the resolver used to solve challenges during
+//   login automatically. Pass nil to disable it, which is the default.
+func (insta *Instagram) SetChallengeResolver(r ChallengeResolver) {
+	insta.challengeResolver = r
+}
+
+// trackChallenge remembers the challenge of a ChallengeError, so that it can
+//   be solved with Instagram.Challenge.Resolve.
+func (insta *Instagram) trackChallenge(err error) {
+	var cerr ChallengeError
+	if !errors.As(err, &cerr) || cerr.Challenge.APIPath == "" {
+		return
+	}
+	c := newChallenge(insta)
+	c.ApiPath = cerr.Challenge.APIPath
+	c.URL = cerr.Challenge.URL
+	c.Lock = cerr.Challenge.Lock
+	c.Logout = cerr.Challenge.Logout
+	c.NativeFlow = cerr.Challenge.NativeFlow
+	c.HideWebviewHeader = cerr.Challenge.HideWebviewHeader
+	insta.Challenge = c
+}
+
+// Pending reports whether the challenge still needs to be solved
+func (c *Challenge) Pending() bool {
+	return c.insta.challengeURL != "" || c.ApiPath != ""
+}
+
+// Resolve solves the challenge step by step, asking r for the input needed.
+//
+// Supported steps are select_verify_method, delta_login_review, verify_code,
+//   verify_email, verify_sms, submit_phone and change_password. Captchas,
+//   escalations and bloks based challenges return ChallengeManualError.
+//
+// The challenge is exported with the session, if it has not been solved yet.
+//   Resolve can be called again after Import to continue, e.g. once the
+//   security code is available.
+func (c *Challenge) Resolve(ctx context.Context, r ChallengeResolver) error {
 	insta := c.insta
+	if c.ApiPath != "" {
+		insta.challengeURL = strings.TrimPrefix(c.ApiPath, "/")
+	}
+	if insta.challengeURL == "" {
+		return errors.New("No challenge to resolve")
+	}
 
-	ctx, err := json.Marshal(c.Context)
-	if err != nil {
+	if err := c.updateState(ctx); err != nil {
 		return err
 	}
+	for i := 0; i < maxChallengeSteps; i++ {
+		if c.solved() {
+			c.finish()
+			return nil
+		}
+		if err := c.next(ctx, r); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("Challenge not solved after %d steps", maxChallengeSteps)
+}
 
-	body, _, err := insta.sendRequest(
-		&reqOptions{
-			Endpoint: c.insta.challengeURL,
-			Query: map[string]string{
-				"guid":              insta.uuid,
-				"device_id":         insta.dID,
-				"challenge_context": string(ctx),
-			},
-		},
-	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*c = *resp.Challenge
-			c.insta = insta
+// next solves the current step
+func (c *Challenge) next(ctx context.Context, r ChallengeResolver) error {
+	name := c.StepName
+	switch {
+	case name == "select_verify_method" || name == "select_contact_point_recovery":
+		choice, err := r.SelectMethod(ctx, c)
+		if err != nil {
+			return err
+		}
+		if choice == "" {
+			choice = c.StepData.Choice
+		}
+		return c.submit(ctx, c.insta.challengeURL, map[string]string{"choice": choice})
+	case name == "delta_login_review":
+		// It was me
+		return c.submit(ctx, c.insta.challengeURL, map[string]string{"choice": "0"})
+	case name == "verify_code" || name == "verify_email" || name == "verify_sms" ||
+		name == "verify_email_code":
+		code, err := r.SecurityCode(ctx, c)
+		if err != nil {
+			return err
 		}
+		return c.submit(ctx, c.insta.challengeURL, map[string]string{"security_code": code})
+	case name == "submit_phone":
+		phone, err := r.PhoneNumber(ctx, c)
+		if err != nil {
+			return err
+		}
+		return c.submit(ctx, c.insta.challengeURL, map[string]string{"phone_number": phone})
+	case name == "change_password":
+		pass, err := r.NewPassword(ctx, c)
+		if err != nil {
+			return err
+		}
+		return c.changePassword(ctx, pass)
+	case strings.Contains(name, "recaptcha") || strings.HasPrefix(name, "escalation") ||
+		name == "" && c.BloksAction != "":
+		return ChallengeManualError{StepName: name, URL: c.webURL()}
 	}
-	return err
+	return ErrChallengeProcess{StepName: name}
 }
 
-// selectVerifyMethod selects a way and verify it (Phone number = 0, email = 1)
-func (challenge *Challenge) selectVerifyMethod(choice string, isReplay ...bool) error {
-	insta := challenge.insta
+// solved reports whether Instagram has closed the challenge
+func (c *Challenge) solved() bool {
+	return c.Action == "close" || (c.LoggedInUser != nil && c.LoggedInUser.ID != 0)
+}
 
-	url := challenge.insta.challengeURL
-	if len(isReplay) > 0 && isReplay[0] {
-		url = strings.Replace(url, "/challenge/", "/challenge/replay/", -1)
+// finish updates the session after the challenge has been solved
+func (c *Challenge) finish() {
+	insta := c.insta
+	insta.challengeURL = ""
+	c.ApiPath = ""
+	if c.LoggedInUser != nil && c.LoggedInUser.ID != 0 {
+		insta.loggedIn(c.LoggedInUser)
+		return
 	}
+	insta.ResetHealth()
+	insta.autoSave()
+}
 
-	data, err := json.Marshal(
-		map[string]string{
-			"choice":    choice,
-			"guid":      insta.uuid,
-			"device_id": insta.dID,
-			"_uuid":     insta.uuid,
-			"_uid":      toString(insta.Account.ID),
-		})
+func (c *Challenge) webURL() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return "https://i.instagram.com/" + c.insta.challengeURL
+}
+
+func (c *Challenge) changePassword(ctx context.Context, pass string) error {
+	insta := c.insta
+	// the public key is not exported, fetch it if the challenge has been
+	//   resumed after an import
+	if insta.pubKey == "" || insta.pubKeyID == 0 {
+		if err := insta.sync(ctx); err != nil {
+			return err
+		}
+	}
+	timestamp := strconv.Itoa(int(time.Now().Unix()))
+	enc, err := utilities.EncryptPassword(pass, insta.pubKey, insta.pubKeyID, timestamp)
 	if err != nil {
 		return err
 	}
+	return c.submit(ctx, insta.challengeURL, map[string]string{
+		"enc_new_password1": enc,
+		"enc_new_password2": enc,
+	})
+}
+
+// updateState updates current data from challenge url
+func (c *Challenge) updateState(ctx context.Context) error {
+	insta := c.insta
+
+	query := map[string]string{
+		"guid":      insta.uuid,
+		"device_id": insta.dID,
+	}
+	if c.Context != nil {
+		b, err := json.Marshal(c.Context)
+		if err != nil {
+			return err
+		}
+		query["challenge_context"] = string(b)
+	}
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: url,
-			Query:    generateSignature(data),
-			IsPost:   true,
+			Endpoint: insta.challengeURL,
+			Query:    query,
+			Context:  ctx,
 		},
 	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*challenge = *resp.Challenge
-			challenge.insta = insta
-		}
+	if err != nil {
+		return err
 	}
-	return err
+	return c.update(body)
 }
 
-// sendSecurityCode sends the code received in the message
-func (challenge *Challenge) SendSecurityCode(code string) error {
-	insta := challenge.insta
-	url := challenge.insta.challengeURL
+// submit posts the input of a step, and updates the challenge with the next
+//   step from the response.
+func (c *Challenge) submit(ctx context.Context, url string, data map[string]string) error {
+	insta := c.insta
 
-	data, err := json.Marshal(map[string]string{
-		"security_code": code,
-		"guid":          insta.uuid,
-		"device_id":     insta.dID,
-		"_uuid":         insta.uuid,
-		"_uid":          toString(insta.Account.ID),
-	})
+	data["guid"] = insta.uuid
+	data["device_id"] = insta.dID
+	data["_uuid"] = insta.uuid
+	if c.UserID != 0 {
+		data["_uid"] = toString(c.UserID)
+	} else if insta.Account != nil {
+		data["_uid"] = toString(insta.Account.ID)
+	}
+	b, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
@@ -172,19 +355,58 @@ func (challenge *Challenge) SendSecurityCode(code string) error {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: url,
+			Query:    generateSignature(b),
 			IsPost:   true,
-			Query:    generateSignature(data),
+			Context:  ctx,
 		},
 	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*challenge = *resp.Challenge
-			challenge.insta = insta
-			challenge.LoggedInUser.insta = insta
-			insta.Account = challenge.LoggedInUser
-		}
+	if err != nil {
+		return err
+	}
+	return c.update(body)
+}
+
+// update replaces the challenge with the state of the response
+func (c *Challenge) update(body []byte) error {
+	resp := challengeResp{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	if resp.Challenge == nil {
+		return nil
+	}
+
+	insta := c.insta
+	*c = *resp.Challenge
+	c.insta = insta
+	if c.StepName == "" && c.Context != nil {
+		c.StepName = c.Context.StepName
+		c.StepData = c.Context.StepData
+	}
+	if c.LoggedInUser != nil {
+		c.LoggedInUser.insta = insta
+	}
+	return nil
+}
+
+// selectVerifyMethod selects a way and verify it (Phone number = 0, email = 1)
+func (challenge *Challenge) selectVerifyMethod(choice string, isReplay ...bool) error {
+	url := challenge.insta.challengeURL
+	if len(isReplay) > 0 && isReplay[0] {
+		url = strings.Replace(url, "/challenge/", "/challenge/replay/", -1)
+	}
+	return challenge.submit(context.Background(), url, map[string]string{"choice": choice})
+}
+
+// sendSecurityCode sends the code received in the message
+func (challenge *Challenge) SendSecurityCode(code string) error {
+	err := challenge.submit(
+		context.Background(),
+		challenge.insta.challengeURL,
+		map[string]string{"security_code": code},
+	)
+	if err == nil && challenge.solved() {
+		challenge.finish()
 	}
 	return err
 }
@@ -197,18 +419,18 @@ func (c *Challenge) deltaLoginReview() error {
 func (c *Challenge) Process(apiURL string) error {
 	c.insta.challengeURL = apiURL[1:]
 
-	if err := c.updateState(); err != nil {
+	if err := c.updateState(context.Background()); err != nil {
 		return err
 	}
 
-	switch c.Context.StepName {
+	switch c.StepName {
 	case "select_verify_method":
-		return c.selectVerifyMethod(c.Context.StepData.Choice)
+		return c.selectVerifyMethod(c.StepData.Choice)
 	case "delta_login_review":
 		return c.deltaLoginReview()
 	}
 
-	return ErrChallengeProcess{StepName: c.Context.StepName}
+	return ErrChallengeProcess{StepName: c.StepName}
 }
 
 // Check2FATrusted checks whether the device has been trusted.
@@ -247,39 +469,11 @@ func (info *TwoFactorInfo) Check2FATrusted() error {
 
 // Login2FA allows for a login through 2FA
 func (info *TwoFactorInfo) Login2FA(code string) error {
-	insta := info.insta
-	data, err := json.Marshal(
-		map[string]string{
-			"verification_code":     code,
-			"phone_id":              insta.fID,
-			"two_factor_identifier": info.TwoFactorIdentifier,
-			"username":              insta.user,
-			"trust_this_device":     "1",
-			"guid":                  insta.uuid,
-			"device_id":             insta.dID,
-			"waterfall_id":          generateUUID(),
-			"verification_method":   "4",
-		},
-	)
-	if err != nil {
-		return err
-	}
-	body, _, err := insta.sendRequest(
-		&reqOptions{
-			Endpoint: url2FALogin,
-			IsPost:   true,
-			Query:    generateSignature(data),
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	err = insta.verifyLogin(body)
+	err := info.login2FA(context.Background(), code, "4")
 	if err != nil {
 		return err
 	}
 
-	err = insta.OpenApp()
+	err = info.insta.OpenApp()
 	return err
 }
@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -90,6 +91,11 @@ func (comments *Comments) toggleComments(endpoint string) error {
 //
 // New comments are stored in Comments.Items
 func (comments *Comments) Next() bool {
+	return comments.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (comments *Comments) NextCtx(ctx context.Context) bool {
 	if comments.err != nil {
 		return false
 	}
@@ -113,6 +119,7 @@ func (comments *Comments) Next() bool {
 			Endpoint:   endpoint,
 			Connection: "keep-alive",
 			Query:      query,
+			Context:    ctx,
 		},
 	)
 	if err == nil {
@@ -151,17 +158,32 @@ func (comments *Comments) Add(text string) (err error) {
 	return comments.item.Comment(text)
 }
 
+// AddCtx is like Add, but the requests can be canceled with ctx.
+func (comments *Comments) AddCtx(ctx context.Context, text string) error {
+	return comments.item.CommentCtx(ctx, text)
+}
+
 // Delete deletes a single comment.
 func (c *Comment) Delete() error {
-	return c.item.insta.bulkDelComments([]*Comment{c})
+	return c.DeleteCtx(context.Background())
+}
+
+// DeleteCtx is like Delete, but the request can be canceled with ctx.
+func (c *Comment) DeleteCtx(ctx context.Context) error {
+	return c.item.insta.bulkDelComments(ctx, []*Comment{c})
 }
 
 // BulkDelete allows you to select and delete multiple comments on a single post.
 func (comments *Comments) BulkDelete(c []*Comment) error {
-	return comments.item.insta.bulkDelComments(c)
+	return comments.BulkDeleteCtx(context.Background(), c)
 }
 
-func (insta *Instagram) bulkDelComments(c []*Comment) error {
+// BulkDeleteCtx is like BulkDelete, but the request can be canceled with ctx.
+func (comments *Comments) BulkDeleteCtx(ctx context.Context, c []*Comment) error {
+	return comments.item.insta.bulkDelComments(ctx, c)
+}
+
+func (insta *Instagram) bulkDelComments(ctx context.Context, c []*Comment) error {
 	if len(c) == 0 {
 		return nil
 	}
@@ -192,6 +214,7 @@ func (insta *Instagram) bulkDelComments(c []*Comment) error {
 			Endpoint: fmt.Sprintf(urlCommentBulkDelete, pID),
 			Query:    generateSignature(data),
 			IsPost:   true,
+			Context:  ctx,
 		},
 	)
 	return err
@@ -206,12 +229,17 @@ func (insta *Instagram) bulkDelComments(c []*Comment) error {
 //
 // See example: examples/media/commentsDelMine.go
 func (comments *Comments) DeleteMine(limit int) error {
+	return comments.DeleteMineCtx(context.Background(), limit)
+}
+
+// DeleteMineCtx is like DeleteMine, but it can be canceled with ctx.
+func (comments *Comments) DeleteMineCtx(ctx context.Context, limit int) error {
 	comments.Sync()
 	cList := make([]*Comment, 1)
 
 	insta := comments.item.insta
 floop:
-	for i := 0; comments.Next(); i++ {
+	for i := 0; comments.NextCtx(ctx); i++ {
 		for _, c := range comments.Items {
 			if c.UserID == insta.Account.ID || c.User.ID == insta.Account.ID {
 				if limit > 0 && i >= limit {
@@ -220,12 +248,14 @@ floop:
 				cList = append(cList, &c)
 			}
 		}
-		time.Sleep(100 * time.Millisecond)
+		if err := sleep(ctx, 100*time.Millisecond); err != nil {
+			return err
+		}
 	}
 	if err := comments.Error(); err != nil && err != ErrNoMore {
 		return err
 	}
-	err := comments.BulkDelete(cList)
+	err := comments.BulkDeleteCtx(ctx, cList)
 	return err
 }
 
@@ -324,6 +354,8 @@ func (c *Comment) changeLike(endpoint string) error {
 			Endpoint: fmt.Sprintf(endpoint, c.getid()),
 			IsPost:   true,
 			Query:    generateSignature(data),
+			NoRetry:  true,
+			Action:   ActionLike,
 		},
 	)
 	return err
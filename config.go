@@ -0,0 +1,214 @@
+package goinsta
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ConfigVersion is the current version of the ConfigFile schema. Configs
+//   without version have been exported by older versions of goinsta, and are
+//   migrated on import.
+const ConfigVersion = 3
+
+var (
+	uuidRegex     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	deviceIDRegex = regexp.MustCompile(`^android-[0-9a-f]{16}$`)
+	// idRegex matches the characters IDs are made of. IDs containing other
+	//   characters would break the requests they are sent with.
+	idRegex = regexp.MustCompile(`^[0-9A-Za-z._:-]{1,128}$`)
+)
+
+// configMigrations upgrade a config by one version each, the migration at
+//   index i upgrades a config of version i to version i+1. They return
+//   warnings about values, which had to be made up.
+var configMigrations = []func(c *ConfigFile) []string{
+	migrateConfigV0,
+	migrateConfigV1,
+	migrateConfigV2,
+}
+
+// ConfigError is returned if a config is invalid. It lists all problems found.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e ConfigError) Error() string {
+	return "Invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Migrate upgrades a config exported by an older version of goinsta to the
+//   current schema version. It is called by ImportConfig, which passes the
+//   returned warnings to the WarnHandler.
+//
+// The warnings describe values the migration had to make up, such as a new
+//   family ID, as they were missing in the config. The session may not look
+//   like the same device to Instagram anymore.
+func (c *ConfigFile) Migrate() ([]string, error) {
+	if c.Version > ConfigVersion {
+		return nil, fmt.Errorf(
+			"Config version %d is newer than the supported version %d, please update goinsta",
+			c.Version, ConfigVersion,
+		)
+	}
+	var warnings []string
+	for c.Version < ConfigVersion {
+		warnings = append(warnings, configMigrations[c.Version](c)...)
+		c.Version++
+	}
+	return warnings, nil
+}
+
+// Validate checks the config for missing or malformed fields, which would
+//   break the session. It is called by ImportConfig, after migrating the
+//   config. IDs in an unusual format are accepted, see Warnings.
+func (c *ConfigFile) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.User == "" {
+		add("username is empty")
+	}
+	for _, id := range c.ids() {
+		if !idRegex.MatchString(id.value) {
+			add("%s %q is empty or malformed", id.name, id.value)
+		}
+	}
+	if c.Device.Manufacturer == "" || c.Device.Model == "" || c.Device.AndroidVersion == 0 {
+		add("device is incomplete")
+	}
+	if c.AppVersion.Version == "" || c.AppVersion.Code == "" || c.AppVersion.BloksVersionID == "" {
+		add("app version is incomplete")
+	}
+
+	auth, hasAuth := c.HeaderOptions["Authorization"]
+	if hasAuth && len(strings.Split(auth, ":")) < 3 {
+		add("authorization header option is malformed")
+	}
+	if !hasAuth && c.ID != 0 {
+		add("authorization header option is missing for logged in user %d", c.ID)
+	}
+
+	for i, cookie := range c.Cookies {
+		if cookie == nil {
+			add("cookie %d is empty", i)
+			continue
+		}
+		if !validCookieName(cookie.Name) {
+			add("cookie %d has an invalid name %q", i, cookie.Name)
+		}
+		if strings.ContainsAny(cookie.Value, ";\"\\\r\n") {
+			add("cookie %s has an invalid value", cookie.Name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// Warnings returns the IDs of the config, which are valid, but not in the
+//   format Instagram uses, e.g. a family ID which is not a UUID. They are
+//   passed to the WarnHandler by ImportConfig.
+func (c *ConfigFile) Warnings() []string {
+	var warnings []string
+	for _, id := range c.ids() {
+		if id.value == "" {
+			continue
+		}
+		if id.name == "device_id" && !deviceIDRegex.MatchString(id.value) {
+			warnings = append(warnings, fmt.Sprintf("device_id %q is not an android device ID", id.value))
+		} else if id.name != "device_id" && !uuidRegex.MatchString(id.value) {
+			warnings = append(warnings, fmt.Sprintf("%s %q is not a UUID", id.name, id.value))
+		}
+	}
+	return warnings
+}
+
+type configID struct {
+	name  string
+	value string
+}
+
+// ids returns the device IDs of the config
+func (c *ConfigFile) ids() []configID {
+	return []configID{
+		{"uuid", c.UUID},
+		{"family_id", c.FamilyID},
+		{"phone_id", c.PhoneID},
+		{"device_id", c.DeviceID},
+	}
+}
+
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// migrateConfigV0 upgrades configs exported before the version field has been
+//   introduced. Older versions did not export the device, family ID and phone
+//   ID, and did not normalize header names.
+func migrateConfigV0(c *ConfigFile) []string {
+	var warnings []string
+	headers := make(map[string]string, len(c.HeaderOptions))
+	for k, v := range c.HeaderOptions {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	c.HeaderOptions = headers
+
+	if c.Device.Manufacturer == "" {
+		c.Device = GalaxyS10
+		warnings = append(warnings, "device is missing, using the default device")
+	}
+	if c.FamilyID == "" {
+		c.FamilyID = generateUUID()
+		warnings = append(warnings, "family_id is missing, generated a new one")
+	}
+	if c.PhoneID == "" {
+		c.PhoneID = generateUUID()
+		warnings = append(warnings, "phone_id is missing, generated a new one")
+	}
+	if c.Account == nil && c.ID != 0 {
+		c.Account = &Account{ID: c.ID}
+	}
+
+	cookies := make([]*http.Cookie, 0, len(c.Cookies))
+	for _, cookie := range c.Cookies {
+		if cookie != nil {
+			cookies = append(cookies, cookie)
+		}
+	}
+	c.Cookies = cookies
+	return warnings
+}
+
+// migrateConfigV1 upgrades configs exported before the app version has been
+//   exported. These sessions have been created with the default app version.
+func migrateConfigV1(c *ConfigFile) []string {
+	if c.AppVersion.Version == "" {
+		c.AppVersion = DefaultAppVersion
+		return []string{"app version is missing, using the default app version"}
+	}
+	return nil
+}
+
+// migrateConfigV2 upgrades configs exported before the ad ID has been
+//   exported.
+func migrateConfigV2(c *ConfigFile) []string {
+	if c.AdID == "" {
+		c.AdID = generateUUID()
+		return []string{"ad_id is missing, generated a new one"}
+	}
+	return nil
+}
@@ -57,6 +57,13 @@ var (
 		"X-Ig-Www-Claim",
 		"X-Bloks-Is-Panorama-Enabled",
 	}
+	// DefaultAppVersion is the app version used by default
+	DefaultAppVersion = AppVersion{
+		Version:        appVersion,
+		Code:           appVersionCode,
+		BloksVersionID: bloksVerID,
+		Capabilities:   igCapabilities,
+	}
 	// Default Device
 	GalaxyS10 = Device{
 		Manufacturer:     "samsung",
@@ -250,8 +257,44 @@ const (
 	urlConfigureStory   = "media/configure_to_story/"
 
 	// 2FA
-	url2FACheckTrusted = "two_factor/check_trusted_notification_status/"
-	url2FALogin        = "accounts/two_factor_login/"
+	url2FACheckTrusted        = "two_factor/check_trusted_notification_status/"
+	url2FALogin               = "accounts/two_factor_login/"
+	url2FASecurityInfo        = "accounts/account_security_info/"
+	url2FAGenerateTOTPKey     = "accounts/generate_two_factor_totp_key/"
+	url2FAEnableTOTP          = "accounts/enable_totp_two_factor/"
+	url2FADisableTOTP         = "accounts/disable_totp_two_factor/"
+	url2FASendEnableSMS       = "accounts/send_two_factor_enable_sms/"
+	url2FAEnableSMS           = "accounts/enable_sms_two_factor/"
+	url2FADisableSMS          = "accounts/disable_sms_two_factor/"
+	url2FARegenBackupCodes    = "accounts/regen_backup_codes/"
+	url2FARemoveTrustedDevice = "accounts/remove_trusted_device/"
+
+	// Login activity
+	urlLoginActivity       = "session/login_activity/"
+	urlLoginActivityAvow   = "session/login_activity/avow_login/"
+	urlLoginActivityUndo   = "session/login_activity/undo_avow_login/"
+	urlLoginActivityLogout = "session/login_activity/logout_session/"
+
+	// Register
+	urlCheckEmail             = "users/check_email/"
+	urlSendVerifyEmail        = "accounts/send_verify_email/"
+	urlCheckConfirmationCode  = "accounts/check_confirmation_code/"
+	urlCheckPhoneNumber       = "accounts/check_phone_number/"
+	urlSendSignupSMSCode      = "accounts/send_signup_sms_code/"
+	urlValidateSignupSMSCode  = "accounts/validate_signup_sms_code/"
+	urlSignupConfig           = "consent/get_signup_config/"
+	urlCheckAgeEligibility    = "consent/check_age_eligibility/"
+	urlUsernameSuggestions    = "accounts/username_suggestions/"
+	urlCheckUsername          = "users/check_username/"
+	urlCreateAccount          = "accounts/create/"
+	urlCreateValidatedAccount = "accounts/create_validated/"
+
+	// Account recovery
+	urlLookup             = "users/lookup/"
+	urlSendRecoveryEmail  = "accounts/send_recovery_flow_email/"
+	urlSendRecoverySMS    = "users/lookup_phone/"
+	urlRecoveryCodeVerify = "accounts/account_recovery_code_verify/"
+	urlPasswordReset      = "accounts/password_reset/"
 )
 
 // Errors
@@ -269,6 +312,19 @@ var (
 	ErrStoryBadMediaType  = errors.New("When uploading multiple items to your story at once, all have to be mp4")
 	ErrStoryMediaTooLong  = errors.New("Story media must not exceed 15 seconds per item")
 
+	// Upload validation errors, see UploadOptions.Validate
+	ErrNoUploadFile        = errors.New("No file to upload, set File or Album")
+	ErrCarouselTooFewItems = errors.New("Carousels need at least 2 items")
+	ErrFileTooLarge        = errors.New("File exceeds the size limit")
+	ErrAspectRatio         = errors.New("Aspect ratio is not accepted by Instagram")
+	ErrVideoTooShort       = errors.New("Video is too short")
+	ErrVideoTooLong        = errors.New("Video is too long")
+	ErrCaptionTooLong      = errors.New("Caption exceeds 2200 characters")
+	ErrTooManyHashtags     = errors.New("Caption exceeds 30 hashtags")
+	ErrTooManyMentions     = errors.New("Caption exceeds 20 mentions")
+	ErrTooManyUserTags     = errors.New("Too many users tagged, at most 20 per item")
+	ErrInvalidUserTag      = errors.New("Invalid user tag")
+
 	// Search Errors
 	ErrSearchUserNotFound = errors.New("User not found in search result")
 
@@ -0,0 +1,166 @@
+package goinsta
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+var (
+	Pixel5 = Device{
+		Manufacturer:     "Google/google",
+		Model:            "Pixel 5",
+		CodeName:         "redfin",
+		AndroidVersion:   30,
+		AndroidRelease:   11,
+		ScreenDpi:        "440dpi",
+		ScreenResolution: "1080x2340",
+		Chipset:          "redfin",
+	}
+	GalaxyS21 = Device{
+		Manufacturer:     "samsung",
+		Model:            "SM-G991B",
+		CodeName:         "o1s",
+		AndroidVersion:   31,
+		AndroidRelease:   12,
+		ScreenDpi:        "420dpi",
+		ScreenResolution: "1080x2400",
+		Chipset:          "exynos2100",
+	}
+	GalaxyA52 = Device{
+		Manufacturer:     "samsung",
+		Model:            "SM-A525F",
+		CodeName:         "a52q",
+		AndroidVersion:   30,
+		AndroidRelease:   11,
+		ScreenDpi:        "450dpi",
+		ScreenResolution: "1080x2400",
+		Chipset:          "qcom",
+	}
+	OnePlus7T = Device{
+		Manufacturer:     "OnePlus",
+		Model:            "HD1903",
+		CodeName:         "OnePlus7T",
+		AndroidVersion:   29,
+		AndroidRelease:   10,
+		ScreenDpi:        "420dpi",
+		ScreenResolution: "1080x2400",
+		Chipset:          "qcom",
+	}
+	RedmiNote8 = Device{
+		Manufacturer:     "Xiaomi",
+		Model:            "Redmi Note 8",
+		CodeName:         "ginkgo",
+		AndroidVersion:   29,
+		AndroidRelease:   10,
+		ScreenDpi:        "440dpi",
+		ScreenResolution: "1080x2340",
+		Chipset:          "qcom",
+	}
+	HuaweiP30 = Device{
+		Manufacturer:     "HUAWEI",
+		Model:            "ELE-L29",
+		CodeName:         "HWELE",
+		AndroidVersion:   29,
+		AndroidRelease:   10,
+		ScreenDpi:        "480dpi",
+		ScreenResolution: "1080x2340",
+		Chipset:          "kirin980",
+	}
+
+	// Devices is the catalog of known device profiles. Append to it to add
+	//   your own profiles. Fingerprints are derived from a pinned catalog,
+	//   which is not changed by changing Devices.
+	Devices = []Device{GalaxyS10, G6, Pixel5, GalaxyS21, GalaxyA52, OnePlus7T, RedmiNote8, HuaweiP30}
+
+	// AppVersions is the catalog of app versions supported by goinsta, newest
+	//   first. Fingerprints are derived from a pinned catalog, which is not
+	//   changed by changing AppVersions.
+	AppVersions = []AppVersion{DefaultAppVersion}
+)
+
+// fingerprintDevices is the catalog NewFingerprint picks devices from. It is
+//   pinned, as the device of a seed depends on the size of the catalog. Never
+//   change it, add a new version instead if needed.
+var fingerprintDevices = [...]Device{GalaxyS10, G6, Pixel5, GalaxyS21, GalaxyA52, OnePlus7T, RedmiNote8, HuaweiP30}
+
+// fingerprintAppVersions is the catalog NewFingerprint picks app versions
+//   from. It is pinned like fingerprintDevices.
+var fingerprintAppVersions = [...]AppVersion{DefaultAppVersion}
+
+// Fingerprint is the identity of an emulated phone: the device, app version
+//   and the IDs Instagram uses to recognize it.
+type Fingerprint struct {
+	Device     Device
+	AppVersion AppVersion
+	// DeviceID is the android device ID | android-1923fjnma8123
+	DeviceID string
+	// UUID, PhoneID, FamilyID and AdID are v4 UUIDs
+	UUID     string
+	PhoneID  string
+	FamilyID string
+	AdID     string
+}
+
+// NewFingerprint derives a fingerprint from a seed, e.g. the username. The
+//   same seed always results in the same device and IDs, so that an account
+//   keeps its fingerprint, even if its session is lost. Different seeds get
+//   different IDs, and one of the devices and app versions of the initial
+//   Devices and AppVersions catalogs.
+func NewFingerprint(seed string) Fingerprint {
+	h := fingerprintHash(seed, "device")
+	device := fingerprintDevices[binary.BigEndian.Uint32(h)%uint32(len(fingerprintDevices))]
+	h = fingerprintHash(seed, "app_version")
+	app := fingerprintAppVersions[binary.BigEndian.Uint32(h)%uint32(len(fingerprintAppVersions))]
+
+	return Fingerprint{
+		Device:     device,
+		AppVersion: app,
+		DeviceID:   "android-" + hex.EncodeToString(fingerprintHash(seed, "device_id")[:8]),
+		UUID:       fingerprintUUID(seed, "uuid"),
+		PhoneID:    fingerprintUUID(seed, "phone_id"),
+		FamilyID:   fingerprintUUID(seed, "family_id"),
+		AdID:       fingerprintUUID(seed, "ad_id"),
+	}
+}
+
+// Fingerprint returns the fingerprint currently used
+func (insta *Instagram) Fingerprint() Fingerprint {
+	return Fingerprint{
+		Device:     insta.device,
+		AppVersion: insta.appVersion,
+		DeviceID:   insta.dID,
+		UUID:       insta.uuid,
+		PhoneID:    insta.pid,
+		FamilyID:   insta.fID,
+		AdID:       insta.adid,
+	}
+}
+
+// SetFingerprint sets the device, app version and all device IDs at once.
+//   Call it before login, changing the fingerprint of a logged in session
+//   looks suspicious.
+func (insta *Instagram) SetFingerprint(f Fingerprint) {
+	insta.dID = f.DeviceID
+	insta.uuid = f.UUID
+	insta.pid = f.PhoneID
+	insta.fID = f.FamilyID
+	insta.adid = f.AdID
+	insta.device = f.Device
+	insta.appVersion = f.AppVersion
+	insta.userAgent = createUserAgent(f.Device, f.AppVersion)
+}
+
+func fingerprintHash(seed, label string) []byte {
+	h := sha256.Sum256([]byte(label + ":" + seed))
+	return h[:]
+}
+
+// fingerprintUUID derives a v4 UUID from the seed
+func fingerprintUUID(seed, label string) string {
+	b := fingerprintHash(seed, label)[:16]
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
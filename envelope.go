@@ -0,0 +1,275 @@
+package goinsta
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/UliSotschok/goinsta/utilities"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// envelopeVersion is the current version of the encrypted config format
+const envelopeVersion = 1
+
+// DefaultPBKDF2Iterations is the number of iterations used to derive a key
+//   from a passphrase
+const DefaultPBKDF2Iterations = 310000
+
+// Bounds of the PBKDF2 iterations. The iterations are stored unauthenticated
+//   with an encrypted config, configs outside of the bounds are rejected, so
+//   that a tampered file can neither weaken the key, nor use up the CPU.
+const (
+	MinPBKDF2Iterations = 100000
+	MaxPBKDF2Iterations = 10000000
+)
+
+// ErrUnsupportedEnvelope is returned if an encrypted config has been written
+//   by a newer version of goinsta.
+var ErrUnsupportedEnvelope = errors.New("Unsupported encrypted config version")
+
+// KeyProvider protects the key, which encrypts an exported config. Every
+//   export is encrypted with a new random data key, which is wrapped by the
+//   key provider, and stored next to the encrypted config.
+//
+// Implement it to use a key management service (KMS) or a hardware module.
+//   NewPassphraseKeyProvider derives the key from a passphrase.
+type KeyProvider interface {
+	// WrapKey encrypts the data key. keyID identifies the key used, and is
+	//   passed to UnwrapKey again.
+	WrapKey(dataKey []byte) (wrapped []byte, keyID string, err error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey
+	UnwrapKey(wrapped []byte, keyID string) ([]byte, error)
+}
+
+// envelope is the format of an encrypted config
+type envelope struct {
+	Version    int    `json:"goinsta_envelope"`
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (e *envelope) additionalData() []byte {
+	return []byte(fmt.Sprintf("goinsta-envelope-v%d:%s", e.Version, e.KeyID))
+}
+
+// EncryptConfig encrypts a config with AES-GCM, and returns the encrypted
+//   envelope, which can be decrypted with DecryptConfig.
+func EncryptConfig(config ConfigFile, kp KeyProvider) ([]byte, error) {
+	plain, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	env, err := sealEnvelope(plain, kp)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// sealEnvelope encrypts plain with a new data key, wrapped by kp
+func sealEnvelope(plain []byte, kp KeyProvider) (*envelope, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	env := &envelope{Version: envelopeVersion}
+	var err error
+	env.WrappedKey, env.KeyID, err = kp.WrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, encrypted, tag, err := utilities.AESGCMEncrypt(dataKey, plain, env.additionalData())
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = iv
+	env.Ciphertext = append(encrypted, tag...)
+	return env, nil
+}
+
+// open decrypts the envelope with the data key unwrapped by kp
+func (e *envelope) open(kp KeyProvider) ([]byte, error) {
+	if e.Version > envelopeVersion {
+		return nil, ErrUnsupportedEnvelope
+	}
+	dataKey, err := kp.UnwrapKey(e.WrappedKey, e.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return utilities.AESGCMDecrypt(dataKey, e.Nonce, e.Ciphertext, e.additionalData())
+}
+
+// DecryptConfig decrypts a config encrypted with EncryptConfig. Plain text
+//   configs, as written by Export, are returned as is, so older configs can
+//   still be imported.
+func DecryptConfig(b []byte, kp KeyProvider) (*ConfigFile, error) {
+	env := envelope{}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, err
+	}
+
+	config := &ConfigFile{}
+	if env.Version == 0 {
+		if err := json.Unmarshal(b, config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
+	if env.Version > envelopeVersion {
+		return nil, ErrUnsupportedEnvelope
+	}
+	if kp == nil {
+		return nil, errors.New("Config is encrypted, but no key provider has been passed")
+	}
+
+	dataKey, err := kp.UnwrapKey(env.WrappedKey, env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := utilities.AESGCMDecrypt(dataKey, env.Nonce, env.Ciphertext, env.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decrypt config: %s", err)
+	}
+	if err := json.Unmarshal(plain, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ExportEncrypted exports the session encrypted to path. The file is only
+//   readable by the owner.
+func (insta *Instagram) ExportEncrypted(path string, kp KeyProvider) error {
+	config, err := insta.ExportConfig()
+	if err != nil {
+		return err
+	}
+	b, err := EncryptConfig(config, kp)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0o600)
+}
+
+// ExportEncryptedIO exports the session encrypted to an io.Writer
+func (insta *Instagram) ExportEncryptedIO(w io.Writer, kp KeyProvider) error {
+	config, err := insta.ExportConfig()
+	if err != nil {
+		return err
+	}
+	b, err := EncryptConfig(config, kp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ImportEncrypted imports a session exported with ExportEncrypted. Plain text
+//   configs are imported as well.
+//
+// This function does not set proxy automatically. Use SetProxy after this call.
+func ImportEncrypted(path string, kp KeyProvider, args ...interface{}) (*Instagram, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportEncryptedReader(f, kp, args...)
+}
+
+// ImportEncryptedReader imports an encrypted session from an io.Reader. Plain
+//   text configs are imported as well.
+//
+// This function does not set proxy automatically. Use SetProxy after this call.
+func ImportEncryptedReader(r io.Reader, kp KeyProvider, args ...interface{}) (*Instagram, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, err := DecryptConfig(b, kp)
+	if err != nil {
+		return nil, err
+	}
+	return ImportConfig(*config, args...)
+}
+
+// passphraseKeyProvider wraps data keys with a key derived from a passphrase
+type passphraseKeyProvider struct {
+	passphrase []byte
+	iterations int
+}
+
+// NewPassphraseKeyProvider returns a key provider, which derives the key from
+//   a passphrase with PBKDF2-SHA256, and a random salt for every export.
+//   iterations defaults to DefaultPBKDF2Iterations, if not passed, and has
+//   to be between MinPBKDF2Iterations and MaxPBKDF2Iterations.
+func NewPassphraseKeyProvider(passphrase string, iterations ...int) KeyProvider {
+	p := &passphraseKeyProvider{
+		passphrase: []byte(passphrase),
+		iterations: DefaultPBKDF2Iterations,
+	}
+	if len(iterations) > 0 && iterations[0] > 0 {
+		p.iterations = iterations[0]
+	}
+	return p
+}
+
+// WrapKey encrypts the data key, the wrapped key is: salt (16) | iv (12) |
+//   encrypted key | tag (16)
+func (p *passphraseKeyProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, "", err
+	}
+	if err := checkPBKDF2Iterations(p.iterations); err != nil {
+		return nil, "", err
+	}
+	keyID := "pbkdf2-sha256:" + strconv.Itoa(p.iterations)
+	kek := pbkdf2.Key(p.passphrase, salt, p.iterations, 32, sha256.New)
+
+	iv, encrypted, tag, err := utilities.AESGCMEncrypt(kek, dataKey, []byte(keyID))
+	if err != nil {
+		return nil, "", err
+	}
+	wrapped := append(append(append(salt, iv...), encrypted...), tag...)
+	return wrapped, keyID, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *passphraseKeyProvider) UnwrapKey(wrapped []byte, keyID string) ([]byte, error) {
+	iterations, err := strconv.Atoi(strings.TrimPrefix(keyID, "pbkdf2-sha256:"))
+	if err != nil || !strings.HasPrefix(keyID, "pbkdf2-sha256:") {
+		return nil, fmt.Errorf("Config has not been encrypted with a passphrase (key %s)", keyID)
+	}
+	if err := checkPBKDF2Iterations(iterations); err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 16+12+16 {
+		return nil, errors.New("Wrapped key too short")
+	}
+	kek := pbkdf2.Key(p.passphrase, wrapped[:16], iterations, 32, sha256.New)
+	key, err := utilities.AESGCMDecrypt(kek, wrapped[16:28], wrapped[28:], []byte(keyID))
+	if err != nil {
+		return nil, errors.New("Failed to decrypt config, wrong passphrase")
+	}
+	return key, nil
+}
+
+func checkPBKDF2Iterations(n int) error {
+	if n < MinPBKDF2Iterations || n > MaxPBKDF2Iterations {
+		return fmt.Errorf("Invalid PBKDF2 iterations %d, expected %d to %d",
+			n, MinPBKDF2Iterations, MaxPBKDF2Iterations)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package goinsta
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by Instagram are mapped to one of the following errors, and
+//   can be checked with errors.Is, e.g.:
+//
+//   if errors.Is(err, goinsta.ErrLoginRequired) {
+//     // login again
+//   }
+//
+// The typed errors (Error400, ErrorN, Error503, RateLimitError,
+//   ChallengeError) can be retrieved with errors.As, to access all fields
+//   of the response.
+var (
+	// ErrLoginRequired is returned if the session is no longer valid
+	ErrLoginRequired = errors.New("Login required, the session is no longer valid")
+	// ErrChallengeRequired is returned if Instagram asks to solve a
+	//   checkpoint or challenge before continuing
+	ErrChallengeRequired = errors.New("Challenge required")
+	// ErrFeedbackRequired is returned if an action has been blocked
+	ErrFeedbackRequired = errors.New("Feedback required, the action has been blocked")
+	// ErrNotFound is returned if the requested resource does not exist
+	ErrNotFound = errors.New("Not found")
+	// ErrPrivateAccount is returned if the requested content belongs to a
+	//   private account, which is not followed
+	ErrPrivateAccount = errors.New("Not authorized to view user, the account is private")
+	// ErrTranscoding is returned if an uploaded video has not been processed yet
+	ErrTranscoding = errors.New("Transcode not finished yet")
+	// ErrMediaNeedsReupload is returned if Instagram could not process an
+	//   uploaded video, and asks for it to be uploaded again
+	ErrMediaNeedsReupload = errors.New("Media needs to be reuploaded")
+)
+
+// RetryableError is implemented by all errors, which can tell whether the
+//   failed request is worth retrying.
+type RetryableError interface {
+	error
+	// Retryable reports whether the request can be retried
+	Retryable() bool
+	// RetryAfter is the time Instagram asked to wait before retrying. It is 0
+	//   if no time has been specified.
+	RetryAfter() time.Duration
+}
+
+// IsRetryable reports whether a request that failed with err can be retried
+func IsRetryable(err error) bool {
+	var r RetryableError
+	return errors.As(err, &r) && r.Retryable()
+}
+
+// RetryAfter returns the time Instagram asked to wait, before the request that
+//   failed with err can be retried, 0 if unknown.
+func RetryAfter(err error) time.Duration {
+	var r RetryableError
+	if errors.As(err, &r) {
+		return r.RetryAfter()
+	}
+	return 0
+}
+
+// errorKind maps the status code, error_type and message of an error response
+//   to one of the sentinel errors, nil if none matches.
+func errorKind(code int, errorType, message string) error {
+	switch {
+	case message == "login_required" || errorType == "login_required":
+		return ErrLoginRequired
+	case message == "challenge_required" || strings.HasPrefix(errorType, "checkpoint"):
+		return ErrChallengeRequired
+	case message == "feedback_required" || errorType == "feedback_required" ||
+		errorType == "sentry_block":
+		return ErrFeedbackRequired
+	case code == 429 || errorType == "rate_limit_error" ||
+		strings.Contains(message, "wait a few minutes"):
+		return ErrTooManyRequests
+	case message == "Sorry, this media has been deleted" ||
+		message == "Media not found or unavailable":
+		return ErrMediaDeleted
+	case message == "Not authorized to view user":
+		return ErrPrivateAccount
+	case strings.HasPrefix(message, "Transcode not finished"):
+		return ErrTranscoding
+	case message == "media_needs_reupload":
+		return ErrMediaNeedsReupload
+	case errorType == "bad_password":
+		return ErrBadPassword
+	case code == 404 || message == "User not found" || errorType == "invalid_user":
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ErrorN is general instagram error
+type ErrorN struct {
+	Message   string `json:"message"`
+	Endpoint  string `json:"endpoint"`
+	Status    string `json:"status"`
+	ErrorType string `json:"error_type"`
+	// Code is the HTTP status code. It is kept apart from Status, which is
+	//   overwritten by the status of a JSON response, e.g. "fail".
+	Code int `json:"-"`
+}
+
+func (e ErrorN) Error() string {
+	return fmt.Sprintf(
+		"Error while calling %s, status code %d: %s (%s)",
+		e.Endpoint, e.code(), e.Message, e.ErrorType,
+	)
+}
+
+func (e ErrorN) code() int {
+	if e.Code != 0 {
+		return e.Code
+	}
+	code, _ := strconv.Atoi(e.Status)
+	return code
+}
+
+// Is allows to compare the error with the sentinel errors using errors.Is
+func (e ErrorN) Is(target error) bool {
+	kind := errorKind(e.code(), e.ErrorType, e.Message)
+	return kind != nil && kind == target
+}
+
+// Retryable reports whether the request can be retried, which is the case for
+//   server errors.
+func (e ErrorN) Retryable() bool {
+	return e.code() >= 500
+}
+
+// RetryAfter is always 0, as Instagram sends no wait time with these errors
+func (e ErrorN) RetryAfter() time.Duration {
+	return 0
+}
+
+// Error503 is instagram API error
+type Error503 struct {
+	Message string
+	// Wait is the time to wait as specified by the Retry-After header, if any
+	Wait time.Duration
+}
+
+func (e Error503) Error() string {
+	return e.Message
+}
+
+// Retryable is always true, as the service is only temporarily unavailable
+func (e Error503) Retryable() bool {
+	return true
+}
+
+// RetryAfter returns the time to wait as sent by Instagram, 0 if unknown
+func (e Error503) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// RateLimitError is returned by HTTP 429 status code. It matches
+//   ErrTooManyRequests with errors.Is.
+type RateLimitError struct {
+	Endpoint string
+	Message  string
+	// Wait is the time to wait as specified by the Retry-After header, if any
+	Wait time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	msg := ErrTooManyRequests.Error()
+	if e.Wait > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.Wait)
+	}
+	return fmt.Sprintf("Error while calling %s: %s", e.Endpoint, msg)
+}
+
+// Is allows to compare the error with ErrTooManyRequests using errors.Is
+func (e RateLimitError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
+// Retryable is always true, after waiting for RetryAfter, or a backoff delay
+func (e RateLimitError) Retryable() bool {
+	return true
+}
+
+// RetryAfter returns the time to wait as sent by Instagram, 0 if unknown
+func (e RateLimitError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// Error400 is error returned by HTTP 400 status code.
+type Error400 struct {
+	ChallengeError
+	Endpoint   string `json:"endpoint"`
+	Action     string `json:"action"`
+	StatusCode string `json:"status_code"`
+	Payload    struct {
+		ClientContext string `json:"client_context"`
+		Message       string `json:"message"`
+	} `json:"payload"`
+	DebugInfo struct {
+		Message   string `json:"string"`
+		Retriable bool   `json:"retriable"`
+		Type      string `json:"type"`
+	} `json:"debug_info"`
+	// Feedback is set if an action has been blocked (feedback_required)
+	FeedbackTitle   string `json:"feedback_title"`
+	FeedbackMessage string `json:"feedback_message"`
+	Spam            bool   `json:"spam"`
+	LogoutReason    int    `json:"logout_reason"`
+	Code            int
+	Status          string `json:"status"`
+}
+
+func (e Error400) Error() string {
+	var msg string
+	if e.Payload.Message != "" {
+		msg = e.Payload.Message
+	}
+	if e.DebugInfo.Message != "" {
+		msg = e.DebugInfo.Message
+	}
+	if e.ChallengeError.Message != "" {
+		if msg != "" {
+			msg += "; " + e.ChallengeError.Message
+		} else {
+			msg = e.ChallengeError.Message
+		}
+	}
+	if e.FeedbackMessage != "" {
+		msg += "; " + e.FeedbackMessage
+	}
+
+	if e.Code == 0 {
+		e.Code = 400
+	}
+	return fmt.Sprintf("Request Status Code %d: %s, %s", e.Code, e.Status, msg)
+}
+
+// Is allows to compare the error with the sentinel errors using errors.Is
+func (e Error400) Is(target error) bool {
+	code := e.Code
+	if code == 0 {
+		code = 400
+	}
+	if e.Spam && target == ErrFeedbackRequired {
+		return true
+	}
+	kind := errorKind(code, e.ErrorType, e.ChallengeError.Message)
+	return kind != nil && kind == target
+}
+
+// Retryable reports whether Instagram marked the request as retriable
+func (e Error400) Retryable() bool {
+	return e.DebugInfo.Retriable
+}
+
+// RetryAfter is always 0, as Instagram sends no wait time with these errors
+func (e Error400) RetryAfter() time.Duration {
+	return 0
+}
+
+// ChallengeError is error returned by HTTP 400 status code.
+type ChallengeError struct {
+	Message   string `json:"message"`
+	Challenge struct {
+		URL               string `json:"url"`
+		APIPath           string `json:"api_path"`
+		HideWebviewHeader bool   `json:"hide_webview_header"`
+		Lock              bool   `json:"lock"`
+		Logout            bool   `json:"logout"`
+		NativeFlow        bool   `json:"native_flow"`
+	} `json:"challenge"`
+	Status    string `json:"status"`
+	ErrorType string `json:"error_type"`
+}
+
+func (e ChallengeError) Error() string {
+	return fmt.Sprintf("Challenge Required: %s, %s", e.Status, e.Message)
+}
+
+// Is allows to compare the error with ErrChallengeRequired using errors.Is
+func (e ChallengeError) Is(target error) bool {
+	return target == ErrChallengeRequired
+}
@@ -1,6 +1,9 @@
 package goinsta
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
 
 type Discover struct {
 	insta      *Instagram
@@ -82,6 +85,11 @@ func newDiscover(insta *Instagram) *Discover {
 // Next allows you to paginate explore page results. Also use this for your
 //  first fetch
 func (disc *Discover) Next() bool {
+	return disc.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (disc *Discover) NextCtx(ctx context.Context) bool {
 	if disc.sessionId == "" {
 		disc.sessionId = generateUUID()
 	}
@@ -105,6 +113,7 @@ func (disc *Discover) Next() bool {
 	body, _, err := disc.insta.sendRequest(&reqOptions{
 		Endpoint: urlDiscoverExplore,
 		Query:    query,
+		Context:  ctx,
 	})
 	if err != nil {
 		disc.err = err
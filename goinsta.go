@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/UliSotschok/goinsta/utilities"
@@ -73,6 +75,8 @@ type Instagram struct {
 	pubKeyID int
 	// Device Settings
 	device Device
+	// Version of the Instagram app
+	appVersion AppVersion
 	// User-Agent
 	userAgent string
 
@@ -109,6 +113,42 @@ type Instagram struct {
 
 	c *http.Client
 
+	// middleware hooks, see Instagram.Use
+	middleware []Middleware
+	// retry policy, see Instagram.SetRetryPolicy
+	retryPolicy *RetryPolicy
+	// rate limiter, see Instagram.SetRateLimiter
+	limiter *RateLimiter
+
+	// account health, see Instagram.Health
+	healthMu      sync.Mutex
+	health        Health
+	healthPolicy  *HealthPolicy
+	healthHandler func(old, new Health)
+
+	// automatic login, see Instagram.SetAutoRelogin
+	relogin *ReloginOptions
+	// reloginCall is the automatic login in progress, nil if there is none
+	reloginMu   sync.Mutex
+	reloginCall *reloginCall
+	// sessionGen is incremented on every successful login
+	sessionGen uint32
+
+	// session store, see Instagram.SetSessionStore
+	store    SessionStore
+	storeKey string
+
+	// TOTP secret, see Instagram.SetTOTPSecret. totpSealed is the encrypted
+	//   secret of an imported session, which is decrypted when needed.
+	totpSecret string
+	totpSealed string
+	totpKey    []byte
+	totpKP     KeyProvider
+	totpWarned bool
+
+	// challenge resolver used during login, see Instagram.SetChallengeResolver
+	challengeResolver ChallengeResolver
+
 	// Non-error message handlers.
 	// By default they will be printed out, alternatively you can e.g. pass them to a logger
 	InfoHandler func(...interface{})
@@ -169,7 +209,15 @@ func (insta *Instagram) SetAdID(id string) {
 //   user agent based on the new device.
 func (insta *Instagram) SetDevice(device Device) {
 	insta.device = device
-	insta.userAgent = createUserAgent(device)
+	insta.userAgent = createUserAgent(device, insta.appVersion)
+}
+
+// SetAppVersion sets the version of the Instagram app to emulate. This will
+//   also change the user agent. Only use versions, whose bloks version ID and
+//   capabilities are known, see AppVersions.
+func (insta *Instagram) SetAppVersion(app AppVersion) {
+	insta.appVersion = app
+	insta.userAgent = createUserAgent(insta.device, app)
 }
 
 // SetCookieJar sets the Cookie Jar. This further allows to use a custom implementation
@@ -199,11 +247,13 @@ func New(username, password string) *Instagram {
 		uuid:          generateUUID(),
 		pid:           generateUUID(),
 		fID:           generateUUID(),
+		adid:          generateUUID(),
 		psID:          "UFS-" + generateUUID() + "-0",
 		headerOptions: sync.Map{},
 		xmidExpiry:    -1,
 		device:        GalaxyS10,
-		userAgent:     createUserAgent(GalaxyS10),
+		appVersion:    DefaultAppVersion,
+		userAgent:     createUserAgent(GalaxyS10, DefaultAppVersion),
 		c: &http.Client{
 			Transport: &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
@@ -268,49 +318,46 @@ func (insta *Instagram) Save() error {
 
 // Export exports *Instagram object options
 func (insta *Instagram) Export(path string) error {
-	url, err := neturl.Parse(instaAPIUrl)
+	config, err := insta.ExportConfig()
 	if err != nil {
 		return err
 	}
-
-	config := ConfigFile{
-		ID:            insta.Account.ID,
-		User:          insta.user,
-		DeviceID:      insta.dID,
-		FamilyID:      insta.fID,
-		UUID:          insta.uuid,
-		RankToken:     insta.rankToken,
-		Token:         insta.token,
-		PhoneID:       insta.pid,
-		XmidExpiry:    insta.xmidExpiry,
-		HeaderOptions: map[string]string{},
-		Cookies:       insta.c.Jar.Cookies(url),
-		Account:       insta.Account,
-		Device:        insta.device,
-	}
 	bytes, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
-
-	setHeaders := func(key, value interface{}) bool {
-		config.HeaderOptions[key.(string)] = value.(string)
-		return true
-	}
-	insta.headerOptions.Range(setHeaders)
-
-	return ioutil.WriteFile(path, bytes, 0o644)
+	return ioutil.WriteFile(path, bytes, 0o600)
 }
 
 // Export exports selected *Instagram object options to an io.Writer
 func (insta *Instagram) ExportIO(writer io.Writer) error {
-	url, err := neturl.Parse(instaAPIUrl)
+	config, err := insta.ExportConfig()
+	if err != nil {
+		return err
+	}
+	bytes, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
+	_, err = writer.Write(bytes)
+	return err
+}
+
+// ExportConfig returns the configuration of the session, which can be
+//   imported again with ImportConfig.
+func (insta *Instagram) ExportConfig() (ConfigFile, error) {
+	url, err := neturl.Parse(instaAPIUrl)
+	if err != nil {
+		return ConfigFile{}, err
+	}
+
+	totp, err := insta.sealTOTP()
+	if err != nil {
+		return ConfigFile{}, err
+	}
 
 	config := ConfigFile{
-		ID:            insta.Account.ID,
+		Version:       ConfigVersion,
 		User:          insta.user,
 		DeviceID:      insta.dID,
 		FamilyID:      insta.fID,
@@ -318,25 +365,31 @@ func (insta *Instagram) ExportIO(writer io.Writer) error {
 		RankToken:     insta.rankToken,
 		Token:         insta.token,
 		PhoneID:       insta.pid,
+		AdID:          insta.adid,
 		XmidExpiry:    insta.xmidExpiry,
 		HeaderOptions: map[string]string{},
 		Cookies:       insta.c.Jar.Cookies(url),
 		Account:       insta.Account,
 		Device:        insta.device,
+		AppVersion:    insta.appVersion,
+		RateLimits:    insta.limiter,
+		Health:        insta.Health(),
+		TOTPSecret:    totp,
+	}
+	if insta.Account != nil {
+		config.ID = insta.Account.ID
+	}
+	if insta.Challenge != nil && insta.Challenge.Pending() {
+		config.Challenge = insta.Challenge
+		config.ChallengeURL = insta.challengeURL
 	}
 
 	setHeaders := func(key, value interface{}) bool {
 		config.HeaderOptions[key.(string)] = value.(string)
 		return true
 	}
-
 	insta.headerOptions.Range(setHeaders)
-	bytes, err := json.Marshal(config)
-	if err != nil {
-		return err
-	}
-	_, err = writer.Write(bytes)
-	return err
+	return config, nil
 }
 
 // ImportReader imports instagram configuration from io.Reader
@@ -365,6 +418,15 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 		return nil, err
 	}
 
+	warnings, err := config.Migrate()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, config.Warnings()...)
+
 	insta := &Instagram{
 		user:          config.User,
 		dID:           config.DeviceID,
@@ -373,9 +435,11 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 		rankToken:     config.RankToken,
 		token:         config.Token,
 		pid:           config.PhoneID,
+		adid:          config.AdID,
 		xmidExpiry:    config.XmidExpiry,
 		headerOptions: sync.Map{},
 		device:        config.Device,
+		appVersion:    config.AppVersion,
 		c: &http.Client{
 			Transport: &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
@@ -384,8 +448,11 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 		InfoHandler: defaultHandler,
 		WarnHandler: defaultHandler,
 		Account:     config.Account,
+		limiter:     config.RateLimits,
+		health:      config.Health,
+		totpSealed:  config.TOTPSecret,
 	}
-	insta.userAgent = createUserAgent(insta.device)
+	insta.userAgent = createUserAgent(insta.device, insta.appVersion)
 	insta.c.Jar, err = cookiejar.New(nil)
 	if err != nil {
 		return insta, err
@@ -396,7 +463,16 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 		insta.headerOptions.Store(k, v)
 	}
 
+	for _, w := range warnings {
+		insta.WarnHandler("Config:", w)
+	}
+
 	insta.init()
+	if config.Challenge != nil {
+		insta.Challenge = config.Challenge
+		insta.Challenge.insta = insta
+		insta.challengeURL = config.ChallengeURL
+	}
 
 	dontSync := false
 	if len(args) != 0 {
@@ -407,7 +483,9 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 	}
 
 	if dontSync {
-		insta.Account.insta = insta
+		if insta.Account != nil {
+			insta.Account.insta = insta
+		}
 	} else {
 		insta.Account = &Account{
 			insta: insta,
@@ -437,135 +515,160 @@ func Import(path string, args ...interface{}) (*Instagram, error) {
 // Login performs instagram login sequence in close resemblance to the android apk.
 //
 // Password will be deleted after login
-func (insta *Instagram) Login() (err error) {
-	// pre-login sequence
-	err = insta.zrToken()
+func (insta *Instagram) Login() error {
+	return insta.LoginCtx(context.Background())
+}
+
+// LoginCtx is like Login, but the login sequence can be canceled with ctx.
+func (insta *Instagram) LoginCtx(ctx context.Context) (err error) {
+	err = insta.prelogin(ctx)
+	if err != nil {
+		return
+	}
+
+	err = insta.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	return
+}
+
+// prelogin performs the requests the app sends before login or registration,
+//   and fetches the public key used to encrypt the password.
+func (insta *Instagram) prelogin(ctx context.Context) (err error) {
+	err = insta.zrToken(ctx)
 	if err != nil {
 		return
 	}
-	err = insta.sync()
+	err = insta.sync(ctx)
 	if err != nil {
 		return
 	}
 
-	err = insta.getPrefill()
+	err = insta.getPrefill(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching prefill:", err)
 	}
 
-	err = insta.contactPrefill()
+	err = insta.contactPrefill(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching contact prefill:", err)
 	}
 
-	err = insta.sync()
+	err = insta.sync(ctx)
 	if err != nil {
 		return
 	}
 	if insta.pubKey == "" || insta.pubKeyID == 0 {
 		return errors.New("Sync returned empty public key and/or public key id")
 	}
-
-	err = insta.login()
-	if err != nil {
-		return err
-	}
-
 	return
 }
 
 // Logout closes current session
 func (insta *Instagram) Logout() error {
 	_, err := insta.sendSimpleRequest(urlLogout)
+	if insta.store != nil {
+		if serr := insta.store.Delete(insta.sessionKey()); serr != nil {
+			insta.WarnHandler("Failed to delete session from store:", serr)
+		}
+	}
 	insta.c.Jar = nil
 	insta.c = nil
 	return err
 }
 
-func (insta *Instagram) OpenApp() (err error) {
-	err = insta.zrToken()
+// OpenApp sends the requests the app sends when it is opened, after login.
+func (insta *Instagram) OpenApp() error {
+	return insta.OpenAppCtx(context.Background())
+}
+
+// OpenAppCtx is like OpenApp, but the requests can be canceled with ctx.
+func (insta *Instagram) OpenAppCtx(ctx context.Context) (err error) {
+	err = insta.zrToken(ctx)
 	if err != nil {
 		return
 	}
 
-	err = insta.getAccountFamily()
+	err = insta.getAccountFamily(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching account family:", err)
 	}
 
-	err = insta.sync()
+	err = insta.sync(ctx)
 	if err != nil {
 		return
 	}
 
-	err = insta.getNdxSteps()
+	err = insta.getNdxSteps(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching ndx steps:", err)
 	}
 
-	if !insta.Timeline.Next() {
+	if !insta.Timeline.NextCtx(ctx) {
 		return errors.New("Failed to fetch timeline during login procedure: " +
 			insta.Timeline.err.Error())
 	}
 
-	err = insta.callNotifBadge()
+	err = insta.callNotifBadge(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching notify badge", err)
 	}
 
-	err = insta.banyan()
+	err = insta.banyan(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching banyan", err)
 	}
 
-	err = insta.callMediaBlocked()
+	err = insta.callMediaBlocked(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching blocked media", err)
 	}
 
 	// no clue what theses values could be used for
-	_, err = insta.getCooldowns()
+	_, err = insta.getCooldowns(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching cool downs", err)
 	}
 
-	if !insta.Discover.Next() {
+	if !insta.Discover.NextCtx(ctx) {
 		insta.WarnHandler("Non fatal error while fetching explore page",
 			insta.Discover.Error())
 	}
 
-	err = insta.getConfig()
+	err = insta.getConfig(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching config", err)
 	}
 
 	// no clue what theses values could be used for
-	_, err = insta.getScoresBootstrapUsers()
+	_, err = insta.getScoresBootstrapUsers(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while fetching bootstrap user scores", err)
 	}
 
-	if !insta.Activity.Next() {
+	if !insta.Activity.NextCtx(ctx) {
 		return errors.New("Failed to fetch recent activity: " +
 			insta.Activity.err.Error())
 	}
 
-	err = insta.sendAdID()
+	err = insta.sendAdID(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while sending ad id", err)
 	}
 
-	err = insta.callStClPushPerm()
+	err = insta.callStClPushPerm(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while calling store client push permissions", err)
 	}
 
-	if !insta.Inbox.InitialSnapshot() {
+	if !insta.Inbox.InitialSnapshotCtx(ctx) {
 		return errors.New("Failed to fetch initial messages inbox snapshot: " +
 			insta.Inbox.err.Error())
 	}
 
-	err = insta.callContPointSig()
+	err = insta.callContPointSig(ctx)
 	if err != nil {
 		insta.WarnHandler("Non fatal error while calling contact point signal:", err)
 	}
@@ -573,7 +676,7 @@ func (insta *Instagram) OpenApp() (err error) {
 	return nil
 }
 
-func (insta *Instagram) login() error {
+func (insta *Instagram) login(ctx context.Context) error {
 	timestamp := strconv.Itoa(int(time.Now().Unix()))
 	if insta.pubKey == "" || insta.pubKeyID == 0 {
 		return errors.New(
@@ -602,17 +705,27 @@ func (insta *Instagram) login() error {
 	if err != nil {
 		return err
 	}
+	insta.TwoFactorInfo = nil
+	insta.Challenge = newChallenge(insta)
 	body, h, reqErr := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlLogin,
 			Query:    map[string]string{"signed_body": "SIGNATURE." + string(result)},
 			IsPost:   true,
+			Context:  ctx,
 		},
 	)
 	h.Clone()
 
 	insta.pass = ""
 	err = insta.verifyLogin(body)
+	if err != nil && insta.TwoFactorInfo != nil && insta.TwoFactorInfo.TotpTwoFactorOn &&
+		(insta.totpSecret != "" || insta.totpSealed != "") {
+		return insta.TwoFactorInfo.loginTOTP(ctx)
+	}
+	if err != nil && insta.challengeResolver != nil && insta.Challenge.Pending() {
+		return insta.Challenge.Resolve(ctx, insta.challengeResolver)
+	}
 	if err == nil && reqErr != nil {
 		return reqErr
 	}
@@ -648,14 +761,21 @@ func (insta *Instagram) verifyLogin(body []byte) error {
 		return err
 	}
 
-	insta.Account = &res.Account
+	insta.loggedIn(&res.Account)
+	return nil
+}
+
+// loggedIn sets the account after a successful login
+func (insta *Instagram) loggedIn(account *Account) {
+	insta.Account = account
 	insta.Account.insta = insta
 	insta.rankToken = strconv.FormatInt(insta.Account.ID, 10) + "_" + insta.uuid
-
-	return nil
+	insta.ResetHealth()
+	atomic.AddUint32(&insta.sessionGen, 1)
+	insta.autoSave()
 }
 
-func (insta *Instagram) getPrefill() error {
+func (insta *Instagram) getPrefill(ctx context.Context) error {
 	data, err := json.Marshal(
 		map[string]string{
 			"android_device_id": insta.dID,
@@ -671,15 +791,17 @@ func (insta *Instagram) getPrefill() error {
 	// ignore the error returned by the request, because 429 if often returned
 	insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlGetPrefill,
-			IsPost:   true,
-			Query:    generateSignature(data),
+			Endpoint:   urlGetPrefill,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      generateSignature(data),
+			Context:    ctx,
 		},
 	)
 	return nil
 }
 
-func (insta *Instagram) contactPrefill() error {
+func (insta *Instagram) contactPrefill(ctx context.Context) error {
 	data, err := json.Marshal(
 		map[string]string{
 			"phone_id": insta.fID,
@@ -693,19 +815,22 @@ func (insta *Instagram) contactPrefill() error {
 	// ignore the error returned by the request, because 429 if often returned
 	insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlContactPrefill,
-			IsPost:   true,
-			Query:    generateSignature(data),
+			Endpoint:   urlContactPrefill,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      generateSignature(data),
+			Context:    ctx,
 		},
 	)
 	return nil
 }
 
-func (insta *Instagram) zrToken() error {
+func (insta *Instagram) zrToken(ctx context.Context) error {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlZrToken,
 			IsPost:   false,
+			Context:  ctx,
 			Query: map[string]string{
 				"device_id":        insta.dID,
 				"token_hash":       "",
@@ -741,22 +866,26 @@ func (insta *Instagram) zrToken() error {
 	return err
 }
 
-func (insta *Instagram) sendAdID() error {
+func (insta *Instagram) sendAdID(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlLogAttribution,
-			IsPost:   true,
-			Query:    map[string]string{"signed_body": "SIGNATURE.{}"},
+			Endpoint:   urlLogAttribution,
+			Context:    ctx,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      map[string]string{"signed_body": "SIGNATURE.{}"},
 		},
 	)
 	return err
 }
 
-func (insta *Instagram) callStClPushPerm() error {
+func (insta *Instagram) callStClPushPerm(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlStoreClientPushPermissions,
-			IsPost:   true,
+			Endpoint:   urlStoreClientPushPermissions,
+			Context:    ctx,
+			IsPost:     true,
+			Idempotent: true,
 			Query: map[string]string{
 				"enabled":   "true",
 				"device_id": insta.uuid,
@@ -767,7 +896,7 @@ func (insta *Instagram) callStClPushPerm() error {
 	return err
 }
 
-func (insta *Instagram) sync(args ...map[string]string) error {
+func (insta *Instagram) sync(ctx context.Context, args ...map[string]string) error {
 	var query map[string]string
 	if insta.Account == nil {
 		query = map[string]string{
@@ -790,9 +919,11 @@ func (insta *Instagram) sync(args ...map[string]string) error {
 
 	_, h, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlSync,
-			Query:    generateSignature(data),
-			IsPost:   true,
+			Endpoint:   urlSync,
+			Query:      generateSignature(data),
+			IsPost:     true,
+			Idempotent: true,
+			Context:    ctx,
 			IgnoreHeaders: []string{
 				"Authorization",
 			},
@@ -821,29 +952,32 @@ func (insta *Instagram) sync(args ...map[string]string) error {
 	return nil
 }
 
-func (insta *Instagram) getAccountFamily() error {
+func (insta *Instagram) getAccountFamily(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlGetAccFamily,
+			Context:  ctx,
 		},
 	)
 	return err
 }
 
-func (insta *Instagram) getNdxSteps() error {
+func (insta *Instagram) getNdxSteps(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlGetNdxSteps,
+			Context:  ctx,
 		},
 	)
 	return err
 }
 
-func (insta *Instagram) banyan() error {
+func (insta *Instagram) banyan(ctx context.Context) error {
 	// TODO: process body, and put the data in a struct
 	_, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlBanyan,
+			Context:  ctx,
 			Query: map[string]string{
 				"views": `["story_share_sheet","direct_user_search_nullstate","forwarding_recipient_sheet","threads_people_picker","direct_inbox_active_now","group_stories_share_sheet","call_recipients","reshare_share_sheet","direct_user_search_keypressed"]`,
 			},
@@ -852,11 +986,13 @@ func (insta *Instagram) banyan() error {
 	return err
 }
 
-func (insta *Instagram) callNotifBadge() error {
+func (insta *Instagram) callNotifBadge(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlNotifBadge,
-			IsPost:   true,
+			Endpoint:   urlNotifBadge,
+			Context:    ctx,
+			IsPost:     true,
+			Idempotent: true,
 			Query: map[string]string{
 				"phone_id":  insta.fID,
 				"user_ids":  strconv.Itoa(int(insta.Account.ID)),
@@ -868,7 +1004,7 @@ func (insta *Instagram) callNotifBadge() error {
 	return err
 }
 
-func (insta *Instagram) callContPointSig() error {
+func (insta *Instagram) callContPointSig(ctx context.Context) error {
 	query := map[string]string{
 		"phone_id":      insta.fID,
 		"_uid":          strconv.Itoa(int(insta.Account.ID)),
@@ -882,27 +1018,31 @@ func (insta *Instagram) callContPointSig() error {
 	}
 	_, _, err = insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlProcessContactPointSignals,
-			IsPost:   true,
-			Query:    map[string]string{"signed_body": "SIGNATURE." + string(b)},
+			Endpoint:   urlProcessContactPointSignals,
+			Context:    ctx,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      map[string]string{"signed_body": "SIGNATURE." + string(b)},
 		},
 	)
 	return err
 }
 
-func (insta *Instagram) callMediaBlocked() error {
+func (insta *Instagram) callMediaBlocked(ctx context.Context) error {
 	_, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlMediaBlocked,
+			Context:  ctx,
 		},
 	)
 	return err
 }
 
-func (insta *Instagram) getCooldowns() (*Cooldowns, error) {
+func (insta *Instagram) getCooldowns(ctx context.Context) (*Cooldowns, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlCooldowns,
+			Context:  ctx,
 			Query: map[string]string{
 				"signed_body": "SIGNATURE.{}",
 			},
@@ -921,10 +1061,11 @@ func (insta *Instagram) getCooldowns() (*Cooldowns, error) {
 	return &temp, nil
 }
 
-func (insta *Instagram) getScoresBootstrapUsers() (*ScoresBootstrapUsers, error) {
+func (insta *Instagram) getScoresBootstrapUsers(ctx context.Context) (*ScoresBootstrapUsers, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlCooldowns,
+			Context:  ctx,
 			Query: map[string]string{
 				"surfaces": `["autocomplete_user_list","coefficient_besties_list_ranking","coefficient_rank_recipient_user_suggestion","coefficient_ios_section_test_bootstrap_ranking","coefficient_direct_recipients_ranking_variant_2"]`,
 			},
@@ -947,12 +1088,13 @@ func (insta *Instagram) getScoresBootstrapUsers() (*ScoresBootstrapUsers, error)
 	return &s, nil
 }
 
-func (insta *Instagram) getConfig() error {
+func (insta *Instagram) getConfig(ctx context.Context) error {
 	// returns a bunch of values with single letter labels
 	// see unparsedResp/loom_fetch_config/*.json for examples
 	_, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlFetchConfig,
+			Context:  ctx,
 		},
 	)
 	return err
@@ -0,0 +1,178 @@
+// Package goinstatest provides helpers to test code that uses goinsta, without
+//   talking to Instagram.
+//
+// A Recorder wraps a real http transport and writes every request/response
+//   pair to a fixture file, with credentials, authorization headers and
+//   cookies redacted. A Replayer serves those fixtures back. Both can be used
+//   with Instagram.SetHTTPTransport.
+//
+package goinstatest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+// Redacted is the placeholder for all redacted values
+const Redacted = "REDACTED"
+
+const bearerPrefix = "Bearer IGT:2:"
+
+// redactedAuth is used for redacted authorization headers. goinsta parses
+//   these headers, so the structure of a bearer token needs to be kept.
+const redactedAuth = bearerPrefix + Redacted
+
+// Interaction is a single recorded request/response pair, as stored in a
+//   fixture file. Fixture files contain one JSON encoded interaction per line.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a redacted copy of a request sent to Instagram
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	// Form holds the decoded body of url encoded requests
+	Form map[string]string `json:"form,omitempty"`
+	// Body holds the body of all other requests
+	Body []byte `json:"body,omitempty"`
+}
+
+// RecordedResponse is a redacted copy of a response sent by Instagram. The
+//   body is always stored uncompressed.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// sensitiveHeaders are redacted in both requests and responses
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Ig-Set-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Ig-Www-Claim",
+	"X-Ig-Set-Www-Claim",
+	"Ig-Set-Ig-U-Rur",
+	"Ig-U-Rur",
+	"Ig-Set-Ig-U-Shbid",
+	"Ig-U-Shbid",
+	"Ig-Set-Ig-U-Shbts",
+	"Ig-U-Shbts",
+}
+
+// sensitiveFields are redacted from request forms, and the signed_body JSON
+var sensitiveFields = []string{
+	"password",
+	"enc_password",
+	"enc_new_password1",
+	"enc_new_password2",
+	"enc_old_password",
+	"verification_code",
+	"security_code",
+	"two_factor_identifier",
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range sensitiveHeaders {
+		if _, ok := h[k]; ok {
+			h.Set(k, redactedHeader(k))
+		}
+	}
+	return h
+}
+
+func redactedHeader(k string) string {
+	if strings.HasSuffix(k, "Authorization") {
+		return redactedAuth
+	}
+	return Redacted
+}
+
+func redactForm(v url.Values) map[string]string {
+	form := make(map[string]string, len(v))
+	for k := range v {
+		form[k] = v.Get(k)
+	}
+	for _, k := range sensitiveFields {
+		if _, ok := form[k]; ok {
+			form[k] = Redacted
+		}
+	}
+
+	// signed_body is formatted as SIGNATURE.<json>
+	if s, ok := form["signed_body"]; ok {
+		if i := strings.IndexByte(s, '.'); i != -1 {
+			form["signed_body"] = s[:i+1] + redactJSON(s[i+1:])
+		}
+	}
+	return form
+}
+
+func redactJSON(s string) string {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return s
+	}
+	for _, k := range sensitiveFields {
+		if _, ok := m[k]; ok {
+			m[k] = Redacted
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return s
+	}
+	return string(b)
+}
+
+// RedactConfig removes the session tokens and cookies from an exported
+//   config, so it can be stored next to fixture files, and used for replays.
+func RedactConfig(config *goinsta.ConfigFile) {
+	for _, k := range sensitiveHeaders {
+		if _, ok := config.HeaderOptions[k]; ok {
+			config.HeaderOptions[k] = redactedHeader(k)
+		}
+	}
+	for _, c := range config.Cookies {
+		c.Value = Redacted
+	}
+	if config.TOTPSecret != "" {
+		config.TOTPSecret = Redacted
+	}
+}
+
+// ReadFixture reads all interactions from a fixture file
+func ReadFixture(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(line, &i); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, scanner.Err()
+}
@@ -0,0 +1,1446 @@
+package goinstatest
+
+import (
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+// call holds the state of a single request to the fake server
+type call struct {
+	r      *http.Request
+	header http.Header
+	viewer *user
+	args   []string
+	form   url.Values
+	signed map[string]interface{}
+	body   []byte
+}
+
+type handler func(s *Server, c *call) (int, interface{})
+
+type route struct {
+	method string
+	path   *regexp.Regexp
+	public bool
+	handle handler
+}
+
+func newRoute(method, path string, public bool, h handler) route {
+	return route{
+		method: method,
+		path:   regexp.MustCompile("^" + path + "$"),
+		public: public,
+		handle: h,
+	}
+}
+
+var routes = []route{
+	// login sequence
+	newRoute("GET", "/api/v1/zr/token/result/", true, (*Server).zrToken),
+	newRoute("POST", "/api/v1/launcher/sync/", true, (*Server).sync),
+	newRoute("POST", "/api/v1/accounts/get_prefill_candidates/", true, (*Server).empty),
+	newRoute("POST", "/api/v1/accounts/contact_point_prefill/", true, (*Server).empty),
+	newRoute("POST", "/api/v1/accounts/login/", true, (*Server).login),
+	newRoute("POST", "/api/v1/accounts/two_factor_login/", true, (*Server).twoFactorLogin),
+	newRoute("GET", "/api/v1/challenge/([0-9]+)/([^/]+)/", true, (*Server).challengeState),
+	newRoute("POST", "/api/v1/challenge/([0-9]+)/([^/]+)/", true, (*Server).challengeSubmit),
+	newRoute("GET", "/api/v1/accounts/logout/", false, (*Server).logout),
+	newRoute("GET", "/api/v1/accounts/current_user/", false, (*Server).currentUser),
+
+	// sign up
+	newRoute("POST", "/api/v1/users/check_email/", true, (*Server).checkEmail),
+	newRoute("POST", "/api/v1/accounts/send_verify_email/", true, (*Server).sendVerifyEmail),
+	newRoute("POST", "/api/v1/accounts/check_confirmation_code/", true, (*Server).checkConfirmationCode),
+	newRoute("POST", "/api/v1/accounts/check_phone_number/", true, (*Server).empty),
+	newRoute("POST", "/api/v1/accounts/send_signup_sms_code/", true, (*Server).sendSignupSMSCode),
+	newRoute("POST", "/api/v1/accounts/validate_signup_sms_code/", true, (*Server).validateSignupSMSCode),
+	newRoute("GET", "/api/v1/consent/get_signup_config/", true, (*Server).signupConfig),
+	newRoute("POST", "/api/v1/consent/check_age_eligibility/", true, (*Server).checkAgeEligibility),
+	newRoute("POST", "/api/v1/accounts/username_suggestions/", true, (*Server).usernameSuggestions),
+	newRoute("POST", "/api/v1/users/check_username/", true, (*Server).checkUsername),
+	newRoute("POST", "/api/v1/accounts/create/", true, (*Server).createAccount),
+	newRoute("POST", "/api/v1/accounts/create_validated/", true, (*Server).createAccount),
+
+	// account recovery
+	newRoute("POST", "/api/v1/users/lookup/", true, (*Server).lookup),
+	newRoute("POST", "/api/v1/accounts/send_recovery_flow_email/", true, (*Server).sendRecoveryEmail),
+	newRoute("POST", "/api/v1/users/lookup_phone/", true, (*Server).sendRecoverySMS),
+	newRoute("POST", "/api/v1/accounts/account_recovery_code_verify/", true, (*Server).recoveryCodeVerify),
+	newRoute("POST", "/api/v1/accounts/password_reset/", true, (*Server).passwordReset),
+
+	// two factor settings
+	newRoute("POST", "/api/v1/accounts/account_security_info/", false, (*Server).securityInfo),
+	newRoute("POST", "/api/v1/accounts/generate_two_factor_totp_key/", false, (*Server).generateTOTPKey),
+	newRoute("POST", "/api/v1/accounts/enable_totp_two_factor/", false, (*Server).enableTOTP),
+	newRoute("POST", "/api/v1/accounts/disable_totp_two_factor/", false, (*Server).disableTOTP),
+	newRoute("POST", "/api/v1/accounts/regen_backup_codes/", false, (*Server).regenBackupCodes),
+
+	// login activity
+	newRoute("GET", "/api/v1/session/login_activity/", false, (*Server).loginActivity),
+	newRoute("POST", "/api/v1/session/login_activity/avow_login/", false, (*Server).avowLogin),
+	newRoute("POST", "/api/v1/session/login_activity/undo_avow_login/", false, (*Server).undoAvowLogin),
+	newRoute("POST", "/api/v1/session/login_activity/logout_session/", false, (*Server).logoutSession),
+
+	// users
+	newRoute("GET", "/api/v1/users/([^/]+)/usernameinfo/", false, (*Server).userByNameInfo),
+	newRoute("GET", "/api/v1/users/([0-9]+)/info/", false, (*Server).userInfo),
+	newRoute("POST", "/api/v1/friendships/create/([0-9]+)/", false, (*Server).follow),
+	newRoute("POST", "/api/v1/friendships/destroy/([0-9]+)/", false, (*Server).unfollow),
+	newRoute("GET", "/api/v1/friendships/show/([0-9]+)/", false, (*Server).friendshipShow),
+	newRoute("GET", "/api/v1/friendships/([0-9]+)/followers/", false, (*Server).followers),
+	newRoute("GET", "/api/v1/friendships/([0-9]+)/following/", false, (*Server).following),
+
+	// media
+	newRoute("GET", "/api/v1/feed/user/([0-9]+)/", false, (*Server).userFeed),
+	newRoute("GET", "/api/v1/media/([^/]+)/info/", false, (*Server).mediaInfo),
+	newRoute("POST", "/api/v1/media/([^/]+)/like/", false, (*Server).like),
+	newRoute("POST", "/api/v1/media/([^/]+)/unlike/", false, (*Server).unlike),
+
+	// direct
+	newRoute("GET", "/api/v1/direct_v2/inbox/", false, (*Server).inbox),
+	newRoute("GET", "/api/v1/direct_v2/pending_inbox/", false, (*Server).pendingInbox),
+	newRoute("GET", "/api/v1/direct_v2/threads/get_by_participants/", false, (*Server).threadByParticipants),
+	newRoute("POST", "/api/v1/direct_v2/threads/broadcast/text/", false, (*Server).sendText),
+	newRoute("GET", "/api/v1/direct_v2/threads/([^/]+)/", false, (*Server).thread),
+
+	// uploads
+	newRoute("POST", "/rupload_igphoto/(.+)", false, (*Server).uploadPhoto),
+	newRoute("GET", "/rupload_igvideo/(.+)", false, (*Server).videoOffset),
+	newRoute("POST", "/rupload_igvideo/(.+)", false, (*Server).uploadVideo),
+	newRoute("POST", "/api/v1/media/upload_finish/", false, (*Server).uploadFinish),
+	newRoute("POST", "/api/v1/media/configure/", false, (*Server).configure),
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &call{r: r, header: w.Header()}
+	if err := c.parse(); err != nil {
+		writeJSON(w, 400, fail(err.Error()))
+		return
+	}
+
+	for _, rt := range routes {
+		if rt.method != r.Method {
+			continue
+		}
+		m := rt.path.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			continue
+		}
+		c.args = m[1:]
+		if !rt.public {
+			c.viewer = s.viewer(r)
+			if c.viewer == nil {
+				writeJSON(w, 403, map[string]interface{}{
+					"message":       "login_required",
+					"logout_reason": 2,
+					"status":        "fail",
+				})
+				return
+			}
+		}
+		code, resp := rt.handle(s, c)
+		writeJSON(w, code, resp)
+		return
+	}
+	writeJSON(w, 404, fail("goinstatest: endpoint not implemented: "+r.Method+" "+r.URL.Path))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		code = 500
+		b = []byte(`{"status":"fail"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+func fail(msg string) map[string]interface{} {
+	return map[string]interface{}{"message": msg, "status": "fail"}
+}
+
+func statusOK() map[string]interface{} {
+	return map[string]interface{}{"status": "ok"}
+}
+
+// parse reads the request body, and decodes form values and the signed body
+func (c *call) parse() error {
+	if c.r.Body == nil {
+		return nil
+	}
+	body := c.r.Body
+	if c.r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		body = zr
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	c.body = b
+
+	ct := c.r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+		return nil
+	}
+	c.form, err = url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	if sb := c.form.Get("signed_body"); sb != "" {
+		if i := strings.IndexByte(sb, '.'); i != -1 {
+			c.signed = map[string]interface{}{}
+			return json.Unmarshal([]byte(sb[i+1:]), &c.signed)
+		}
+	}
+	return nil
+}
+
+// param returns a parameter from the signed body, form, or url query
+func (c *call) param(k string) string {
+	if v, ok := c.signed[k]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprint(v)
+	}
+	if v := c.form.Get(k); v != "" {
+		return v
+	}
+	return c.r.URL.Query().Get(k)
+}
+
+func (c *call) argID() int64 {
+	id, _ := strconv.ParseInt(c.args[0], 10, 64)
+	return id
+}
+
+func (s *Server) viewer(r *http.Request) *user {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), bearerPrefix)
+	sess, ok := s.sessions[auth]
+	if !ok {
+		return nil
+	}
+	return s.users[sess.userID]
+}
+
+func (s *Server) empty(c *call) (int, interface{}) {
+	return 200, statusOK()
+}
+
+func (s *Server) zrToken(c *call) (int, interface{}) {
+	return 200, map[string]interface{}{
+		"token": map[string]interface{}{
+			"ttl":          86400,
+			"request_time": time.Now().Unix(),
+			"token_hash":   "",
+		},
+		"status": "ok",
+	}
+}
+
+func (s *Server) sync(c *call) (int, interface{}) {
+	c.header.Set("Ig-Set-Password-Encryption-Pub-Key", s.publicKey())
+	c.header.Set("Ig-Set-Password-Encryption-Key-Id", strconv.Itoa(s.keyID))
+	return 200, map[string]interface{}{"configs": map[string]interface{}{}, "status": "ok"}
+}
+
+func (s *Server) login(c *call) (int, interface{}) {
+	u := s.userByName(c.param("username"))
+	if u == nil {
+		return 400, map[string]interface{}{
+			"message":             "The username you entered doesn't appear to belong to an account.",
+			"invalid_credentials": true,
+			"error_type":          "invalid_user",
+			"status":              "fail",
+		}
+	}
+	pass, err := s.decryptPassword(c.param("enc_password"))
+	if err != nil {
+		return 400, fail(err.Error())
+	}
+	if pass != u.Password {
+		return 400, map[string]interface{}{
+			"message":             "The password you entered is incorrect. Please try again.",
+			"invalid_credentials": true,
+			"error_type":          "bad_password",
+			"status":              "fail",
+		}
+	}
+
+	if u.TOTPSecret != "" {
+		id := strconv.FormatInt(s.newID(), 10)
+		s.twoFactor[id] = u.ID
+		return 400, map[string]interface{}{
+			"message":             "",
+			"two_factor_required": true,
+			"two_factor_info": map[string]interface{}{
+				"username":              u.Username,
+				"totp_two_factor_on":    true,
+				"sms_two_factor_on":     false,
+				"two_factor_identifier": id,
+			},
+			"error_type": "two_factor_required",
+			"status":     "fail",
+		}
+	}
+	if u.Checkpoint {
+		nonce := strconv.FormatInt(s.newID(), 36)
+		s.challenges[nonce] = &challenge{userID: u.ID, step: "select_verify_method"}
+		path := fmt.Sprintf("/challenge/%d/%s/", u.ID, nonce)
+		return 400, map[string]interface{}{
+			"message": "challenge_required",
+			"challenge": map[string]interface{}{
+				"url":         "https://i.instagram.com" + path,
+				"api_path":    path,
+				"lock":        true,
+				"logout":      false,
+				"native_flow": true,
+			},
+			"error_type": "checkpoint_challenge_required",
+			"status":     "fail",
+		}
+	}
+	return s.loggedIn(c, u)
+}
+
+func (s *Server) challengeState(c *call) (int, interface{}) {
+	ch, ok := s.challenges[c.args[1]]
+	if !ok || ch.userID != c.argID() {
+		return 404, fail("Challenge not found")
+	}
+	return 200, s.challengeJSON(c.args[1], ch)
+}
+
+func (s *Server) challengeSubmit(c *call) (int, interface{}) {
+	nonce := c.args[1]
+	ch, ok := s.challenges[nonce]
+	if !ok || ch.userID != c.argID() {
+		return 404, fail("Challenge not found")
+	}
+
+	switch ch.step {
+	case "select_verify_method":
+		if c.param("choice") == "" {
+			return 400, fail("No verification method selected")
+		}
+		code, err := randomCode()
+		if err != nil {
+			return 500, fail(err.Error())
+		}
+		ch.code = code
+		ch.step = "verify_email"
+		return 200, s.challengeJSON(nonce, ch)
+	case "verify_email":
+		if c.param("security_code") != ch.code {
+			return 400, map[string]interface{}{
+				"message":    "Please check the code we sent you and try again.",
+				"error_type": "invalid_security_code",
+				"status":     "fail",
+			}
+		}
+		u := s.users[ch.userID]
+		if u.ChangePassword {
+			ch.step = "change_password"
+			return 200, s.challengeJSON(nonce, ch)
+		}
+		delete(s.challenges, nonce)
+		u.Checkpoint = false
+		code, resp := s.loggedIn(c, u)
+		resp["action"] = "close"
+		return code, resp
+	case "change_password":
+		pass, err := s.decryptPassword(c.param("enc_new_password1"))
+		if err != nil || c.param("enc_new_password2") == "" {
+			return 400, fail("Invalid password")
+		}
+		delete(s.challenges, nonce)
+		u := s.users[ch.userID]
+		u.Password = pass
+		u.Checkpoint = false
+		u.ChangePassword = false
+		code, resp := s.loggedIn(c, u)
+		resp["action"] = "close"
+		return code, resp
+	}
+	return 500, fail("Unknown challenge step " + ch.step)
+}
+
+func (s *Server) challengeJSON(nonce string, ch *challenge) map[string]interface{} {
+	return map[string]interface{}{
+		"step_name": ch.step,
+		"step_data": map[string]interface{}{
+			"choice":       "1",
+			"email":        "a***@example.com",
+			"phone_number": "+1 *** ***-**00",
+		},
+		"user_id":    ch.userID,
+		"nonce_code": nonce,
+		"status":     "ok",
+	}
+}
+
+// randomCode returns a six digit security code
+func randomCode() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", (int(b[0])<<16|int(b[1])<<8|int(b[2]))%1000000), nil
+}
+
+func (s *Server) twoFactorLogin(c *call) (int, interface{}) {
+	id, ok := s.twoFactor[c.param("two_factor_identifier")]
+	if !ok {
+		return 400, fail("Invalid two factor identifier")
+	}
+	u := s.users[id]
+	code, err := goinsta.GenerateTOTP(u.TOTPSecret, time.Now().Add(s.ClockSkew))
+	if err != nil {
+		return 500, fail(err.Error())
+	}
+	if c.param("verification_code") != code {
+		return 400, map[string]interface{}{
+			"message":    "Please check the security code and try again.",
+			"error_type": "sms_code_validation_code_invalid",
+			"status":     "fail",
+		}
+	}
+	delete(s.twoFactor, c.param("two_factor_identifier"))
+	return s.loggedIn(c, u)
+}
+
+// loggedIn creates a new session for the user
+func (s *Server) loggedIn(c *call, u *user) (int, map[string]interface{}) {
+	token, err := s.newSession(u, c.r.UserAgent())
+	if err != nil {
+		return 500, fail(err.Error())
+	}
+	c.header.Set("Ig-Set-Authorization", bearerPrefix+token)
+	c.header.Set("Ig-Set-Ig-U-Ds-User-Id", strconv.FormatInt(u.ID, 10))
+	return 200, map[string]interface{}{
+		"logged_in_user": s.userJSON(u, u),
+		"status":         "ok",
+	}
+}
+
+func (s *Server) newSession(u *user, userAgent string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	cookie, err := json.Marshal(map[string]string{
+		"ds_user_id": strconv.FormatInt(u.ID, 10),
+		"sessionid":  strconv.FormatInt(u.ID, 10) + "%3A" + hex.EncodeToString(b),
+	})
+	if err != nil {
+		return "", err
+	}
+	token := base64.StdEncoding.EncodeToString(cookie)
+	s.sessions[token] = &session{
+		id:        strconv.FormatInt(s.newID(), 10),
+		userID:    u.ID,
+		device:    userAgent,
+		timestamp: time.Now().Unix(),
+	}
+	return token, nil
+}
+
+// decryptPassword decrypts a password encrypted by utilities.EncryptPassword.
+//
+// Format: #PWD_INSTAGRAM:4:<time>:<base64>, where the decoded data is
+//   1 | key id | iv (12) | key size (2) | rsa encrypted key | tag (16) | data
+func (s *Server) decryptPassword(enc string) (string, error) {
+	parts := strings.SplitN(enc, ":", 4)
+	if len(parts) != 4 || parts[0] != "#PWD_INSTAGRAM" {
+		return "", errors.New("invalid password encryption")
+	}
+	if parts[1] == "0" {
+		return parts[3], nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", err
+	}
+	if len(b) < 16 {
+		return "", errors.New("encrypted password too short")
+	}
+	iv := b[2:14]
+	size := int(binary.LittleEndian.Uint16(b[14:16]))
+	if len(b) < 16+size+16 {
+		return "", errors.New("encrypted password too short")
+	}
+	encKey := b[16 : 16+size]
+	tag := b[16+size : 16+size+16]
+	data := b[16+size+16:]
+
+	key, err := rsa.DecryptPKCS1v15(nil, s.key, encKey)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	sealed := append(append([]byte{}, data...), tag...)
+	plain, err := gcm.Open(nil, iv, sealed, []byte(parts[2]))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func (s *Server) logout(c *call) (int, interface{}) {
+	for k, v := range s.sessions {
+		if v.userID == c.viewer.ID {
+			delete(s.sessions, k)
+		}
+	}
+	return 200, statusOK()
+}
+
+func (s *Server) currentUser(c *call) (int, interface{}) {
+	return 200, map[string]interface{}{
+		"user":   s.userJSON(c.viewer, c.viewer),
+		"status": "ok",
+	}
+}
+
+func (s *Server) checkEmail(c *call) (int, interface{}) {
+	email := c.param("email")
+	resp := map[string]interface{}{
+		"valid":     strings.Contains(email, "@"),
+		"available": true,
+		"status":    "ok",
+	}
+	for _, u := range s.users {
+		if u.Email != "" && u.Email == email {
+			resp["available"] = false
+			resp["error_type"] = "email_is_taken"
+		}
+	}
+	return 200, resp
+}
+
+// sendCode creates a pending sign up, with a new code sent to contact
+func (s *Server) sendCode(contact string) error {
+	code, err := randomCode()
+	if err != nil {
+		return err
+	}
+	s.signups[contact] = &signup{code: code}
+	return nil
+}
+
+func (s *Server) sendVerifyEmail(c *call) (int, interface{}) {
+	if err := s.sendCode(c.param("email")); err != nil {
+		return 500, fail(err.Error())
+	}
+	return 200, map[string]interface{}{"email_sent": true, "status": "ok"}
+}
+
+func (s *Server) checkConfirmationCode(c *call) (int, interface{}) {
+	su, ok := s.signups[c.param("email")]
+	if !ok || su.code != c.param("code") {
+		return 400, map[string]interface{}{
+			"message":    "That code isn't valid. You can request a new one.",
+			"error_type": "invalid_nonce",
+			"status":     "fail",
+		}
+	}
+	su.confirmed = true
+	su.signupCode = strconv.FormatInt(s.newID(), 36)
+	return 200, map[string]interface{}{"signup_code": su.signupCode, "status": "ok"}
+}
+
+func (s *Server) sendSignupSMSCode(c *call) (int, interface{}) {
+	if err := s.sendCode(c.param("phone_number")); err != nil {
+		return 500, fail(err.Error())
+	}
+	return 200, statusOK()
+}
+
+func (s *Server) validateSignupSMSCode(c *call) (int, interface{}) {
+	su, ok := s.signups[c.param("phone_number")]
+	if !ok || su.code != c.param("verification_code") {
+		return 400, map[string]interface{}{
+			"message":    "That code isn't valid. You can request a new one.",
+			"error_type": "invalid_nonce",
+			"status":     "fail",
+		}
+	}
+	su.confirmed = true
+	return 200, map[string]interface{}{"verified": true, "status": "ok"}
+}
+
+func (s *Server) signupConfig(c *call) (int, interface{}) {
+	return 200, map[string]interface{}{
+		"age_required": true,
+		"tos_version":  "row",
+		"status":       "ok",
+	}
+}
+
+// checkAgeEligibility requires users to be at least 13 years old
+func (s *Server) checkAgeEligibility(c *call) (int, interface{}) {
+	day, _ := strconv.Atoi(c.param("day"))
+	month, _ := strconv.Atoi(c.param("month"))
+	year, _ := strconv.Atoi(c.param("year"))
+	birthday := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return 200, map[string]interface{}{
+		"eligible_to_register": year > 0 && !birthday.AddDate(13, 0, 0).After(time.Now()),
+		"status":               "ok",
+	}
+}
+
+func (s *Server) usernameSuggestions(c *call) (int, interface{}) {
+	base := strings.ToLower(strings.Join(strings.Fields(c.param("name")), "."))
+	if base == "" {
+		base = strings.SplitN(c.param("email"), "@", 2)[0]
+	}
+	var suggestions []map[string]interface{}
+	for i := 0; len(suggestions) < 3 && i < 100; i++ {
+		name := base
+		if i > 0 {
+			name = fmt.Sprintf("%s%d", base, i)
+		}
+		if name == "" || s.userByName(name) != nil {
+			continue
+		}
+		suggestions = append(suggestions, map[string]interface{}{
+			"username":   name,
+			"prototype":  base,
+			"confidence": 1 / float64(i+1),
+		})
+	}
+	return 200, map[string]interface{}{
+		"suggestions_with_metadata": map[string]interface{}{"suggestions": suggestions},
+		"status":                    "ok",
+	}
+}
+
+func (s *Server) checkUsername(c *call) (int, interface{}) {
+	username := c.param("username")
+	taken := s.userByName(username) != nil
+	resp := map[string]interface{}{
+		"username":  username,
+		"available": !taken,
+		"status":    "ok",
+	}
+	if taken {
+		resp["error_type"] = "username_is_taken"
+	}
+	return 200, resp
+}
+
+func (s *Server) createAccount(c *call) (int, interface{}) {
+	errs := map[string][]string{}
+	u := User{
+		Username:    c.param("username"),
+		FullName:    c.param("first_name"),
+		Email:       c.param("email"),
+		PhoneNumber: c.param("phone_number"),
+	}
+
+	var su *signup
+	if u.Email != "" {
+		su = s.signups[u.Email]
+		if su == nil || !su.confirmed || su.signupCode != c.param("force_sign_up_code") {
+			errs["email"] = []string{"The email address has not been confirmed."}
+		}
+	} else {
+		su = s.signups[u.PhoneNumber]
+		if su == nil || !su.confirmed || su.code != c.param("verification_code") {
+			errs["phone_number"] = []string{"The phone number has not been confirmed."}
+		}
+	}
+	if u.Username == "" || s.userByName(u.Username) != nil {
+		errs["username"] = []string{"A user with that username already exists."}
+	}
+	pass, err := s.decryptPassword(c.param("enc_password"))
+	if err != nil || pass == "" {
+		errs["password"] = []string{"Create a password at least 6 characters long."}
+	}
+	if len(errs) > 0 {
+		return 200, map[string]interface{}{
+			"account_created": false,
+			"errors":          errs,
+			"error_type":      "validation_error",
+			"status":          "ok",
+		}
+	}
+
+	u.Password = pass
+	delete(s.signups, u.Email+u.PhoneNumber)
+	code, resp := s.loggedIn(c, s.addUser(u))
+	resp["created_user"] = resp["logged_in_user"]
+	resp["account_created"] = true
+	delete(resp, "logged_in_user")
+	return code, resp
+}
+
+func (s *Server) lookup(c *call) (int, interface{}) {
+	u := s.lookupUser(c.param("q"))
+	if u == nil {
+		return 404, fail("No users found")
+	}
+	return 200, map[string]interface{}{
+		"user":             s.userShortJSON(u),
+		"can_email_reset":  u.Email != "",
+		"can_sms_reset":    u.PhoneNumber != "",
+		"obfuscated_email": obfuscate(u.Email),
+		"obfuscated_phone": obfuscate(u.PhoneNumber),
+		"status":           "ok",
+	}
+}
+
+// obfuscate hides all but the first and last character of a contact
+func obfuscate(contact string) string {
+	if len(contact) < 3 {
+		return contact
+	}
+	return contact[:1] + strings.Repeat("*", len(contact)-2) + contact[len(contact)-1:]
+}
+
+func (s *Server) sendRecoveryEmail(c *call) (int, interface{}) {
+	u := s.lookupUser(c.param("query"))
+	if u == nil || u.Email == "" {
+		return 404, fail("No users found")
+	}
+	s.resets[u.ID] = &passwordReset{token: strconv.FormatInt(s.newID(), 36)}
+	return 200, map[string]interface{}{"email_sent": true, "status": "ok"}
+}
+
+func (s *Server) sendRecoverySMS(c *call) (int, interface{}) {
+	u := s.lookupUser(c.param("query"))
+	if u == nil || u.PhoneNumber == "" {
+		return 404, fail("No users found")
+	}
+	code, err := randomCode()
+	if err != nil {
+		return 500, fail(err.Error())
+	}
+	s.resets[u.ID] = &passwordReset{code: code}
+	return 200, statusOK()
+}
+
+func (s *Server) recoveryCodeVerify(c *call) (int, interface{}) {
+	u := s.lookupUser(c.param("query"))
+	if u == nil {
+		return 404, fail("No users found")
+	}
+	r, ok := s.resets[u.ID]
+	if !ok || r.code == "" || r.code != c.param("recover_code") {
+		return 400, map[string]interface{}{
+			"message":    "Please check the code we sent you and try again.",
+			"error_type": "invalid_security_code",
+			"status":     "fail",
+		}
+	}
+	r.token = strconv.FormatInt(s.newID(), 36)
+	return 200, map[string]interface{}{"user_id": u.ID, "token": r.token, "status": "ok"}
+}
+
+func (s *Server) passwordReset(c *call) (int, interface{}) {
+	id, _ := strconv.ParseInt(c.param("user_id"), 10, 64)
+	r, ok := s.resets[id]
+	if !ok || r.token == "" || r.token != c.param("token") {
+		return 400, fail("Invalid password reset token")
+	}
+	pass, err := s.decryptPassword(c.param("enc_new_password1"))
+	if err != nil {
+		return 400, fail(err.Error())
+	}
+	if pass2, _ := s.decryptPassword(c.param("enc_new_password2")); pass2 != pass {
+		return 400, fail("Please make sure both passwords match.")
+	}
+
+	delete(s.resets, id)
+	u := s.users[id]
+	u.Password = pass
+	return s.loggedIn(c, u)
+}
+
+func (s *Server) securityInfo(c *call) (int, interface{}) {
+	u := c.viewer
+	return 200, map[string]interface{}{
+		"is_phone_confirmed":         false,
+		"is_two_factor_enabled":      u.TOTPSecret != "",
+		"is_totp_two_factor_enabled": u.TOTPSecret != "",
+		"backup_codes":               u.backupCodes,
+		"trusted_devices":            []interface{}{},
+		"status":                     "ok",
+	}
+}
+
+func (s *Server) generateTOTPKey(c *call) (int, interface{}) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return 500, fail(err.Error())
+	}
+	c.viewer.totpSeed = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	return 200, map[string]interface{}{"totp_seed": c.viewer.totpSeed, "status": "ok"}
+}
+
+func (s *Server) enableTOTP(c *call) (int, interface{}) {
+	u := c.viewer
+	if u.totpSeed == "" {
+		return 400, fail("No TOTP key has been generated")
+	}
+	code, err := goinsta.GenerateTOTP(u.totpSeed, time.Now().Add(s.ClockSkew))
+	if err != nil {
+		return 500, fail(err.Error())
+	}
+	if c.param("verification_code") != code {
+		return 400, map[string]interface{}{
+			"message":    "Please check the security code and try again.",
+			"error_type": "invalid_verification_code",
+			"status":     "fail",
+		}
+	}
+	u.TOTPSecret, u.totpSeed = u.totpSeed, ""
+	return s.regenBackupCodes(c)
+}
+
+func (s *Server) disableTOTP(c *call) (int, interface{}) {
+	c.viewer.TOTPSecret = ""
+	c.viewer.backupCodes = nil
+	return 200, statusOK()
+}
+
+func (s *Server) regenBackupCodes(c *call) (int, interface{}) {
+	codes := make([]string, 5)
+	for i := range codes {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return 500, fail(err.Error())
+		}
+		codes[i] = hex.EncodeToString(b)
+	}
+	c.viewer.backupCodes = codes
+	return 200, map[string]interface{}{"backup_codes": codes, "status": "ok"}
+}
+
+func (s *Server) loginActivity(c *call) (int, interface{}) {
+	current := strings.TrimPrefix(c.r.Header.Get("Authorization"), bearerPrefix)
+	sessions := []map[string]interface{}{}
+	suspicious := []map[string]interface{}{}
+	for token, sess := range s.sessions {
+		if sess.userID != c.viewer.ID {
+			continue
+		}
+		j := map[string]interface{}{
+			"id":              sess.id,
+			"login_id":        sess.id,
+			"device":          sess.device,
+			"location":        sess.location,
+			"login_timestamp": sess.timestamp,
+			"timestamp":       sess.timestamp,
+			"is_current":      token == current,
+		}
+		sessions = append(sessions, j)
+		if sess.suspicious {
+			suspicious = append(suspicious, j)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i]["id"].(string) < sessions[j]["id"].(string)
+	})
+	return 200, map[string]interface{}{
+		"sessions":          sessions,
+		"suspicious_logins": suspicious,
+		"status":            "ok",
+	}
+}
+
+// userSession returns the token and session of the viewer with an ID
+func (s *Server) userSession(c *call, id string) (string, *session) {
+	for token, sess := range s.sessions {
+		if sess.userID == c.viewer.ID && sess.id == id {
+			return token, sess
+		}
+	}
+	return "", nil
+}
+
+func (s *Server) avowLogin(c *call) (int, interface{}) {
+	_, sess := s.userSession(c, c.param("login_id"))
+	if sess == nil || !sess.suspicious {
+		return 400, fail("Login not found")
+	}
+	sess.suspicious = false
+	return 200, statusOK()
+}
+
+func (s *Server) undoAvowLogin(c *call) (int, interface{}) {
+	token, sess := s.userSession(c, c.param("login_id"))
+	if sess == nil || !sess.suspicious {
+		return 400, fail("Login not found")
+	}
+	delete(s.sessions, token)
+	return 200, statusOK()
+}
+
+func (s *Server) logoutSession(c *call) (int, interface{}) {
+	token, sess := s.userSession(c, c.param("session_id"))
+	if sess == nil {
+		return 400, fail("Session not found")
+	}
+	if token == strings.TrimPrefix(c.r.Header.Get("Authorization"), bearerPrefix) {
+		return 400, fail("The current session can not be logged out")
+	}
+	delete(s.sessions, token)
+	return 200, statusOK()
+}
+
+func (s *Server) userByNameInfo(c *call) (int, interface{}) {
+	u := s.userByName(c.args[0])
+	if u == nil {
+		return 404, fail("User not found")
+	}
+	return 200, map[string]interface{}{"user": s.userJSON(u, c.viewer), "status": "ok"}
+}
+
+func (s *Server) userInfo(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 404, fail("User not found")
+	}
+	return 200, map[string]interface{}{"user": s.userJSON(u, c.viewer), "status": "ok"}
+}
+
+func (s *Server) follow(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok || u == c.viewer {
+		return 400, fail("Sorry, you can't follow this user")
+	}
+	if u.IsPrivate && !c.viewer.following[u.ID] {
+		c.viewer.requested[u.ID] = true
+	} else {
+		c.viewer.following[u.ID] = true
+	}
+	return 200, map[string]interface{}{
+		"friendship_status": friendship(c.viewer, u),
+		"status":            "ok",
+	}
+}
+
+func (s *Server) unfollow(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 400, fail("Sorry, you can't unfollow this user")
+	}
+	delete(c.viewer.following, u.ID)
+	delete(c.viewer.requested, u.ID)
+	return 200, map[string]interface{}{
+		"friendship_status": friendship(c.viewer, u),
+		"status":            "ok",
+	}
+}
+
+func (s *Server) friendshipShow(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 404, fail("User not found")
+	}
+	f := friendship(c.viewer, u)
+	f["status"] = "ok"
+	return 200, f
+}
+
+func (s *Server) followers(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 404, fail("User not found")
+	}
+	if !canView(c.viewer, u) {
+		return 400, fail("Not authorized to view user")
+	}
+	var users []*user
+	for _, f := range s.users {
+		if f.following[u.ID] {
+			users = append(users, f)
+		}
+	}
+	return 200, s.usersJSON(users, c.viewer)
+}
+
+func (s *Server) following(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 404, fail("User not found")
+	}
+	if !canView(c.viewer, u) {
+		return 400, fail("Not authorized to view user")
+	}
+	var users []*user
+	for id := range u.following {
+		users = append(users, s.users[id])
+	}
+	return 200, s.usersJSON(users, c.viewer)
+}
+
+// feedPageSize is the number of items returned per feed page
+const feedPageSize = 18
+
+func (s *Server) userFeed(c *call) (int, interface{}) {
+	u, ok := s.users[c.argID()]
+	if !ok {
+		return 404, fail("User not found")
+	}
+	if !canView(c.viewer, u) {
+		return 400, fail("Not authorized to view user")
+	}
+
+	start := 0
+	if maxID := c.param("max_id"); maxID != "" {
+		for i, m := range u.posts {
+			if m.id() == maxID {
+				start = i + 1
+			}
+		}
+	}
+	end := start + feedPageSize
+	if end > len(u.posts) {
+		end = len(u.posts)
+	}
+
+	items := []interface{}{}
+	for _, m := range u.posts[start:end] {
+		items = append(items, s.itemJSON(m, c.viewer))
+	}
+	resp := map[string]interface{}{
+		"items":                  items,
+		"num_results":            len(items),
+		"more_available":         end < len(u.posts),
+		"auto_load_more_enabled": true,
+		"status":                 "ok",
+	}
+	if end < len(u.posts) {
+		resp["next_max_id"] = u.posts[end-1].id()
+	}
+	return 200, resp
+}
+
+func (s *Server) mediaInfo(c *call) (int, interface{}) {
+	m, ok := s.media[c.args[0]]
+	if !ok {
+		return 400, fail("Media not found or unavailable")
+	}
+	return 200, map[string]interface{}{
+		"items":          []interface{}{s.itemJSON(m, c.viewer)},
+		"num_results":    1,
+		"more_available": false,
+		"status":         "ok",
+	}
+}
+
+func (s *Server) like(c *call) (int, interface{}) {
+	m, ok := s.media[c.args[0]]
+	if !ok {
+		return 400, fail("Sorry, this media has been deleted")
+	}
+	m.likes[c.viewer.ID] = true
+	return 200, statusOK()
+}
+
+func (s *Server) unlike(c *call) (int, interface{}) {
+	m, ok := s.media[c.args[0]]
+	if !ok {
+		return 400, fail("Sorry, this media has been deleted")
+	}
+	delete(m.likes, c.viewer.ID)
+	return 200, statusOK()
+}
+
+func (s *Server) inbox(c *call) (int, interface{}) {
+	var threads []*thread
+	for _, t := range s.threads {
+		if t.member(c.viewer) {
+			threads = append(threads, t)
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].lastActivity > threads[j].lastActivity
+	})
+	return 200, s.inboxJSON(threads, c.viewer)
+}
+
+func (s *Server) pendingInbox(c *call) (int, interface{}) {
+	return 200, s.inboxJSON(nil, c.viewer)
+}
+
+func (s *Server) threadByParticipants(c *call) (int, interface{}) {
+	var ids []int64
+	if err := json.Unmarshal([]byte(c.param("recipient_users")), &ids); err != nil || len(ids) == 0 {
+		return 400, fail("Invalid recipient_users")
+	}
+	resp := statusOK()
+	if t := s.privateThread(c.viewer.ID, ids[0]); t != nil {
+		resp["thread"] = s.threadJSON(t, c.viewer)
+	}
+	return 200, resp
+}
+
+func (s *Server) thread(c *call) (int, interface{}) {
+	t, ok := s.threads[c.args[0]]
+	if !ok || !t.member(c.viewer) {
+		return 404, fail("Thread not found")
+	}
+	return 200, map[string]interface{}{
+		"thread": s.threadJSON(t, c.viewer),
+		"status": "ok",
+	}
+}
+
+func (s *Server) sendText(c *call) (int, interface{}) {
+	var t *thread
+
+	var threadIDs []string
+	if err := json.Unmarshal([]byte(c.param("thread_ids")), &threadIDs); err == nil && len(threadIDs) > 0 {
+		if tt, ok := s.threads[threadIDs[0]]; ok && tt.member(c.viewer) {
+			t = tt
+		}
+	}
+	if t == nil {
+		var recipients [][]int64
+		if err := json.Unmarshal([]byte(c.param("recipient_users")), &recipients); err != nil {
+			return 400, fail("Invalid recipient_users")
+		}
+		users := []*user{c.viewer}
+		for _, r := range recipients {
+			for _, id := range r {
+				u, ok := s.users[id]
+				if !ok {
+					return 400, fail("User not found")
+				}
+				users = append(users, u)
+			}
+		}
+		if len(users) < 2 {
+			return 400, fail("No recipients")
+		}
+		if len(users) == 2 {
+			t = s.privateThread(users[0].ID, users[1].ID)
+		}
+		if t == nil {
+			t = &thread{id: "34028236" + strconv.FormatInt(s.newID(), 10), users: users}
+			s.threads[t.id] = t
+		}
+	}
+
+	msg := &message{
+		id:            "2" + strconv.FormatInt(s.newID(), 10),
+		userID:        c.viewer.ID,
+		text:          c.param("text"),
+		clientContext: c.param("client_context"),
+		timestamp:     s.timestamp(),
+	}
+	t.items = append([]*message{msg}, t.items...)
+	t.lastActivity = msg.timestamp
+
+	return 200, map[string]interface{}{
+		"action": "item_ack",
+		"payload": map[string]interface{}{
+			"client_context": msg.clientContext,
+			"item_id":        msg.id,
+			"thread_id":      t.id,
+			"timestamp":      strconv.FormatInt(msg.timestamp, 10),
+		},
+		"status":      "ok",
+		"status_code": "200",
+	}
+}
+
+func (s *Server) uploadPhoto(c *call) (int, interface{}) {
+	var params struct {
+		UploadID string `json:"upload_id"`
+	}
+	err := json.Unmarshal([]byte(c.r.Header.Get("X-Instagram-Rupload-Params")), &params)
+	if err != nil || params.UploadID == "" {
+		return 400, fail("Invalid rupload params")
+	}
+	if len(c.body) == 0 {
+		return 400, fail("Empty upload")
+	}
+	s.uploads[params.UploadID] = true
+	return 200, map[string]interface{}{
+		"upload_id":       params.UploadID,
+		"xsharing_nonces": map[string]interface{}{},
+		"status":          "ok",
+	}
+}
+
+// videoOffset returns the number of bytes received of a video entity
+func (s *Server) videoOffset(c *call) (int, interface{}) {
+	return 200, map[string]interface{}{"offset": len(s.entities[c.args[0]])}
+}
+
+// uploadVideo receives a video entity, which is the whole video or a segment
+//   of it. Segmented uploads are started and ended with a phase parameter.
+func (s *Server) uploadVideo(c *call) (int, interface{}) {
+	switch c.param("phase") {
+	case "start":
+		return 200, map[string]interface{}{"stream_id": s.newID(), "status": "ok"}
+	case "end":
+		return 200, statusOK()
+	}
+
+	var params struct {
+		UploadID string `json:"upload_id"`
+	}
+	err := json.Unmarshal([]byte(c.r.Header.Get("X-Instagram-Rupload-Params")), &params)
+	if err != nil || params.UploadID == "" {
+		return 400, fail("Invalid rupload params")
+	}
+	length, err := strconv.Atoi(c.r.Header.Get("X-Entity-Length"))
+	if err != nil {
+		return 400, fail("Invalid entity length")
+	}
+	name := c.args[0]
+	data := s.entities[name]
+	if c.r.Header.Get("Offset") != strconv.Itoa(len(data)) {
+		return 400, fail("Offset does not match the received bytes")
+	}
+
+	body := c.body
+	if s.interrupt > 0 && len(body) > 1 {
+		s.interrupt--
+		s.entities[name] = append(data, body[:len(body)/2]...)
+		return 500, fail("Upload interrupted")
+	}
+	data = append(data, body...)
+	if len(data) > length {
+		return 400, fail("Entity is longer than its length")
+	}
+	s.entities[name] = data
+
+	if len(data) == length {
+		video := s.videos[params.UploadID]
+		if start := c.r.Header.Get("Segment-Start-Offset"); start != "" && start != strconv.Itoa(len(video)) {
+			return 400, fail("Segment does not follow the previous one")
+		}
+		s.videos[params.UploadID] = append(video, data...)
+		s.uploads[params.UploadID] = true
+	}
+	return 200, map[string]interface{}{
+		"upload_id":       params.UploadID,
+		"xsharing_nonces": map[string]interface{}{},
+		"status":          "ok",
+	}
+}
+
+func (s *Server) uploadFinish(c *call) (int, interface{}) {
+	if !s.uploads[c.param("upload_id")] {
+		return 400, fail("Upload not found")
+	}
+	return 200, statusOK()
+}
+
+func (s *Server) configure(c *call) (int, interface{}) {
+	uploadID := c.param("upload_id")
+	if !s.uploads[uploadID] {
+		return 400, fail("Upload not found")
+	}
+	if s.transcoding > 0 {
+		s.transcoding--
+		return 202, fail("Transcode not finished yet.")
+	}
+	if s.reuploads > 0 {
+		s.reuploads--
+		delete(s.uploads, uploadID)
+		delete(s.videos, uploadID)
+		return 400, fail("media_needs_reupload")
+	}
+	delete(s.uploads, uploadID)
+
+	m := s.addMedia(c.viewer, c.param("caption"), uploadID)
+	m.video = s.videos[uploadID]
+	delete(s.videos, uploadID)
+	if s.failConfigure > 0 {
+		s.failConfigure--
+		return 502, fail("Bad Gateway")
+	}
+	return 200, map[string]interface{}{
+		"media":     s.itemJSON(m, c.viewer),
+		"upload_id": uploadID,
+		"status":    "ok",
+	}
+}
+
+func (t *thread) member(u *user) bool {
+	for _, m := range t.users {
+		if m == u {
+			return true
+		}
+	}
+	return false
+}
+
+// canView reports whether viewer can see the feed and follows of u
+func canView(viewer, u *user) bool {
+	return !u.IsPrivate || viewer == u || viewer.following[u.ID]
+}
+
+func friendship(viewer, u *user) map[string]interface{} {
+	return map[string]interface{}{
+		"following":        viewer.following[u.ID],
+		"followed_by":      u.following[viewer.ID],
+		"outgoing_request": viewer.requested[u.ID],
+		"incoming_request": u.requested[viewer.ID],
+		"is_private":       u.IsPrivate,
+	}
+}
+
+func (s *Server) userJSON(u, viewer *user) map[string]interface{} {
+	followers := 0
+	for _, f := range s.users {
+		if f.following[u.ID] {
+			followers++
+		}
+	}
+	j := s.userShortJSON(u)
+	j["follower_count"] = followers
+	j["following_count"] = len(u.following)
+	j["media_count"] = len(u.posts)
+	if viewer != nil && viewer != u {
+		j["friendship_status"] = friendship(viewer, u)
+	}
+	return j
+}
+
+func (s *Server) userShortJSON(u *user) map[string]interface{} {
+	return map[string]interface{}{
+		"pk":         u.ID,
+		"username":   u.Username,
+		"full_name":  u.FullName,
+		"is_private": u.IsPrivate,
+	}
+}
+
+func (s *Server) usersJSON(users []*user, viewer *user) map[string]interface{} {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	list := []interface{}{}
+	for _, u := range users {
+		list = append(list, s.userShortJSON(u))
+	}
+	return map[string]interface{}{
+		"users":     list,
+		"big_list":  false,
+		"page_size": 200,
+		"status":    "ok",
+	}
+}
+
+func (s *Server) itemJSON(m *media, viewer *user) map[string]interface{} {
+	j := map[string]interface{}{
+		"pk":         m.pk,
+		"id":         m.id(),
+		"media_type": 1,
+		"taken_at":   m.takenAt,
+		"user":       s.userShortJSON(m.owner),
+		"like_count": len(m.likes),
+		"has_liked":  m.likes[viewer.ID],
+	}
+	if m.uploadID != "" {
+		j["upload_id"] = m.uploadID
+	}
+	if m.caption != "" {
+		j["caption"] = map[string]interface{}{
+			"pk":         m.pk + 1,
+			"user_id":    m.owner.ID,
+			"text":       m.caption,
+			"created_at": m.takenAt,
+			"media_id":   m.pk,
+		}
+	}
+	return j
+}
+
+func (s *Server) inboxJSON(threads []*thread, viewer *user) map[string]interface{} {
+	list := []interface{}{}
+	for _, t := range threads {
+		list = append(list, s.threadJSON(t, viewer))
+	}
+	return map[string]interface{}{
+		"inbox": map[string]interface{}{
+			"threads":       list,
+			"has_older":     false,
+			"oldest_cursor": "",
+			"unseen_count":  0,
+		},
+		"seq_id":                 s.lastID,
+		"snapshot_at_ms":         time.Now().UnixNano() / int64(time.Millisecond),
+		"pending_requests_total": 0,
+		"status":                 "ok",
+	}
+}
+
+func (s *Server) threadJSON(t *thread, viewer *user) map[string]interface{} {
+	users := []interface{}{}
+	for _, u := range t.users {
+		if u != viewer {
+			users = append(users, s.userShortJSON(u))
+		}
+	}
+	items := []interface{}{}
+	for _, m := range t.items {
+		items = append(items, map[string]interface{}{
+			"item_id":        m.id,
+			"user_id":        m.userID,
+			"timestamp":      m.timestamp,
+			"item_type":      "text",
+			"text":           m.text,
+			"client_context": m.clientContext,
+		})
+	}
+	threadType := "private"
+	if len(t.users) > 2 {
+		threadType = "group"
+	}
+	return map[string]interface{}{
+		"thread_id":        t.id,
+		"thread_v2_id":     t.id,
+		"users":            users,
+		"items":            items,
+		"viewer_id":        viewer.ID,
+		"thread_type":      threadType,
+		"is_group":         len(t.users) > 2,
+		"has_older":        false,
+		"has_newer":        false,
+		"last_activity_at": t.lastActivity,
+	}
+}
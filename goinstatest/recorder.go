@@ -0,0 +1,146 @@
+package goinstatest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Recorder is a http transport, which forwards all requests to an underlying
+//   transport, and writes them to a fixture file, together with the response.
+//
+// Usage:
+//   rec, err := goinstatest.NewRecorder("fixtures/login.jsonl", nil)
+//   insta.SetHTTPTransport(rec)
+//   ...
+//   rec.Close()
+//
+type Recorder struct {
+	mu sync.Mutex
+
+	next http.RoundTripper
+	f    *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder creates a new recorder writing to path. If the file exists, it
+//   will be truncated. If next is nil, http.DefaultTransport will be used.
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		next: next,
+		f:    f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the body uncompressed, and hand it back to goinsta as received
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	plain, err := decompress(resp.Header.Get("Content-Encoding"), respBody)
+	if err != nil {
+		return nil, err
+	}
+	respHeader := redactHeader(resp.Header)
+	respHeader.Del("Content-Encoding")
+	respHeader.Del("Content-Length")
+
+	i := Interaction{
+		Request: recordRequest(req, reqBody),
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     respHeader,
+			Body:       string(plain),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(i); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Close closes the fixture file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+func recordRequest(req *http.Request, body []byte) RecordedRequest {
+	u := *req.URL
+	if q := u.Query(); q.Get("key") != "" {
+		// api keys of third party services, used to download test media
+		q.Set("key", Redacted)
+		u.RawQuery = q.Encode()
+	}
+	rec := RecordedRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: redactHeader(req.Header),
+	}
+	if len(body) == 0 {
+		return rec
+	}
+
+	if b, err := decompress(req.Header.Get("Content-Encoding"), body); err == nil {
+		body = b
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if v, err := url.ParseQuery(string(body)); err == nil {
+			rec.Form = redactForm(v)
+			return rec
+		}
+	}
+	rec.Body = body
+	return rec
+}
+
+func decompress(encoding string, b []byte) ([]byte, error) {
+	if encoding != "gzip" {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,122 @@
+package goinstatest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Replayer is a http transport, which serves responses from a fixture file,
+//   instead of sending requests to Instagram.
+//
+// Requests are matched by method and path, as query parameters and bodies
+//   contain random values, such as uuids and timestamps. For the same reason
+//   the randomly generated upload name in rupload paths is ignored.
+//
+// If the same endpoint has been recorded multiple times, the responses are
+//   served in the order they were recorded. Once all responses of an endpoint
+//   have been served, the last one is repeated, unless Strict is set.
+type Replayer struct {
+	mu sync.Mutex
+
+	// Strict makes the replayer return an error for requests, that have no
+	//   unused recorded response left.
+	Strict bool
+
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+// NewReplayer loads a fixture file written by a Recorder
+func NewReplayer(path string) (*Replayer, error) {
+	interactions, err := ReadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerFromInteractions(interactions), nil
+}
+
+// NewReplayerFromInteractions creates a replayer from in-memory interactions
+func NewReplayerFromInteractions(interactions []Interaction) *Replayer {
+	r := &Replayer{
+		interactions: map[string][]Interaction{},
+		served:       map[string]int{},
+	}
+	for _, i := range interactions {
+		u, err := url.Parse(i.Request.URL)
+		if err != nil {
+			continue
+		}
+		key := matchKey(i.Request.Method, u)
+		r.interactions[key] = append(r.interactions[key], i)
+	}
+	return r
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := matchKey(req.Method, req.URL)
+	recorded := r.interactions[key]
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("goinstatest: no fixture recorded for %s", key)
+	}
+	n := r.served[key]
+	if n >= len(recorded) {
+		if r.Strict {
+			return nil, fmt.Errorf(
+				"goinstatest: all %d fixtures for %s have been used",
+				len(recorded), key,
+			)
+		}
+		n = len(recorded) - 1
+	}
+	r.served[key]++
+
+	rec := recorded[n].Response
+	return &http.Response{
+		StatusCode:    rec.StatusCode,
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewBufferString(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the number of recorded responses, that have not been served
+func (r *Replayer) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := 0
+	for k, v := range r.interactions {
+		if d := len(v) - r.served[k]; d > 0 {
+			unused += d
+		}
+	}
+	return unused
+}
+
+func matchKey(method string, u *url.URL) string {
+	path := u.Path
+	for _, prefix := range []string{"/rupload_igphoto/", "/rupload_igvideo/"} {
+		if strings.HasPrefix(path, prefix) {
+			path = prefix + "*"
+		}
+	}
+	return method + " " + u.Host + path
+}
@@ -0,0 +1,540 @@
+package goinstatest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+// Server is an in-process fake of the Instagram private API, for integration
+//   tests. It keeps users, follows, media, likes and direct messages in memory,
+//   so the effects of one call are visible to the following ones, e.g. a
+//   followed user shows up in Account.Following().
+//
+// Implemented endpoints:
+//   - login: zr/token, launcher/sync, prefill, accounts/login,
+//       two_factor_login (TOTP), challenge, current_user
+//   - two factor settings: account_security_info, TOTP enable/disable,
+//       regen_backup_codes
+//   - sign up: check_email, send_verify_email, check_confirmation_code,
+//       check_phone_number, send_signup_sms_code, validate_signup_sms_code,
+//       consent, username_suggestions, check_username, create,
+//       create_validated
+//   - account recovery: users/lookup, send_recovery_flow_email, lookup_phone,
+//       account_recovery_code_verify, password_reset
+//   - login activity: sessions, avow_login, undo_avow_login, logout_session
+//   - users: usernameinfo, info, friendships create/destroy/show,
+//       followers and following
+//   - media: feed/user, media info, like/unlike
+//   - direct: inbox, pending inbox, threads, get_by_participants,
+//       broadcast/text
+//   - uploads: rupload_igphoto, rupload_igvideo (segmented and resumable),
+//       media/upload_finish, media/configure
+//
+// All other endpoints respond with 404. Requests to all endpoints except the
+//   login sequence require a session, otherwise login_required is returned.
+//
+// Usage:
+//   srv := goinstatest.NewServer()
+//   defer srv.Close()
+//   srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+//
+//   insta := srv.NewInstagram("alice", "secret")
+//   err := insta.Login()
+//
+type Server struct {
+	// URL of the server, e.g. http://127.0.0.1:1234
+	URL string
+	// ClockSkew is added to the time used to check TOTP codes, to simulate a
+	//   server clock, which differs from the local one.
+	ClockSkew time.Duration
+
+	srv   *httptest.Server
+	key   *rsa.PrivateKey
+	keyID int
+
+	mu       sync.Mutex
+	lastID   int64
+	lastTS   int64
+	users    map[int64]*user
+	sessions map[string]*session
+	// pending two factor logins by identifier
+	twoFactor map[string]int64
+	// pending challenges by nonce
+	challenges map[string]*challenge
+	// pending sign ups by email address or phone number
+	signups map[string]*signup
+	// pending password resets by user ID
+	resets  map[int64]*passwordReset
+	media   map[string]*media
+	threads map[string]*thread
+	uploads map[string]bool
+	// video bytes by upload ID, and video entities being uploaded by name
+	videos   map[string][]byte
+	entities map[string][]byte
+	// number of video transfers to interrupt
+	interrupt int
+	// number of configure calls answered with "Transcode not finished yet.",
+	//   and with media_needs_reupload
+	transcoding int
+	reuploads   int
+	// number of configure calls failing with a 502, after the media has
+	//   been created
+	failConfigure int
+}
+
+// User is an account on the fake server. If TOTPSecret is set, logins
+//   require a two factor code generated from it.
+//
+// If Checkpoint is set, the next login fails with challenge_required. The
+//   challenge asks to select a verification method, and to enter the code,
+//   which can be retrieved with Server.ChallengeCode. If ChangePassword is
+//   set as well, a new password has to be set after the code.
+//
+// Email and PhoneNumber can not be used to register another account.
+type User struct {
+	ID             int64
+	Username       string
+	FullName       string
+	Password       string
+	Email          string
+	PhoneNumber    string
+	IsPrivate      bool
+	TOTPSecret     string
+	Checkpoint     bool
+	ChangePassword bool
+}
+
+type user struct {
+	User
+
+	following map[int64]bool
+	requested map[int64]bool
+	posts     []*media
+
+	// two factor settings
+	totpSeed    string
+	backupCodes []string
+}
+
+// session is a login of a user, identified by its authorization token.
+//   Suspicious sessions have to be confirmed or denied by the user.
+type session struct {
+	id         string
+	userID     int64
+	device     string
+	location   string
+	timestamp  int64
+	suspicious bool
+}
+
+type challenge struct {
+	userID int64
+	step   string
+	code   string
+}
+
+type signup struct {
+	code string
+	// confirmed is set once the code has been entered. signupCode is returned
+	//   for email addresses, and passed to create the account.
+	confirmed  bool
+	signupCode string
+}
+
+type passwordReset struct {
+	// code is sent by SMS, token by email, or returned once the code has
+	//   been verified
+	code  string
+	token string
+}
+
+type media struct {
+	pk       int64
+	owner    *user
+	caption  string
+	takenAt  int64
+	uploadID string
+	likes    map[int64]bool
+	video    []byte
+}
+
+type thread struct {
+	id           string
+	users        []*user
+	items        []*message
+	lastActivity int64
+}
+
+type message struct {
+	id            string
+	userID        int64
+	text          string
+	clientContext string
+	timestamp     int64
+}
+
+// NewServer starts a new fake server. Call Close when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{
+		key:        key,
+		keyID:      41,
+		lastID:     1000000,
+		users:      map[int64]*user{},
+		sessions:   map[string]*session{},
+		twoFactor:  map[string]int64{},
+		challenges: map[string]*challenge{},
+		signups:    map[string]*signup{},
+		resets:     map[int64]*passwordReset{},
+		media:      map[string]*media{},
+		threads:    map[string]*thread{},
+		uploads:    map[string]bool{},
+		videos:     map[string][]byte{},
+		entities:   map[string][]byte{},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Transport returns a http transport, which sends all requests to the fake
+//   server, instead of Instagram.
+func (s *Server) Transport() http.RoundTripper {
+	u, _ := url.Parse(s.URL)
+	return &rewriteTransport{host: u.Host, next: s.srv.Client().Transport}
+}
+
+// NewInstagram creates a new goinsta instance, which talks to the fake server
+func (s *Server) NewInstagram(username, password string) *goinsta.Instagram {
+	insta := goinsta.New(username, password)
+	insta.SetInfoHandler(func(...interface{}) {})
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetHTTPTransport(s.Transport())
+	return insta
+}
+
+// AddUser creates a new account. If u.ID is 0, an ID is assigned. The created
+//   user is returned.
+func (s *Server) AddUser(u User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addUser(u).User
+}
+
+func (s *Server) addUser(u User) *user {
+	if u.ID == 0 {
+		u.ID = s.newID()
+	}
+	if u.FullName == "" {
+		u.FullName = u.Username
+	}
+	s.users[u.ID] = &user{
+		User:      u,
+		following: map[int64]bool{},
+		requested: map[int64]bool{},
+	}
+	return s.users[u.ID]
+}
+
+// ExpireSessions invalidates all sessions of a user, so that further requests
+//   of the user fail with login_required.
+func (s *Server) ExpireSessions(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range s.sessions {
+		if v.userID == userID {
+			delete(s.sessions, k)
+		}
+	}
+}
+
+// ChallengeCode returns the security code of the pending challenge of a user,
+//   which has been sent after a verification method has been selected.
+func (s *Server) ChallengeCode(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.challenges {
+		if c.userID == userID {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// ConfirmationCode returns the code sent to an email address or phone number
+//   to register a new account.
+func (s *Server) ConfirmationCode(contact string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if su, ok := s.signups[contact]; ok {
+		return su.code
+	}
+	return ""
+}
+
+// PasswordResetLink returns the link of the password reset sent by email to a
+//   user, as received by the user.
+func (s *Server) PasswordResetLink(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.resets[userID]
+	if !ok || r.code != "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"https://instagram.com/accounts/password/reset/confirm/?uidb36=%s&token=%s",
+		strconv.FormatInt(userID, 36), r.token,
+	)
+}
+
+// RecoveryCode returns the code of the password reset sent by SMS to a user
+func (s *Server) RecoveryCode(userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.resets[userID]; ok {
+		return r.code
+	}
+	return ""
+}
+
+// AddSuspiciousLogin logs in a user from another device, and flags the login
+//   as suspicious. The session ID is returned.
+func (s *Server) AddSuspiciousLogin(userID int64, device, location string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return ""
+	}
+	token, err := s.newSession(u, device)
+	if err != nil {
+		return ""
+	}
+	sess := s.sessions[token]
+	sess.location = location
+	sess.suspicious = true
+	return sess.id
+}
+
+// Sessions returns the number of active sessions of a user
+func (s *Server) Sessions(userID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, sess := range s.sessions {
+		if sess.userID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+// AddMedia adds a photo to the feed of a user, and returns its media ID
+func (s *Server) AddMedia(userID int64, caption string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return ""
+	}
+	return s.addMedia(u, caption, "").id()
+}
+
+// Follows reports whether user a follows user b
+func (s *Server) Follows(a, b int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[a]
+	return ok && u.following[b]
+}
+
+// Liked reports whether a user has liked a media item
+func (s *Server) Liked(userID int64, mediaID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.media[mediaID]
+	return ok && m.likes[userID]
+}
+
+// Messages returns the texts of all direct messages sent from one user to
+//   another, oldest first.
+func (s *Server) Messages(from, to int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.privateThread(from, to)
+	if t == nil {
+		return nil
+	}
+	var texts []string
+	for i := len(t.items) - 1; i >= 0; i-- {
+		if t.items[i].userID == from {
+			texts = append(texts, t.items[i].text)
+		}
+	}
+	return texts
+}
+
+// InterruptUploads lets the next n video transfers fail with a 500 error,
+//   after half of their bytes have been received.
+func (s *Server) InterruptUploads(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interrupt = n
+}
+
+// DelayTranscode lets the next n calls to configure an upload fail with
+//   "Transcode not finished yet.".
+func (s *Server) DelayTranscode(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transcoding = n
+}
+
+// RequestReupload lets the next n calls to configure an upload fail with
+//   media_needs_reupload. The uploaded files are discarded.
+func (s *Server) RequestReupload(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reuploads = n
+}
+
+// FailConfigure lets the next n calls to configure an upload fail with a 502
+//   error, after the media has been created.
+func (s *Server) FailConfigure(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failConfigure = n
+}
+
+// UploadedVideo returns the video bytes of a media, nil if it is no video
+func (s *Server) UploadedVideo(mediaID string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.media[mediaID]; ok {
+		return m.video
+	}
+	return nil
+}
+
+func (s *Server) addMedia(u *user, caption, uploadID string) *media {
+	m := &media{
+		pk:       s.newID(),
+		owner:    u,
+		caption:  caption,
+		takenAt:  time.Now().Unix(),
+		uploadID: uploadID,
+		likes:    map[int64]bool{},
+	}
+	u.posts = append([]*media{m}, u.posts...)
+	s.media[m.id()] = m
+	return m
+}
+
+func (m *media) id() string {
+	return strconv.FormatInt(m.pk, 10) + "_" + strconv.FormatInt(m.owner.ID, 10)
+}
+
+func (s *Server) privateThread(a, b int64) *thread {
+	for _, t := range s.threads {
+		if len(t.users) != 2 {
+			continue
+		}
+		if (t.users[0].ID == a && t.users[1].ID == b) ||
+			(t.users[0].ID == b && t.users[1].ID == a) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) userByName(name string) *user {
+	for _, u := range s.users {
+		if u.Username == name {
+			return u
+		}
+	}
+	return nil
+}
+
+// lookupUser finds a user by username, email address or phone number
+func (s *Server) lookupUser(query string) *user {
+	for _, u := range s.users {
+		if u.Username == query || (u.Email != "" && u.Email == query) ||
+			(u.PhoneNumber != "" && u.PhoneNumber == query) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) newID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+// timestamp returns a unique timestamp in microseconds, as used by direct
+func (s *Server) timestamp() int64 {
+	ts := time.Now().UnixNano() / 1000
+	if ts <= s.lastTS {
+		ts = s.lastTS + 1
+	}
+	s.lastTS = ts
+	return ts
+}
+
+// publicKey returns the password encryption key, as sent by launcher/sync
+func (s *Server) publicKey() string {
+	b, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})
+	return base64.StdEncoding.EncodeToString(block)
+}
+
+// rewriteTransport redirects all requests to the fake server
+type rewriteTransport struct {
+	host string
+	next http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = t.host
+	req.Host = t.host
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,278 @@
+package goinsta
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HealthState describes whether an account can currently be used
+type HealthState string
+
+const (
+	// HealthOK means no problems have been detected
+	HealthOK HealthState = "ok"
+	// HealthRateLimited means Instagram responded with too many requests
+	HealthRateLimited HealthState = "rate_limited"
+	// HealthActionBlocked means write actions have been blocked
+	//   (feedback_required)
+	HealthActionBlocked HealthState = "action_blocked"
+	// HealthCheckpoint means a challenge needs to be solved
+	HealthCheckpoint HealthState = "checkpoint_required"
+	// HealthLoggedOut means the session is no longer valid
+	HealthLoggedOut HealthState = "logged_out"
+)
+
+// Health is the account health, as detected from the responses of Instagram.
+//
+// RateLimited and ActionBlocked states expire at Until, and are cleared early
+//   by a successful request, or a successful write action respectively.
+//   Checkpoint and LoggedOut states are only cleared by a successful login, or
+//   by Instagram.ResetHealth.
+type Health struct {
+	State HealthState `json:"state"`
+	// Until is set for states, that expire after some time
+	Until time.Time `json:"until,omitempty"`
+	// Reason is the message Instagram sent with the response
+	Reason string `json:"reason,omitempty"`
+	// Since is the time the state has been entered
+	Since time.Time `json:"since"`
+}
+
+// OK reports whether no problems have been detected
+func (h Health) OK() bool {
+	return h.State == "" || h.State == HealthOK
+}
+
+func (h Health) String() string {
+	s := string(h.State)
+	if h.State == "" {
+		s = string(HealthOK)
+	}
+	if !h.Until.IsZero() {
+		s += fmt.Sprintf(" until %s", h.Until.Format(time.RFC3339))
+	}
+	if h.Reason != "" {
+		s += ": " + h.Reason
+	}
+	return s
+}
+
+// HealthPolicy configures how the account health is tracked.
+type HealthPolicy struct {
+	// FailFast makes write actions (follow, like, comment, direct message)
+	//   fail with ErrAccountUnhealthy, while the account is not healthy,
+	//   instead of sending them to Instagram.
+	FailFast bool
+	// BlockDuration is how long an action block is assumed to last, as
+	//   Instagram does not tell.
+	BlockDuration time.Duration
+	// RateLimitDuration is how long a rate limit is assumed to last, if
+	//   Instagram does not send a Retry-After header.
+	RateLimitDuration time.Duration
+}
+
+// DefaultHealthPolicy is used if no other policy has been set
+var DefaultHealthPolicy = HealthPolicy{
+	FailFast:          false,
+	BlockDuration:     24 * time.Hour,
+	RateLimitDuration: 5 * time.Minute,
+}
+
+// ErrAccountUnhealthy is returned for write actions if HealthPolicy.FailFast
+//   is set, and the account is not healthy. It matches the error which caused
+//   the state with errors.Is, e.g. ErrFeedbackRequired for action blocks.
+type ErrAccountUnhealthy struct {
+	Health Health
+}
+
+func (e ErrAccountUnhealthy) Error() string {
+	return fmt.Sprintf("Request not sent, account is not healthy: %s", e.Health)
+}
+
+// Is allows to compare the error with the sentinel errors using errors.Is
+func (e ErrAccountUnhealthy) Is(target error) bool {
+	switch e.Health.State {
+	case HealthRateLimited:
+		return target == ErrTooManyRequests
+	case HealthActionBlocked:
+		return target == ErrFeedbackRequired
+	case HealthCheckpoint:
+		return target == ErrChallengeRequired
+	case HealthLoggedOut:
+		return target == ErrLoginRequired
+	}
+	return false
+}
+
+// Health returns the current health of the account
+func (insta *Instagram) Health() Health {
+	insta.healthMu.Lock()
+	h, changed, old := insta.expireHealth()
+	insta.healthMu.Unlock()
+
+	if changed {
+		insta.notifyHealth(old, h)
+	}
+	return h
+}
+
+// ResetHealth sets the account health back to OK
+func (insta *Instagram) ResetHealth() {
+	insta.setHealth(Health{State: HealthOK})
+}
+
+// SetHealthPolicy sets the policy used to track the account health. Unset
+//   durations default to the ones of DefaultHealthPolicy.
+func (insta *Instagram) SetHealthPolicy(p HealthPolicy) {
+	insta.healthMu.Lock()
+	defer insta.healthMu.Unlock()
+	insta.healthPolicy = &p
+}
+
+// SetHealthHandler sets a function, which is called every time the account
+//   health changes.
+func (insta *Instagram) SetHealthHandler(f func(old, new Health)) {
+	insta.healthMu.Lock()
+	defer insta.healthMu.Unlock()
+	insta.healthHandler = f
+}
+
+// policy returns the health policy, with defaults for all unset durations
+func (insta *Instagram) policy() HealthPolicy {
+	if insta.healthPolicy == nil {
+		return DefaultHealthPolicy
+	}
+	p := *insta.healthPolicy
+	if p.BlockDuration == 0 {
+		p.BlockDuration = DefaultHealthPolicy.BlockDuration
+	}
+	if p.RateLimitDuration == 0 {
+		p.RateLimitDuration = DefaultHealthPolicy.RateLimitDuration
+	}
+	return p
+}
+
+// expireHealth clears the state if it has expired. healthMu must be held.
+func (insta *Instagram) expireHealth() (h Health, changed bool, old Health) {
+	h = insta.health
+	if h.State == "" {
+		h.State = HealthOK
+	}
+	if !h.Until.IsZero() && time.Now().After(h.Until) {
+		old = h
+		h = Health{State: HealthOK, Since: time.Now()}
+		insta.health = h
+		return h, true, old
+	}
+	return h, false, old
+}
+
+func (insta *Instagram) setHealth(h Health) {
+	if h.Since.IsZero() {
+		h.Since = time.Now()
+	}
+
+	insta.healthMu.Lock()
+	old := insta.health
+	if old.State == "" {
+		old.State = HealthOK
+	}
+	if old.State == h.State && old.Until.Equal(h.Until) && old.Reason == h.Reason {
+		insta.healthMu.Unlock()
+		return
+	}
+	insta.health = h
+	insta.healthMu.Unlock()
+
+	insta.notifyHealth(old, h)
+}
+
+func (insta *Instagram) notifyHealth(old, h Health) {
+	insta.healthMu.Lock()
+	f := insta.healthHandler
+	insta.healthMu.Unlock()
+
+	if f != nil {
+		f(old, h)
+	}
+	if !h.OK() {
+		insta.WarnHandler(fmt.Sprintf("Account health changed to %s", h))
+	}
+}
+
+// checkHealth returns ErrAccountUnhealthy for write actions, if fail fast is
+//   enabled and the account is not healthy.
+func (insta *Instagram) checkHealth(o *reqOptions) error {
+	if !o.isWrite() {
+		return nil
+	}
+	insta.healthMu.Lock()
+	failFast := insta.policy().FailFast
+	insta.healthMu.Unlock()
+	if !failFast {
+		return nil
+	}
+
+	if h := insta.Health(); !h.OK() {
+		return ErrAccountUnhealthy{Health: h}
+	}
+	return nil
+}
+
+// updateHealth updates the account health from the result of a request
+func (insta *Instagram) updateHealth(o *reqOptions, err error) {
+	insta.healthMu.Lock()
+	h, changed, old := insta.expireHealth()
+	p := insta.policy()
+	insta.healthMu.Unlock()
+	if changed {
+		insta.notifyHealth(old, h)
+	}
+
+	if err == nil {
+		switch {
+		case h.State == HealthRateLimited:
+			insta.ResetHealth()
+		case h.State == HealthActionBlocked && o.isWrite():
+			insta.ResetHealth()
+		}
+		return
+	}
+
+	reason := err.Error()
+	var ierr Error400
+	if errors.As(err, &ierr) && ierr.FeedbackMessage != "" {
+		reason = ierr.FeedbackMessage
+	}
+
+	now := time.Now()
+	switch {
+	case errors.Is(err, ErrLoginRequired):
+		insta.setHealth(Health{State: HealthLoggedOut, Reason: reason})
+	case errors.Is(err, ErrChallengeRequired):
+		insta.setHealth(Health{State: HealthCheckpoint, Reason: reason})
+	case errors.Is(err, ErrFeedbackRequired):
+		insta.setHealth(Health{
+			State:  HealthActionBlocked,
+			Until:  now.Add(p.BlockDuration),
+			Reason: reason,
+		})
+	case errors.Is(err, ErrTooManyRequests):
+		wait := RetryAfter(err)
+		if wait == 0 {
+			wait = p.RateLimitDuration
+		}
+		insta.setHealth(Health{
+			State:  HealthRateLimited,
+			Until:  now.Add(wait),
+			Reason: reason,
+		})
+	}
+}
+
+// isWrite reports whether the request performs a write action, which can be
+//   action blocked.
+func (o *reqOptions) isWrite() bool {
+	return o.Action != "" && o.Action != ActionRead
+}
@@ -178,9 +178,10 @@ func (igtv *IGTVChannel) Next(params ...interface{}) bool {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlIGTVChannel,
-			IsPost:   true,
-			Query:    query,
+			Endpoint:   urlIGTVChannel,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      query,
 		})
 	if err != nil {
 		igtv.err = err
@@ -219,9 +220,10 @@ func (insta *Instagram) callIGTVChannel(id, nextID string) (*IGTVChannel, error)
 		query["max_id"] = id
 	}
 	body, _, err := insta.sendRequest(&reqOptions{
-		Endpoint: urlIGTVChannel,
-		IsPost:   true,
-		Query:    query,
+		Endpoint:   urlIGTVChannel,
+		IsPost:     true,
+		Idempotent: true,
+		Query:      query,
 	})
 	if err != nil {
 		return nil, err
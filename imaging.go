@@ -0,0 +1,416 @@
+package goinsta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Aspect ratios (width / height) accepted by Instagram
+const (
+	MinFeedAspectRatio = 4.0 / 5.0
+	MaxFeedAspectRatio = 1.91
+	StoryAspectRatio   = 9.0 / 16.0
+)
+
+// Width limits of uploaded images. Larger images are downscaled by Instagram
+//   anyway, smaller ones are rejected.
+const (
+	MinImageWidth = 320
+	MaxImageWidth = 1080
+)
+
+// ImageFit is the strategy to fit an image into an aspect ratio
+type ImageFit int
+
+const (
+	// FitCrop crops the center of the image
+	FitCrop ImageFit = iota
+	// FitSmartCrop crops the part of the image with the most details
+	FitSmartCrop
+	// FitPad pads the image with ImageOptions.PadColor
+	FitPad
+	// FitBlur pads the image with a blurred copy of itself
+	FitBlur
+)
+
+// ImageOptions configure the preprocessing of images before they are
+//   uploaded, see UploadOptions.Preprocess.
+//
+// Images are converted to JPEG, rotated according to their EXIF orientation,
+//   fit into the aspect ratio accepted by Instagram, and resized to a width
+//   between MinImageWidth and MaxWidth. Feed images are fit into 4:5 to
+//   1.91:1, stories into 9:16, and all images of a carousel into the aspect
+//   ratio of the first one.
+//
+// JPEG, PNG, GIF and WebP images are supported. HEIC images fail with
+//   ErrInvalidFormat, unless a HEIC decoder is registered with
+//   image.RegisterFormat, usually by importing its package.
+type ImageOptions struct {
+	Fit ImageFit
+	// PadColor is the color used by FitPad, white by default
+	PadColor color.Color
+	// AspectRatio forces an aspect ratio (width / height), e.g. 1 for square
+	//   posts. It has to be accepted by Instagram.
+	AspectRatio float64
+	// MaxWidth defaults to MaxImageWidth
+	MaxWidth int
+	// Quality of the JPEG encoding, 90 by default
+	Quality int
+}
+
+// ProcessImage preprocesses an image as described in ImageOptions, fitting it
+//   into an aspect ratio between minRatio and maxRatio, and returns the JPEG.
+func ProcessImage(b []byte, opts ImageOptions, minRatio, maxRatio float64) ([]byte, error) {
+	if opts.AspectRatio > 0 {
+		minRatio, maxRatio = opts.AspectRatio, opts.AspectRatio
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		if brand, ok := ftypBrand(b); ok && isHEIF(brand) {
+			return nil, fmt.Errorf("%w: HEIC images can not be decoded, convert them to JPEG or register a HEIC decoder with image.RegisterFormat", ErrInvalidFormat)
+		}
+		return nil, fmt.Errorf("%w: failed to decode image: %s", ErrInvalidFormat, err)
+	}
+
+	img := toRGBA(decoded)
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(b))
+	}
+	img = fitAspectRatio(img, opts, minRatio, maxRatio)
+
+	maxWidth := opts.MaxWidth
+	if maxWidth <= 0 {
+		maxWidth = MaxImageWidth
+	}
+	if w := img.Rect.Dx(); w > maxWidth || w < MinImageWidth {
+		nw := maxWidth
+		if w < MinImageWidth {
+			nw = MinImageWidth
+		}
+		nh := int(math.Round(float64(img.Rect.Dy()) * float64(nw) / float64(w)))
+		img = resize(img, nw, nh)
+	}
+
+	quality := opts.Quality
+	if quality <= 0 {
+		quality = 90
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA converts an image to RGBA, with transparent parts drawn on white
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Over)
+	return dst
+}
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG, 1 if unknown
+func exifOrientation(b []byte) int {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(b); {
+		marker := b[i+1]
+		if b[i] != 0xFF || marker == 0xDA || marker == 0xD9 {
+			// the metadata is in front of the image data
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		if size < 2 || i+2+size > len(b) {
+			return 1
+		}
+		seg := b[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of a TIFF header
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(t[4:]))
+	if off < 0 || off+2 > len(t) {
+		return 1
+	}
+	n := int(bo.Uint16(t[off:]))
+	for i := 0; i < n; i++ {
+		e := off + 2 + i*12
+		if e+12 > len(t) {
+			return 1
+		}
+		if bo.Uint16(t[e:]) == 0x0112 {
+			if o := int(bo.Uint16(t[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient rotates and flips an image, so that it is displayed upright
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+	return dst
+}
+
+// fitAspectRatio crops or pads the image, if its aspect ratio is not between
+//   minRatio and maxRatio.
+func fitAspectRatio(img *image.RGBA, opts ImageOptions, minRatio, maxRatio float64) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	ratio := float64(w) / float64(h)
+	target := math.Max(minRatio, math.Min(maxRatio, ratio))
+	if math.Abs(ratio-target) < 0.005 {
+		return img
+	}
+
+	switch opts.Fit {
+	case FitPad, FitBlur:
+		cw, ch := w, int(math.Round(float64(w)/target))
+		if ratio < target {
+			cw, ch = int(math.Round(float64(h)*target)), h
+		}
+		canvas := image.NewRGBA(image.Rect(0, 0, cw, ch))
+		if opts.Fit == FitBlur {
+			draw.Draw(canvas, canvas.Rect, blur(cover(img, cw, ch)), image.Point{}, draw.Src)
+		} else {
+			c := opts.PadColor
+			if c == nil {
+				c = color.White
+			}
+			draw.Draw(canvas, canvas.Rect, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+		at := image.Pt((cw-w)/2, (ch-h)/2)
+		draw.Draw(canvas, image.Rectangle{at, at.Add(image.Pt(w, h))}, img, img.Rect.Min, draw.Src)
+		return canvas
+	}
+
+	crop := image.Rect(0, 0, int(math.Round(float64(h)*target)), h)
+	if ratio < target {
+		crop = image.Rect(0, 0, w, int(math.Round(float64(w)/target)))
+	}
+	var offset image.Point
+	if opts.Fit == FitSmartCrop {
+		offset = smartCropOffset(img, crop.Dx(), crop.Dy())
+	} else {
+		offset = image.Pt((w-crop.Dx())/2, (h-crop.Dy())/2)
+	}
+	dst := image.NewRGBA(crop)
+	draw.Draw(dst, crop, img, img.Rect.Min.Add(offset), draw.Src)
+	return dst
+}
+
+// cover scales and crops the center of an image to fill w x h
+func cover(img *image.RGBA, w, h int) *image.RGBA {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	scale := math.Max(float64(w)/float64(iw), float64(h)/float64(ih))
+	sw := int(math.Ceil(float64(iw) * scale))
+	sh := int(math.Ceil(float64(ih) * scale))
+	scaled := resize(img, sw, sh)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Rect, scaled, image.Pt((sw-w)/2, (sh-h)/2), draw.Src)
+	return dst
+}
+
+// blur blurs an image strongly, by downscaling and upscaling it again
+func blur(img *image.RGBA) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	small := resize(img, maxInt(1, w/24), maxInt(1, h/24))
+	return resize(small, w, h)
+}
+
+// smartCropOffset returns the offset of the w x h window of the image, which
+//   contains the most edges. The window is moved along one axis only.
+func smartCropOffset(img *image.RGBA, w, h int) image.Point {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+
+	// detect edges on a small version, for speed
+	scale := math.Min(1, 256/math.Max(float64(iw), float64(ih)))
+	sw, sh := maxInt(1, int(float64(iw)*scale)), maxInt(1, int(float64(ih)*scale))
+	small := resize(img, sw, sh)
+
+	horizontal := w < iw
+	n := sh
+	if horizontal {
+		n = sw
+	}
+	energy := make([]float64, n)
+	gray := func(x, y int) float64 {
+		i := y*small.Stride + x*4
+		p := small.Pix[i : i+3]
+		return 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	}
+	for y := 1; y < sh; y++ {
+		for x := 1; x < sw; x++ {
+			g := gray(x, y)
+			e := math.Abs(g-gray(x-1, y)) + math.Abs(g-gray(x, y-1))
+			if horizontal {
+				energy[x] += e
+			} else {
+				energy[y] += e
+			}
+		}
+	}
+
+	window := int(math.Round(float64(h) * scale))
+	if horizontal {
+		window = int(math.Round(float64(w) * scale))
+	}
+	window = minInt(maxInt(window, 1), n)
+	var sum, best float64
+	bestAt := (n - window) / 2
+	for i := 0; i < n; i++ {
+		sum += energy[i]
+		if i >= window {
+			sum -= energy[i-window]
+		}
+		if i >= window-1 && sum > best {
+			best = sum
+			bestAt = i - window + 1
+		}
+	}
+
+	offset := int(math.Round(float64(bestAt) / scale))
+	if horizontal {
+		return image.Pt(minInt(offset, iw-w), 0)
+	}
+	return image.Pt(0, minInt(offset, ih-h))
+}
+
+// resize scales an image to w x h with a triangle filter, which averages all
+//   source pixels when downscaling, and interpolates linearly when upscaling.
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == w && sh == h {
+		return src
+	}
+	tmp := image.NewRGBA(image.Rect(0, 0, w, sh))
+	resample(tmp.Pix, tmp.Stride, 4, src.Pix, src.Stride, 4, sh, sw, w)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	resample(dst.Pix, 4, dst.Stride, tmp.Pix, 4, tmp.Stride, w, sh, h)
+	return dst
+}
+
+// resample scales lines of pixels along one axis. n lines of srcLen pixels
+//   are scaled to dstLen pixels, pixel i of line l is at
+//   l*lineStride + i*pixStride.
+func resample(dst []uint8, dstLine, dstPix int, src []uint8, srcLine, srcPix int, n, srcLen, dstLen int) {
+	type tap struct {
+		at     int
+		weight float64
+	}
+	scale := float64(srcLen) / float64(dstLen)
+	support := math.Max(1, scale)
+	taps := make([][]tap, dstLen)
+	for i := range taps {
+		center := (float64(i)+0.5)*scale - 0.5
+		var total float64
+		for j := int(math.Floor(center - support)); j <= int(math.Ceil(center+support)); j++ {
+			wt := 1 - math.Abs(float64(j)-center)/support
+			if wt <= 0 {
+				continue
+			}
+			at := minInt(maxInt(j, 0), srcLen-1)
+			taps[i] = append(taps[i], tap{at: at, weight: wt})
+			total += wt
+		}
+		for k := range taps[i] {
+			taps[i][k].weight /= total
+		}
+	}
+
+	for l := 0; l < n; l++ {
+		for i, ts := range taps {
+			var r, g, b, a float64
+			for _, t := range ts {
+				p := src[l*srcLine+t.at*srcPix:]
+				r += float64(p[0]) * t.weight
+				g += float64(p[1]) * t.weight
+				b += float64(p[2]) * t.weight
+				a += float64(p[3]) * t.weight
+			}
+			d := dst[l*dstLine+i*dstPix:]
+			d[0], d[1], d[2], d[3] = clamp8(r), clamp8(g), clamp8(b), clamp8(a)
+		}
+	}
+}
+
+func clamp8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -160,7 +161,7 @@ type lastSeenAt struct {
 	ItemID    string `json:"item_id"`
 }
 
-func (inbox *Inbox) sync(pending bool, params map[string]string) error {
+func (inbox *Inbox) sync(ctx context.Context, pending bool, params map[string]string) error {
 	endpoint := urlInbox
 	if pending {
 		endpoint = urlInboxPending
@@ -171,6 +172,7 @@ func (inbox *Inbox) sync(pending bool, params map[string]string) error {
 		&reqOptions{
 			Endpoint: endpoint,
 			Query:    params,
+			Context:  ctx,
 		},
 	)
 	if err != nil {
@@ -187,7 +189,7 @@ func (inbox *Inbox) sync(pending bool, params map[string]string) error {
 	return nil
 }
 
-func (inbox *Inbox) next(pending bool, params map[string]string) bool {
+func (inbox *Inbox) next(ctx context.Context, pending bool, params map[string]string) bool {
 	endpoint := urlInbox
 	if pending {
 		endpoint = urlInboxPending
@@ -200,6 +202,7 @@ func (inbox *Inbox) next(pending bool, params map[string]string) bool {
 		&reqOptions{
 			Endpoint: endpoint,
 			Query:    params,
+			Context:  ctx,
 		},
 	)
 	if err != nil {
@@ -225,7 +228,12 @@ func (inbox *Inbox) next(pending bool, params map[string]string) bool {
 
 // Sync updates inbox messages.
 func (inbox *Inbox) Sync() error {
-	return inbox.sync(false, map[string]string{
+	return inbox.SyncCtx(context.Background())
+}
+
+// SyncCtx is like Sync, but the request can be canceled with ctx.
+func (inbox *Inbox) SyncCtx(ctx context.Context) error {
+	return inbox.sync(ctx, false, map[string]string{
 		"visual_message_return_type": "unseen",
 		"persistentBadging":          "true",
 		"limit":                      "0",
@@ -234,7 +242,13 @@ func (inbox *Inbox) Sync() error {
 
 // SyncPending updates inbox pending messages.
 func (inbox *Inbox) SyncPending() error {
-	return inbox.sync(true, map[string]string{})
+	return inbox.SyncPendingCtx(context.Background())
+}
+
+// SyncPendingCtx is like SyncPending, but the request can be canceled with
+//   ctx.
+func (inbox *Inbox) SyncPendingCtx(ctx context.Context) error {
+	return inbox.sync(ctx, true, map[string]string{})
 }
 
 // New will send a message to a user in an existring message thread if it exists,
@@ -289,6 +303,8 @@ func (c *Conversation) send(query map[string]string) error {
 			Endpoint: urlInboxSend,
 			IsPost:   true,
 			Query:    query,
+			NoRetry:  true,
+			Action:   ActionDM,
 		},
 	)
 	if err != nil {
@@ -320,7 +336,12 @@ func (inbox *Inbox) Reset() {
 
 // Next allows pagination over message threads.
 func (inbox *Inbox) Next() bool {
-	return inbox.next(false, map[string]string{
+	return inbox.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (inbox *Inbox) NextCtx(ctx context.Context) bool {
+	return inbox.next(ctx, false, map[string]string{
 		"persistentBadging": "true",
 		"cursor":            inbox.Cursor,
 	})
@@ -329,7 +350,13 @@ func (inbox *Inbox) Next() bool {
 // InitialSnapshot fetches the initial messages on app open, and is called
 //   from Instagram.OpenApp() automatically.
 func (inbox *Inbox) InitialSnapshot() bool {
-	return inbox.next(false, map[string]string{
+	return inbox.InitialSnapshotCtx(context.Background())
+}
+
+// InitialSnapshotCtx is like InitialSnapshot, but the request can be
+//   canceled with ctx.
+func (inbox *Inbox) InitialSnapshotCtx(ctx context.Context) bool {
+	return inbox.next(ctx, false, map[string]string{
 		"visual_message_return_type": "unseen",
 		"thread_message_limit":       "10",
 		"persistentBadging":          "true",
@@ -340,7 +367,13 @@ func (inbox *Inbox) InitialSnapshot() bool {
 
 // NextPending allows pagination over pending messages.
 func (inbox *Inbox) NextPending() bool {
-	return inbox.next(true, map[string]string{
+	return inbox.NextPendingCtx(context.Background())
+}
+
+// NextPendingCtx is like NextPending, but the request can be canceled with
+//   ctx.
+func (inbox *Inbox) NextPendingCtx(ctx context.Context) bool {
+	return inbox.next(ctx, true, map[string]string{
 		"cursor": inbox.Cursor,
 	})
 }
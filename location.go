@@ -43,8 +43,9 @@ func (l *LocationInstance) Feeds(locationID int64) (*Section, error) {
 	insta := l.insta
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: fmt.Sprintf(urlFeedLocations, locationID),
-			IsPost:   true,
+			Endpoint:   fmt.Sprintf(urlFeedLocations, locationID),
+			IsPost:     true,
+			Idempotent: true,
 			Query: map[string]string{
 				"rank_token":     insta.rankToken,
 				"ranked_content": "true",
@@ -67,8 +68,9 @@ func (l *Location) Feed() (*Section, error) {
 	insta := l.insta
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: fmt.Sprintf(urlFeedLocations, l.ID),
-			IsPost:   true,
+			Endpoint:   fmt.Sprintf(urlFeedLocations, l.ID),
+			IsPost:     true,
+			Idempotent: true,
 			Query: map[string]string{
 				"rank_token":     insta.rankToken,
 				"ranked_content": "true",
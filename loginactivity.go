@@ -0,0 +1,113 @@
+package goinsta
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// LoginSession is an active session, or a login of the account
+type LoginSession struct {
+	ID             string  `json:"id"`
+	LoginID        string  `json:"login_id"`
+	Device         string  `json:"device"`
+	DeviceID       string  `json:"device_id"`
+	Location       string  `json:"location"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	LoginTimestamp int64   `json:"login_timestamp"`
+	Timestamp      int64   `json:"timestamp"`
+	// IsCurrent is set for the session of this instance
+	IsCurrent bool `json:"is_current"`
+}
+
+// LoginTime returns the time of the login
+func (s LoginSession) LoginTime() time.Time {
+	return time.Unix(s.LoginTimestamp, 0)
+}
+
+// LastActive returns the time the session has been used last
+func (s LoginSession) LastActive() time.Time {
+	return time.Unix(s.Timestamp, 0)
+}
+
+// LoginActivity lists where the account is logged in, and logins Instagram
+//   considers suspicious.
+type LoginActivity struct {
+	Sessions []LoginSession `json:"sessions"`
+	// SuspiciousLogins have to be confirmed with ConfirmLogin or DenyLogin
+	SuspiciousLogins []LoginSession `json:"suspicious_logins"`
+	Status           string         `json:"status"`
+}
+
+// LoginActivity fetches the active sessions, and suspicious logins of the
+//   account.
+func (account *Account) LoginActivity() (*LoginActivity, error) {
+	insta := account.insta
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: urlLoginActivity,
+			Query:    map[string]string{"device_id": insta.dID},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	activity := &LoginActivity{}
+	if err := json.Unmarshal(body, activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// ConfirmLogin confirms a suspicious login: "This Was Me"
+func (account *Account) ConfirmLogin(login LoginSession) error {
+	return account.securityRequest(
+		urlLoginActivityAvow,
+		map[string]string{
+			"login_timestamp": strconv.FormatInt(login.LoginTimestamp, 10),
+			"login_id":        login.LoginID,
+		},
+		nil,
+	)
+}
+
+// DenyLogin reports a suspicious login: "This Wasn't Me". Instagram logs out
+//   the session. Change the password afterwards, see ChangePassword.
+func (account *Account) DenyLogin(login LoginSession) error {
+	return account.securityRequest(
+		urlLoginActivityUndo,
+		map[string]string{
+			"login_timestamp": strconv.FormatInt(login.LoginTimestamp, 10),
+			"login_id":        login.LoginID,
+		},
+		nil,
+	)
+}
+
+// LogoutSession logs out another session of the account, by its ID
+func (account *Account) LogoutSession(id string) error {
+	return account.securityRequest(
+		urlLoginActivityLogout,
+		map[string]string{"session_id": id},
+		nil,
+	)
+}
+
+// LogoutOtherSessions logs out all sessions of the account, except the
+//   current one.
+func (account *Account) LogoutOtherSessions() error {
+	activity, err := account.LoginActivity()
+	if err != nil {
+		return err
+	}
+	for _, s := range activity.Sessions {
+		if s.IsCurrent {
+			continue
+		}
+		if err := account.LogoutSession(s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
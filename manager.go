@@ -0,0 +1,351 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoAccountAvailable is returned by AccountManager.Next, if all accounts
+//   are unhealthy or cooling down.
+var ErrNoAccountAvailable = errors.New("No account available")
+
+// LoadError is returned by AccountManager.Load and LoadAll, if sessions could
+//   not be loaded. Errors maps the key of every failed session to its error,
+//   all other sessions have been loaded.
+type LoadError struct {
+	Errors map[string]error
+}
+
+func (e LoadError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = fmt.Sprintf("%s: %s", key, e.Errors[key])
+	}
+	return fmt.Sprintf("Failed to load %d sessions: %s", len(keys), strings.Join(msgs, "; "))
+}
+
+// AccountManager manages a pool of accounts, and hands out the best available
+//   account for read-only jobs, e.g. fetching profiles or feeds.
+//
+// Every account is a separate Instagram instance, with its own http client,
+//   cookie jar and device. Use Configure to set a proxy, rate limiter or
+//   automatic login per account. Accounts are handed out round-robin,
+//   skipping accounts which are rate limited, need to solve a challenge, are
+//   logged out, or are cooling down. Action blocked accounts are handed out,
+//   as reading is not blocked.
+//
+// Usage:
+//   store, _ := goinsta.NewFileStore("sessions")
+//   m := goinsta.NewAccountManager(store)
+//   m.Cooldown = 10 * time.Second
+//   m.Configure = func(key string, insta *goinsta.Instagram) error {
+//     return insta.SetProxy(proxies[key], false, true)
+//   }
+//   err := m.LoadAll()
+//
+//   insta, err := m.Wait(ctx)
+//   profile, err := insta.Profiles.ByName("instagram")
+//
+type AccountManager struct {
+	// Cooldown is the minimum time between handing out the same account
+	Cooldown time.Duration
+	// Configure is called for every account added to the manager
+	Configure func(key string, insta *Instagram) error
+	// WarnHandler is called with sessions which could not be loaded. If not
+	//   set, they are logged.
+	WarnHandler func(...interface{})
+
+	store SessionStore
+
+	mu       sync.Mutex
+	accounts []*managedAccount
+}
+
+// managedAccount is an account of the manager. insta is never changed, so
+//   that it can be read without holding the lock.
+type managedAccount struct {
+	key       string
+	insta     *Instagram
+	lastUsed  time.Time
+	restUntil time.Time
+}
+
+// AccountStatus describes an account of an AccountManager
+type AccountStatus struct {
+	Key      string
+	Username string
+	Health   Health
+	LastUsed time.Time
+	// Available reports whether the account is handed out now, or will be at
+	//   AvailableAt. Accounts which need to solve a challenge, or to login
+	//   again, are not available until their health is reset.
+	Available   bool
+	AvailableAt time.Time
+}
+
+// NewAccountManager creates an account manager, which loads the sessions
+//   from store, and saves them back to it. store can be nil, if all accounts
+//   are added with Add.
+func NewAccountManager(store SessionStore) *AccountManager {
+	return &AccountManager{store: store}
+}
+
+// Load imports the sessions of keys from the store, and adds the accounts.
+//   The accounts are not synced on import, to not send a request for every
+//   account at once.
+//
+// Sessions which can not be loaded, e.g. as they are corrupt, are skipped
+//   and passed to the WarnHandler. A LoadError listing them is returned,
+//   after all other sessions have been loaded.
+func (m *AccountManager) Load(keys ...string) error {
+	if m.store == nil {
+		return errors.New("No session store has been set")
+	}
+	failed := map[string]error{}
+	for _, key := range keys {
+		err := m.load(key)
+		if err != nil {
+			m.warn(fmt.Sprintf("Skipped session %s, as it could not be loaded:", key), err)
+			failed[key] = err
+		}
+	}
+	if len(failed) > 0 {
+		return LoadError{Errors: failed}
+	}
+	return nil
+}
+
+func (m *AccountManager) load(key string) error {
+	insta, err := ImportFromStore(m.store, key, true)
+	if err != nil {
+		return err
+	}
+	return m.Add(key, insta)
+}
+
+func (m *AccountManager) warn(args ...interface{}) {
+	if m.WarnHandler != nil {
+		m.WarnHandler(args...)
+		return
+	}
+	defaultHandler(args...)
+}
+
+// LoadAll loads all sessions of the store, which has to implement
+//   SessionLister. Sessions which can not be loaded are skipped, see Load.
+func (m *AccountManager) LoadAll() error {
+	lister, ok := m.store.(SessionLister)
+	if !ok {
+		return errors.New("Session store can not list its sessions")
+	}
+	keys, err := lister.Keys()
+	if err != nil {
+		return err
+	}
+	return m.Load(keys...)
+}
+
+// Add adds an account, replacing the account with the same key. If key is
+//   empty, the username is used. The session of the account is saved to the
+//   store of the manager.
+func (m *AccountManager) Add(key string, insta *Instagram) error {
+	if key == "" {
+		key = insta.user
+	}
+	if m.store != nil {
+		insta.SetSessionStore(m.store, key)
+	}
+	if m.Configure != nil {
+		if err := m.Configure(key, insta); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, a := range m.accounts {
+		if a.key == key {
+			m.accounts[i] = &managedAccount{key: key, insta: insta, lastUsed: a.lastUsed, restUntil: a.restUntil}
+			return nil
+		}
+	}
+	m.accounts = append(m.accounts, &managedAccount{key: key, insta: insta})
+	return nil
+}
+
+// Remove removes an account from the manager. The stored session is kept.
+func (m *AccountManager) Remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, a := range m.accounts {
+		if a.key == key {
+			m.accounts = append(m.accounts[:i], m.accounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Account returns the account of key, nil if there is none
+func (m *AccountManager) Account(key string) *Instagram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.key == key {
+			return a.insta
+		}
+	}
+	return nil
+}
+
+// Len returns the number of accounts
+func (m *AccountManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.accounts)
+}
+
+// Rest excludes an account from being handed out for d
+func (m *AccountManager) Rest(key string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.key == key {
+			a.restUntil = time.Now().Add(d)
+		}
+	}
+}
+
+// Status returns the status of all accounts
+func (m *AccountManager) Status() []AccountStatus {
+	accounts, health := m.snapshot()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := make([]AccountStatus, len(accounts))
+	for i, a := range accounts {
+		at, ok := m.availableAt(a, health[i])
+		status[i] = AccountStatus{
+			Key:         a.key,
+			Username:    a.insta.user,
+			Health:      health[i],
+			LastUsed:    a.lastUsed,
+			Available:   ok,
+			AvailableAt: at,
+		}
+	}
+	return status
+}
+
+// Next returns the available account, which has not been used for the longest
+//   time, or ErrNoAccountAvailable.
+func (m *AccountManager) Next() (*Instagram, error) {
+	accounts, health := m.snapshot()
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best *managedAccount
+	for i, a := range accounts {
+		at, ok := m.availableAt(a, health[i])
+		if !ok || at.After(now) {
+			continue
+		}
+		if best == nil || a.lastUsed.Before(best.lastUsed) {
+			best = a
+		}
+	}
+	if best == nil {
+		return nil, ErrNoAccountAvailable
+	}
+	best.lastUsed = now
+	return best.insta, nil
+}
+
+// Wait is like Next, but waits until an account becomes available. It returns
+//   ErrNoAccountAvailable without waiting, if no account is expected to
+//   become available.
+func (m *AccountManager) Wait(ctx context.Context) (*Instagram, error) {
+	for {
+		insta, err := m.Next()
+		if err != ErrNoAccountAvailable {
+			return insta, err
+		}
+
+		// check again at least every minute, as accounts may be added, or
+		//   their health may be reset
+		next := time.Now().Add(time.Minute)
+		available := false
+		for _, st := range m.Status() {
+			if !st.Available {
+				continue
+			}
+			available = true
+			if st.AvailableAt.Before(next) {
+				next = st.AvailableAt
+			}
+		}
+		if !available {
+			return nil, ErrNoAccountAvailable
+		}
+		if err := sleep(ctx, time.Until(next)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SaveAll saves the sessions of all accounts to the store. It continues on
+//   errors, and returns the first one.
+func (m *AccountManager) SaveAll() error {
+	accounts, _ := m.snapshot()
+	var first error
+	for _, a := range accounts {
+		if err := a.insta.SaveSession(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// snapshot returns the accounts and their health. The health is fetched
+//   without holding the lock, as health handlers may call the manager.
+func (m *AccountManager) snapshot() ([]*managedAccount, []Health) {
+	m.mu.Lock()
+	accounts := make([]*managedAccount, len(m.accounts))
+	copy(accounts, m.accounts)
+	m.mu.Unlock()
+
+	health := make([]Health, len(accounts))
+	for i, a := range accounts {
+		health[i] = a.insta.Health()
+	}
+	return accounts, health
+}
+
+// availableAt returns the time an account can be handed out again, and
+//   whether it is expected to become available at all. m.mu must be held.
+func (m *AccountManager) availableAt(a *managedAccount, h Health) (time.Time, bool) {
+	var at time.Time
+	switch h.State {
+	case HealthCheckpoint, HealthLoggedOut:
+		return at, false
+	case HealthRateLimited:
+		at = h.Until
+	}
+	if cooldown := a.lastUsed.Add(m.Cooldown); !a.lastUsed.IsZero() && cooldown.After(at) {
+		at = cooldown
+	}
+	if a.restUntil.After(at) {
+		at = a.restUntil
+	}
+	return at, true
+}
@@ -2,6 +2,7 @@ package goinsta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -103,6 +104,9 @@ type Item struct {
 	Lat             float64  `json:"lat,omitempty"`
 	Lng             float64  `json:"lng,omitempty"`
 
+	// UploadID is the ID the media has been uploaded with
+	UploadID string `json:"upload_id"`
+
 	// Carousel
 	CarouselParentID string `json:"carousel_parent_id"`
 	CarouselMedia    []Item `json:"carousel_media,omitempty"`
@@ -230,22 +234,27 @@ type Item struct {
 // If parent media is a Story this function will send a private message
 // replying the Instagram story.
 func (item *Item) Comment(text string) error {
+	return item.CommentCtx(context.Background(), text)
+}
+
+// CommentCtx is like Comment, but the requests can be canceled with ctx.
+func (item *Item) CommentCtx(ctx context.Context, text string) error {
 	// if comment is called on a story media, use reply
 	if item.ProductType == "story" {
-		return item.Reply(text)
+		return item.ReplyCtx(ctx, text)
 	}
 
-	o, err := item.CommentCheckOffensive(text)
+	o, err := item.CommentCheckOffensiveCtx(ctx, text)
 	if err != nil {
 		return err
 	}
 	if !o.IsOffensive {
-		return item.comment(text)
+		return item.comment(ctx, text)
 	}
 	return errors.New("Failed to post comment, flagged as offensive")
 }
 
-func (item *Item) comment(text string) error {
+func (item *Item) comment(ctx context.Context, text string) error {
 	insta := item.insta
 	query := map[string]string{
 		// "feed_position":           "",
@@ -284,12 +293,21 @@ func (item *Item) comment(text string) error {
 			Endpoint: fmt.Sprintf(urlCommentAdd, item.Pk),
 			Query:    map[string]string{"signed_body": "SIGNATURE." + string(b)},
 			IsPost:   true,
+			NoRetry:  true,
+			Action:   ActionComment,
+			Context:  ctx,
 		},
 	)
 	return err
 }
 
 func (item *Item) CommentCheckOffensive(comment string) (*CommentOffensive, error) {
+	return item.CommentCheckOffensiveCtx(context.Background(), comment)
+}
+
+// CommentCheckOffensiveCtx is like CommentCheckOffensive, but the request can
+//   be canceled with ctx.
+func (item *Item) CommentCheckOffensiveCtx(ctx context.Context, comment string) (*CommentOffensive, error) {
 	insta := item.insta
 	data, err := json.Marshal(map[string]string{
 		"media_id":           item.ID,
@@ -306,6 +324,7 @@ func (item *Item) CommentCheckOffensive(comment string) (*CommentOffensive, erro
 			Endpoint: urlCommentOffensive,
 			IsPost:   true,
 			Query:    generateSignature(data),
+			Context:  ctx,
 		},
 	)
 	if err != nil {
@@ -317,8 +336,13 @@ func (item *Item) CommentCheckOffensive(comment string) (*CommentOffensive, erro
 }
 
 func (item *Item) Reply(text string) error {
+	return item.ReplyCtx(context.Background(), text)
+}
+
+// ReplyCtx is like Reply, but the request can be canceled with ctx.
+func (item *Item) ReplyCtx(ctx context.Context, text string) error {
 	if item.ProductType != "story" {
-		return item.Comment(text)
+		return item.CommentCtx(ctx, text)
 	}
 
 	insta := item.insta
@@ -334,6 +358,9 @@ func (item *Item) Reply(text string) error {
 			Connection: "keep-alive",
 			Endpoint:   fmt.Sprintf("%s?media_type=%s", urlReplyStory, item.MediaToString()),
 			IsPost:     true,
+			NoRetry:    true,
+			Action:     ActionDM,
+			Context:    ctx,
 			Query: map[string]string{
 				"recipient_users":      to,
 				"action":               "send_item",
@@ -610,7 +637,9 @@ func (item *Item) changeLike(endpoint string) error {
 				"signed_body": "SIGNATURE." + string(data),
 				"d":           "0",
 			},
-			IsPost: true,
+			IsPost:  true,
+			NoRetry: true,
+			Action:  ActionLike,
 		},
 	)
 	return err
@@ -885,6 +914,11 @@ func (media *FeedMedia) GetNextID() string {
 // returns false when list reach the end.
 // if FeedMedia.Error() is ErrNoMore no problems have occurred.
 func (media *FeedMedia) Next(params ...interface{}) bool {
+	return media.NextCtx(context.Background(), params...)
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (media *FeedMedia) NextCtx(ctx context.Context, params ...interface{}) bool {
 	if media.err != nil {
 		return false
 	}
@@ -913,6 +947,7 @@ func (media *FeedMedia) Next(params ...interface{}) bool {
 		&reqOptions{
 			Endpoint: endpoint,
 			Query:    query,
+			Context:  ctx,
 		},
 	)
 	if err == nil {
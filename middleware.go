@@ -0,0 +1,97 @@
+package goinsta
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestInfo describes an API request right before it is sent. It is passed
+//   to every Middleware.BeforeRequest hook.
+//
+// Request is the fully prepared *http.Request, including all headers. Hooks are
+//   free to modify its headers, e.g. to experiment with request signing.
+type RequestInfo struct {
+	// Endpoint is the endpoint as passed to the request, e.g. "accounts/login/"
+	Endpoint string
+	// Method is either GET or POST
+	Method string
+	// Query holds the query or form parameters of the request
+	Query map[string]string
+	// Header is the header of Request
+	Header http.Header
+	// Request is the prepared http request
+	Request *http.Request
+	// Account is the logged in account, nil if not logged in
+	Account *Account
+	// Attempt is the number of the current attempt, starting at 1
+	Attempt int
+}
+
+// ResponseInfo describes the outcome of an API request. It is passed to every
+//   Middleware.AfterResponse hook.
+//
+// Err is the error that will be returned to the caller. A hook can replace it,
+//   e.g. to inject faults, or clear it.
+type ResponseInfo struct {
+	Request *RequestInfo
+
+	// StatusCode of the response, 0 if no response has been received
+	StatusCode int
+	// Header of the response, nil if no response has been received
+	Header http.Header
+	// Body of the response, already decompressed
+	Body []byte
+	// Duration it took to send the request and read the response
+	Duration time.Duration
+	// Err is the error of the request, after it has been decoded by goinsta
+	Err error
+}
+
+// Middleware can be used to hook into every request goinsta sends. It can be
+//   used to add logging, metrics, request signing experiments, or fault
+//   injection without the need to touch the transport.
+//
+// BeforeRequest is called before a request is sent. If it returns an error,
+//   the request will not be sent, and the error is returned to the caller.
+//
+// AfterResponse is called after the response has been received and decoded,
+//   or after a BeforeRequest hook aborted the request.
+//
+// Both are optional. BeforeRequest hooks are called in the order the middleware
+//   has been added, AfterResponse hooks in the reverse order.
+type Middleware struct {
+	BeforeRequest func(*RequestInfo) error
+	AfterResponse func(*ResponseInfo)
+}
+
+// Use adds one or more middlewares to the request chain.
+//
+// See the Middleware struct for more details.
+func (insta *Instagram) Use(m ...Middleware) {
+	insta.middleware = append(insta.middleware, m...)
+}
+
+// ClearMiddleware removes all previously added middlewares.
+func (insta *Instagram) ClearMiddleware() {
+	insta.middleware = nil
+}
+
+func (insta *Instagram) beforeRequest(info *RequestInfo) error {
+	for _, m := range insta.middleware {
+		if m.BeforeRequest == nil {
+			continue
+		}
+		if err := m.BeforeRequest(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (insta *Instagram) afterResponse(info *ResponseInfo) {
+	for i := len(insta.middleware) - 1; i >= 0; i-- {
+		if f := insta.middleware[i].AfterResponse; f != nil {
+			f(info)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package goinsta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Video codecs accepted by Instagram, H.264 and HEVC
+var videoCodecs = []string{"avc1", "avc3", "hvc1", "hev1"}
+
+// VideoInfo is the metadata of a MP4 or MOV video
+type VideoInfo struct {
+	// Codec is the sample entry type of the video track, e.g. avc1 for H.264
+	//   or hvc1 for HEVC
+	Codec string
+	// Width and Height are the display size, after applying Rotation
+	Width  int
+	Height int
+	// Rotation is the clockwise rotation in degrees: 0, 90, 180 or 270
+	Rotation  int
+	Duration  time.Duration
+	FrameRate float64
+	HasAudio  bool
+	// Bitrate is the average bitrate of the file in bits per second
+	Bitrate int64
+}
+
+// ReadVideoInfo reads the metadata of a MP4 or MOV video. Only the moov box
+//   is read, which can be located at the beginning or end of the file. The
+//   first video track is used, if the video has multiple ones.
+func ReadVideoInfo(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	moov, err := readMoov(r, size)
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := parseBoxes(moov)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{}
+	var timescale, duration uint64
+	if mvhd := findBox(boxes, "mvhd"); mvhd != nil {
+		timescale, duration, err = readTimes(mvhd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	video := false
+	for _, trak := range boxes {
+		if trak.typ != "trak" {
+			continue
+		}
+		t, err := parseTrack(trak.data)
+		if err != nil {
+			return nil, err
+		}
+		switch t.handler {
+		case "soun":
+			info.HasAudio = true
+		case "vide":
+			if video {
+				continue
+			}
+			video = true
+			info.Codec = t.codec
+			info.Width, info.Height = t.width, t.height
+			info.Rotation = t.rotation
+			if t.rotation == 90 || t.rotation == 270 {
+				info.Width, info.Height = t.height, t.width
+			}
+			if t.duration > 0 && t.timescale > 0 {
+				info.FrameRate = float64(t.samples) * float64(t.timescale) / float64(t.duration)
+			}
+			if duration == 0 && t.timescale > 0 {
+				timescale, duration = t.timescale, t.duration
+			}
+		}
+	}
+	if !video {
+		return nil, fmt.Errorf("%w: the video has no video track", ErrInvalidFormat)
+	}
+
+	if timescale > 0 {
+		info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	if s := info.Duration.Seconds(); s > 0 {
+		info.Bitrate = int64(float64(size*8) / s)
+	}
+	return info, nil
+}
+
+// validate checks whether Instagram accepts the video
+func (info *VideoInfo) validate() error {
+	for _, c := range videoCodecs {
+		if info.Codec == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unsupported video codec %s, use H.264 or HEVC", ErrInvalidFormat, info.Codec)
+}
+
+// mp4Box is an ISO-BMFF box, data is the payload after the header
+type mp4Box struct {
+	typ  string
+	data []byte
+}
+
+// mp4Track is the metadata of a track
+type mp4Track struct {
+	handler   string
+	codec     string
+	width     int
+	height    int
+	rotation  int
+	timescale uint64
+	duration  uint64
+	samples   uint64
+}
+
+var errInvalidMP4 = fmt.Errorf("%w: invalid mp4 container", ErrInvalidFormat)
+
+// ftypBrand returns the major brand of an ISO base media file, like MP4,
+//   QuickTime or HEIF, which starts with a ftyp box.
+func ftypBrand(b []byte) (string, bool) {
+	if len(b) < 12 || string(b[4:8]) != "ftyp" {
+		return "", false
+	}
+	return string(b[8:12]), true
+}
+
+// isHEIF reports whether brand is the brand of a HEIF image, e.g. HEIC
+func isHEIF(brand string) bool {
+	switch brand {
+	case "heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs", "mif1", "msf1", "avif":
+		return true
+	}
+	return false
+}
+
+// readMoov reads the moov box, which contains the metadata of the video,
+//   without reading the media data.
+func readMoov(r io.ReaderAt, size int64) ([]byte, error) {
+	header := make([]byte, 16)
+	for offset := int64(0); offset+8 <= size; {
+		if n, err := r.ReadAt(header[:8], offset); n < 8 {
+			return nil, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		hdr := int64(8)
+		switch boxSize {
+		case 0:
+			// the box extends to the end of the file
+			boxSize = size - offset
+		case 1:
+			// 64 bit size
+			if n, err := r.ReadAt(header[8:], offset+8); n < 8 {
+				return nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:]))
+			hdr = 16
+		}
+		if boxSize < hdr || boxSize > size-offset {
+			return nil, errInvalidMP4
+		}
+		if string(header[4:8]) == "moov" {
+			moov := make([]byte, boxSize-hdr)
+			if n, err := r.ReadAt(moov, offset+hdr); n < len(moov) {
+				return nil, err
+			}
+			return moov, nil
+		}
+		offset += boxSize
+	}
+	return nil, fmt.Errorf("%w: no moov box found", ErrInvalidFormat)
+}
+
+// parseBoxes splits b into its boxes
+func parseBoxes(b []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	// QuickTime files may end a list of boxes with 4 zero bytes
+	for len(b) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(b))
+		hdr := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, errInvalidMP4
+			}
+			size = binary.BigEndian.Uint64(b[8:])
+			hdr = 16
+		}
+		if size < hdr || size > uint64(len(b)) {
+			return nil, errInvalidMP4
+		}
+		boxes = append(boxes, mp4Box{typ: string(b[4:8]), data: b[hdr:size]})
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+// findBox returns the payload of the first box of typ, nil if there is none
+func findBox(boxes []mp4Box, typ string) []byte {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b.data
+		}
+	}
+	return nil
+}
+
+// findPath returns the payload of the first box at path below b
+func findPath(b []byte, path ...string) ([]byte, error) {
+	for _, typ := range path {
+		boxes, err := parseBoxes(b)
+		if err != nil {
+			return nil, err
+		}
+		if b = findBox(boxes, typ); b == nil {
+			return nil, nil
+		}
+	}
+	return b, nil
+}
+
+// readTimes reads the timescale and duration of a mvhd or mdhd box
+func readTimes(b []byte) (timescale, duration uint64, err error) {
+	if len(b) > 0 && b[0] == 1 {
+		// version 1 with 64 bit times
+		if len(b) < 32 {
+			return 0, 0, errInvalidMP4
+		}
+		return uint64(binary.BigEndian.Uint32(b[20:])), binary.BigEndian.Uint64(b[24:]), nil
+	}
+	if len(b) < 20 {
+		return 0, 0, errInvalidMP4
+	}
+	return uint64(binary.BigEndian.Uint32(b[12:])), uint64(binary.BigEndian.Uint32(b[16:])), nil
+}
+
+// parseTrack reads the metadata of a trak box
+func parseTrack(trak []byte) (*mp4Track, error) {
+	t := &mp4Track{}
+	boxes, err := parseBoxes(trak)
+	if err != nil {
+		return nil, err
+	}
+	mdia := findBox(boxes, "mdia")
+	if mdia == nil {
+		return t, nil
+	}
+
+	if hdlr, err := findPath(mdia, "hdlr"); err != nil {
+		return nil, err
+	} else if len(hdlr) >= 12 {
+		t.handler = string(hdlr[8:12])
+	}
+	if t.handler != "vide" {
+		return t, nil
+	}
+
+	if mdhd, err := findPath(mdia, "mdhd"); err != nil {
+		return nil, err
+	} else if mdhd != nil {
+		if t.timescale, t.duration, err = readTimes(mdhd); err != nil {
+			return nil, err
+		}
+	}
+
+	if tkhd := findBox(boxes, "tkhd"); tkhd != nil {
+		if err := t.parseHeader(tkhd); err != nil {
+			return nil, err
+		}
+	}
+
+	stbl, err := findPath(mdia, "minf", "stbl")
+	if err != nil || stbl == nil {
+		return t, err
+	}
+	stblBoxes, err := parseBoxes(stbl)
+	if err != nil {
+		return nil, err
+	}
+	if stsd := findBox(stblBoxes, "stsd"); len(stsd) >= 8 {
+		entries, err := parseBoxes(stsd[8:])
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			e := entries[0]
+			t.codec = e.typ
+			// the size of the encoded frames, if the track header has none
+			if len(e.data) >= 28 && t.width == 0 {
+				t.width = int(binary.BigEndian.Uint16(e.data[24:]))
+				t.height = int(binary.BigEndian.Uint16(e.data[26:]))
+			}
+		}
+	}
+	if stts := findBox(stblBoxes, "stts"); len(stts) >= 8 {
+		n := int(binary.BigEndian.Uint32(stts[4:]))
+		for i := 0; i < n && 8+i*8+8 <= len(stts); i++ {
+			t.samples += uint64(binary.BigEndian.Uint32(stts[8+i*8:]))
+		}
+	}
+	return t, nil
+}
+
+// parseHeader reads the display size and rotation from a tkhd box
+func (t *mp4Track) parseHeader(tkhd []byte) error {
+	// offset of the transformation matrix
+	offset := 40
+	if len(tkhd) > 0 && tkhd[0] == 1 {
+		offset = 52
+	}
+	if len(tkhd) < offset+44 {
+		return errInvalidMP4
+	}
+	m := tkhd[offset:]
+	a := int32(binary.BigEndian.Uint32(m))
+	b := int32(binary.BigEndian.Uint32(m[4:]))
+	deg := int(math.Round(math.Atan2(float64(b), float64(a))*180/math.Pi/90)) * 90
+	t.rotation = (deg + 360) % 360
+
+	// width and height are 16.16 fixed point numbers
+	t.width = int(binary.BigEndian.Uint32(m[36:]) >> 16)
+	t.height = int(binary.BigEndian.Uint32(m[40:]) >> 16)
+	return nil
+}
@@ -0,0 +1,932 @@
+package goinsta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned by a JobStore, if no job has been stored for an
+//   ID.
+var ErrJobNotFound = errors.New("Job not found")
+
+// JobStatus is the state of a PublishJob
+type JobStatus string
+
+// States of a PublishJob
+const (
+	// JobPending jobs are published at NextAttempt
+	JobPending JobStatus = "pending"
+	// JobRunning jobs are being published
+	JobRunning   JobStatus = "running"
+	JobPublished JobStatus = "published"
+	// JobFailed jobs failed with an error which can not be retried, or ran out
+	//   of attempts
+	JobFailed JobStatus = "failed"
+)
+
+// PublishJob is an upload scheduled with a Publisher. Jobs are stored as JSON.
+type PublishJob struct {
+	ID string `json:"id"`
+	// Account is the key of the account to publish with
+	Account string `json:"account"`
+	// At is the time the upload is scheduled for
+	At     time.Time `json:"at"`
+	Upload JobUpload `json:"upload"`
+
+	Status JobStatus `json:"status"`
+	// Attempts is the number of started attempts. NextAttempt is the time of
+	//   the next one, which is At, unless a failed attempt is retried.
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	// Error of the last failed attempt
+	Error string `json:"error,omitempty"`
+	// UploadID is set, while the upload of a running attempt is being
+	//   configured. If the publisher stops meanwhile, the media is looked up
+	//   by it, before the job is published again.
+	UploadID string `json:"upload_id,omitempty"`
+	// MediaID of the published media
+	MediaID string `json:"media_id,omitempty"`
+}
+
+// JobUpload are the UploadOptions of a PublishJob. Images are preprocessed
+//   when the job is scheduled, Preprocess and Progress are not stored.
+type JobUpload struct {
+	// Files is the file of the upload, or the files of the album
+	Files     []JobFile `json:"files"`
+	Album     bool      `json:"album,omitempty"`
+	Thumbnail []byte    `json:"-"`
+
+	Caption              string       `json:"caption,omitempty"`
+	IsStory              bool         `json:"is_story,omitempty"`
+	IsIGTV               bool         `json:"is_igtv,omitempty"`
+	Title                string       `json:"title,omitempty"`
+	IGTVPreview          bool         `json:"igtv_preview,omitempty"`
+	MuteAudio            bool         `json:"mute_audio,omitempty"`
+	DisableComments      bool         `json:"disable_comments,omitempty"`
+	DisableLikeViewCount bool         `json:"disable_like_view_count,omitempty"`
+	DisableSubtitles     bool         `json:"disable_subtitles,omitempty"`
+	ResumeAttempts       int          `json:"resume_attempts,omitempty"`
+	UserTags             *[]UserTag   `json:"user_tags,omitempty"`
+	AlbumTags            *[][]UserTag `json:"album_tags,omitempty"`
+	Location             *LocationTag `json:"location,omitempty"`
+}
+
+// JobFile is a file of a JobUpload. Videos read from an *os.File are
+//   referenced by Path, and have to be kept until the job has been published.
+//   All other files are stored with the media of the job, see JobStore. They
+//   are read from Media, which has Size bytes.
+type JobFile struct {
+	Path  string      `json:"path,omitempty"`
+	Media io.ReaderAt `json:"-"`
+	Size  int64       `json:"-"`
+}
+
+// JobStore persists the jobs of a Publisher. Implement it to store jobs in
+//   e.g. a database.
+//
+// The media of a job, the Media of its files and its thumbnail, is stored
+//   separately from the job with SaveMedia, so that jobs can be listed
+//   without reading it. Save, Load and List only handle the other fields.
+//   Media is read in parts while uploading, LoadMedia should not read videos
+//   into memory. If Media implements io.Closer, it is closed by the Publisher
+//   after the attempt.
+//
+// Implementations must be safe for concurrent use, and return copies of the
+//   stored jobs.
+type JobStore interface {
+	// Load returns the job stored for id, or ErrJobNotFound
+	Load(id string) (*PublishJob, error)
+	// Save stores the job, replacing the previous one with the same ID
+	Save(job *PublishJob) error
+	// Delete removes the job of id and its media. It is not an error if
+	//   there is no job.
+	Delete(id string) error
+	// List returns all stored jobs
+	List() ([]*PublishJob, error)
+	// SaveMedia stores the media of the job
+	SaveMedia(job *PublishJob) error
+	// LoadMedia sets the media stored for the job
+	LoadMedia(job *PublishJob) error
+}
+
+// Publisher publishes uploads at the time they are scheduled for. The jobs
+//   are persisted in a JobStore, so that they survive restarts, and are
+//   published one after another by Run, with the account returned for the
+//   key of the job.
+//
+// Failed attempts are retried with the backoff of Retry, if Instagram has not
+//   finished to transcode a video, or asks for a video to be reuploaded. Rate
+//   limits and network errors are retried, if they occurred before the upload
+//   was configured. If configuring a post fails, it is looked up by its upload
+//   ID in the feed of the account, as Instagram may have created it anyway,
+//   and only retried if it is not found. Uploads are validated when they are
+//   scheduled.
+//
+// The upload ID is stored with the job, before the upload is configured. Jobs
+//   which were interrupted meanwhile are looked up the same way by Run, and
+//   only published again if the post is not found. Stories and IGTV videos
+//   can not be looked up, such jobs fail instead of being published twice,
+//   as do jobs whose lookup fails.
+//
+// Usage:
+//   store, _ := goinsta.NewFileJobStore("jobs")
+//   p := goinsta.NewPublisher(store, m.Account)
+//   p.OnPublished = func(job *goinsta.PublishJob, item *goinsta.Item) {
+//     log.Println("Published", job.ID, item.Code)
+//   }
+//   p.OnFailed = func(job *goinsta.PublishJob, err error) {
+//     log.Println("Failed to publish", job.ID, err)
+//   }
+//   go p.Run(ctx)
+//
+//   f, _ := os.Open("video.mp4")
+//   job, err := p.Schedule("alice", time.Now().Add(time.Hour), &goinsta.UploadOptions{File: f})
+//
+type Publisher struct {
+	// Retry configures how often and when failed attempts are retried,
+	//   DefaultRetryPolicy is used if nil
+	Retry *RetryPolicy
+	// TranscodeWait is how long an upload waits for Instagram to transcode a
+	//   video, before publishing it is tried again, 6 seconds by default
+	TranscodeWait time.Duration
+	// KeepFinished keeps jobs in the store after they have been published or
+	//   have failed. By default they are deleted, once OnPublished or
+	//   OnFailed has returned.
+	KeepFinished bool
+
+	// OnPublished is called after a job has been published
+	OnPublished func(job *PublishJob, item *Item)
+	// OnFailed is called after a job has failed, and will not be retried
+	OnFailed func(job *PublishJob, err error)
+
+	store   JobStore
+	account func(key string) *Instagram
+
+	// mu guards the status of the stored jobs, wake is signaled if a job
+	//   has been scheduled
+	mu   sync.Mutex
+	wake chan struct{}
+}
+
+// NewPublisher creates a publisher, which persists its jobs in store, and
+//   publishes them with the accounts returned by account for the key of a
+//   job, e.g. AccountManager.Account.
+func NewPublisher(store JobStore, account func(key string) *Instagram) *Publisher {
+	return &Publisher{
+		store:   store,
+		account: account,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Schedule validates the upload, and stores it as job, which is published
+//   with the account of key at the given time. Jobs scheduled for the past
+//   are published immediately.
+//
+// The files are read by Schedule, and can be closed afterwards, except for
+//   videos read from an *os.File, see JobFile.
+func (p *Publisher) Schedule(key string, at time.Time, o *UploadOptions) (*PublishJob, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	upload, err := newJobUpload(o)
+	if err != nil {
+		return nil, err
+	}
+	job := &PublishJob{
+		ID:          generateUUID(),
+		Account:     key,
+		At:          at,
+		Upload:      *upload,
+		Status:      JobPending,
+		NextAttempt: at,
+	}
+	if err := p.store.SaveMedia(job); err != nil {
+		return nil, err
+	}
+	if err := p.store.Save(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Job returns the job of id, without its media
+func (p *Publisher) Job(id string) (*PublishJob, error) {
+	return p.store.Load(id)
+}
+
+// Jobs returns all jobs without their media, ordered by the time they are
+//   scheduled for. Published and failed jobs are only included, if
+//   KeepFinished is set, until they are removed with Cancel.
+func (p *Publisher) Jobs() ([]*PublishJob, error) {
+	jobs, err := p.store.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].At.Before(jobs[j].At) })
+	return jobs, nil
+}
+
+// Cancel removes a job from the store. Jobs which are being published can not
+//   be canceled.
+func (p *Publisher) Cancel(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, err := p.store.Load(id)
+	if err != nil {
+		return err
+	}
+	if job.Status == JobRunning {
+		return fmt.Errorf("Job %s is being published", id)
+	}
+	return p.store.Delete(id)
+}
+
+// Run publishes the jobs when they are due, until ctx is done, and returns
+//   its error. Errors of the store are returned as well.
+//
+// Jobs which were running when the publisher stopped are started again,
+//   unless they have been published already, see Publisher.
+func (p *Publisher) Run(ctx context.Context) error {
+	if err := p.restart(); err != nil {
+		return err
+	}
+	for {
+		job, next, err := p.next()
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			if err := p.publish(ctx, job); err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := p.wait(ctx, next); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits until next, or until a job has been scheduled. If next is zero,
+//   there is no pending job to wait for.
+func (p *Publisher) wait(ctx context.Context, next time.Time) error {
+	var due <-chan time.Time
+	if !next.IsZero() {
+		t := time.NewTimer(time.Until(next))
+		defer t.Stop()
+		due = t.C
+	}
+	select {
+	case <-due:
+	case <-p.wake:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// restart sets jobs, which were running when the publisher stopped, back to
+//   pending
+func (p *Publisher) restart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.store.List()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Status != JobRunning {
+			continue
+		}
+		job.Status = JobPending
+		job.NextAttempt = time.Now()
+		if err := p.store.Save(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next marks the next due job as running, and returns it. If no job is due,
+//   the time of the next pending job is returned, which is zero if there is
+//   none.
+func (p *Publisher) next() (*PublishJob, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, err := p.store.List()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var first *PublishJob
+	for _, job := range jobs {
+		if job.Status == JobPending && (first == nil || job.NextAttempt.Before(first.NextAttempt)) {
+			first = job
+		}
+	}
+	if first == nil {
+		return nil, time.Time{}, nil
+	}
+	if first.NextAttempt.After(time.Now()) {
+		return nil, first.NextAttempt, nil
+	}
+
+	first.Status = JobRunning
+	first.Attempts++
+	return first, time.Time{}, p.store.Save(first)
+}
+
+// publish uploads a running job, and stores the result. If ctx is done, the
+//   job is set back to pending.
+func (p *Publisher) publish(ctx context.Context, job *PublishJob) error {
+	item, uploadID, err := p.resume(ctx, job)
+	if item == nil && err == nil {
+		item, uploadID, err = p.upload(ctx, job)
+		if err != nil && uploadID != "" && !errors.Is(err, ErrTranscoding) && !errors.Is(err, ErrMediaNeedsReupload) {
+			// the media may have been created, although configure failed
+			if found, ferr := p.findUploaded(ctx, job, uploadID); found != nil {
+				item, err = found, nil
+			} else if ferr == nil {
+				uploadID = ""
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		job.Status = JobPending
+		job.Attempts--
+		return p.save(job)
+	}
+
+	if err == nil {
+		job.Status = JobPublished
+		job.MediaID = item.ID
+		job.Error = ""
+		if err := p.save(job); err != nil {
+			return err
+		}
+		if p.OnPublished != nil {
+			p.OnPublished(job, item)
+		}
+		return p.finish(job)
+	}
+
+	policy := p.Retry
+	if policy == nil {
+		policy = &DefaultRetryPolicy
+	}
+	job.Error = err.Error()
+	if canRetryPublish(err, uploadID != "") && job.Attempts < policy.MaxAttempts {
+		d := RetryAfter(err)
+		if d == 0 {
+			d, _ = policy.delay(job.Attempts, 0)
+		}
+		job.Status = JobPending
+		job.NextAttempt = time.Now().Add(d)
+		job.UploadID = ""
+		return p.save(job)
+	}
+
+	job.Status = JobFailed
+	if err := p.save(job); err != nil {
+		return err
+	}
+	if p.OnFailed != nil {
+		p.OnFailed(job, err)
+	}
+	return p.finish(job)
+}
+
+// resume looks up the media of an attempt, which has been interrupted after
+//   its upload has been configured, and returns its upload ID. No item and no
+//   error are returned, if the attempt has not been interrupted, or no media
+//   has been found.
+func (p *Publisher) resume(ctx context.Context, job *PublishJob) (*Item, string, error) {
+	if job.UploadID == "" {
+		return nil, "", nil
+	}
+	item, err := p.findUploaded(ctx, job, job.UploadID)
+	if err != nil {
+		return nil, job.UploadID, fmt.Errorf("Publishing has been interrupted, and the upload could not be looked up: %w", err)
+	}
+	if item == nil {
+		job.UploadID = ""
+		return nil, "", nil
+	}
+	return item, job.UploadID, nil
+}
+
+// upload publishes the upload of a job. If configuring the upload has been
+//   tried, its upload ID is returned as well.
+func (p *Publisher) upload(ctx context.Context, job *PublishJob) (*Item, string, error) {
+	insta := p.account(job.Account)
+	if insta == nil {
+		return nil, "", fmt.Errorf("No account for key %q", job.Account)
+	}
+	if err := p.store.LoadMedia(job); err != nil {
+		return nil, "", err
+	}
+	defer job.Upload.closeMedia()
+	o, closeFiles, err := job.Upload.options()
+	if err != nil {
+		return nil, "", err
+	}
+	defer closeFiles()
+
+	o.transcodeWait = p.TranscodeWait
+	o.onConfigure = func(uploadID string) error {
+		job.UploadID = uploadID
+		return p.save(job)
+	}
+	item, err := insta.UploadCtx(ctx, o)
+	return item, o.configured, err
+}
+
+// findUploaded looks for the media with uploadID in the feed of the account,
+//   and returns nil if there is none. Stories and IGTV videos can not be
+//   looked up.
+func (p *Publisher) findUploaded(ctx context.Context, job *PublishJob, uploadID string) (*Item, error) {
+	if job.Upload.IsStory || job.Upload.IsIGTV {
+		return nil, errors.New("Stories and IGTV videos can not be looked up")
+	}
+	insta := p.account(job.Account)
+	if insta == nil || insta.Account == nil {
+		return nil, fmt.Errorf("No account for key %q", job.Account)
+	}
+	feed := insta.Account.Feed()
+	if !feed.NextCtx(ctx) {
+		if err := feed.Error(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Failed to load the feed of the account")
+	}
+	for i := range feed.Items {
+		if feed.Items[i].UploadID == uploadID {
+			return &feed.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *Publisher) save(job *PublishJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.store.Save(job)
+}
+
+// finish deletes a published or failed job, unless KeepFinished is set
+func (p *Publisher) finish(job *PublishJob) error {
+	if p.KeepFinished {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.store.Delete(job.ID)
+}
+
+// canRetryPublish reports whether a failed attempt to publish can be retried.
+//   Retryable errors other than ErrTranscoding and ErrMediaNeedsReupload are
+//   only retried, if the upload has not been configured, as Instagram may
+//   have created the media.
+func canRetryPublish(err error, configured bool) bool {
+	if errors.Is(err, ErrTranscoding) || errors.Is(err, ErrMediaNeedsReupload) {
+		return true
+	}
+	return !configured && (IsRetryable(err) || isTransientErr(err))
+}
+
+// newJobUpload creates the job upload of validated upload options
+func newJobUpload(o *UploadOptions) (*JobUpload, error) {
+	readers := o.Album
+	if len(readers) == 0 {
+		readers = []io.Reader{o.File}
+	}
+
+	u := &JobUpload{
+		Album:                len(o.Album) > 0,
+		Caption:              o.Caption,
+		IsStory:              o.IsStory,
+		IsIGTV:               o.IsIGTV,
+		Title:                o.Title,
+		IGTVPreview:          o.IGTVPreview,
+		MuteAudio:            o.MuteAudio,
+		DisableComments:      o.DisableComments,
+		DisableLikeViewCount: o.DisableLikeViewCount,
+		DisableSubtitles:     o.DisableSubtitles,
+		ResumeAttempts:       o.ResumeAttempts,
+		UserTags:             o.UserTags,
+		AlbumTags:            o.AlbumTags,
+		Location:             o.Location,
+	}
+	for i, f := range o.files {
+		file, err := newJobFile(readers[i], f)
+		if err != nil {
+			return nil, err
+		}
+		u.Files = append(u.Files, file)
+	}
+	if o.Thumbnail != nil {
+		b, err := ioutil.ReadAll(o.Thumbnail)
+		if err != nil {
+			return nil, err
+		}
+		u.Thumbnail = b
+	}
+	return u, nil
+}
+
+// newJobFile references videos streamed from a file by path. All other files
+//   are read from their source, when the media of the job is saved.
+func newJobFile(r io.Reader, f *uploadFile) (JobFile, error) {
+	if file, ok := r.(*os.File); ok && f.buf == nil {
+		path, err := filepath.Abs(file.Name())
+		return JobFile{Path: path}, err
+	}
+	if f.buf != nil {
+		return JobFile{Media: bytes.NewReader(f.buf.Bytes()), Size: int64(f.buf.Len())}, nil
+	}
+	return JobFile{Media: f.src, Size: f.size}, nil
+}
+
+// options creates the upload options of a job, and opens its files. The
+//   returned function closes them.
+func (u *JobUpload) options() (*UploadOptions, func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	readers := make([]io.Reader, len(u.Files))
+	for i, f := range u.Files {
+		if f.Path == "" {
+			readers[i] = io.NewSectionReader(f.Media, 0, f.Size)
+			continue
+		}
+		file, err := os.Open(f.Path)
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		files = append(files, file)
+		readers[i] = file
+	}
+
+	o := &UploadOptions{
+		Caption:              u.Caption,
+		IsStory:              u.IsStory,
+		IsIGTV:               u.IsIGTV,
+		Title:                u.Title,
+		IGTVPreview:          u.IGTVPreview,
+		MuteAudio:            u.MuteAudio,
+		DisableComments:      u.DisableComments,
+		DisableLikeViewCount: u.DisableLikeViewCount,
+		DisableSubtitles:     u.DisableSubtitles,
+		ResumeAttempts:       u.ResumeAttempts,
+		UserTags:             u.UserTags,
+		AlbumTags:            u.AlbumTags,
+		Location:             u.Location,
+	}
+	if u.Album {
+		o.Album = readers
+	} else if len(readers) > 0 {
+		o.File = readers[0]
+	}
+	if u.Thumbnail != nil {
+		o.Thumbnail = bytes.NewReader(u.Thumbnail)
+	}
+	return o, closeFiles, nil
+}
+
+// closeMedia closes the media of the files, which implements io.Closer
+func (u *JobUpload) closeMedia() {
+	for _, f := range u.Files {
+		if c, ok := f.Media.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// jobMedia is the media of a job, as kept by MemoryJobStore
+type jobMedia struct {
+	files     []JobFile
+	thumbnail []byte
+}
+
+func newJobMedia(job *PublishJob) *jobMedia {
+	return &jobMedia{
+		files:     append([]JobFile{}, job.Upload.Files...),
+		thumbnail: job.Upload.Thumbnail,
+	}
+}
+
+// set sets the media of the job. The media is wrapped, so that the Publisher
+//   does not close it after an attempt.
+func (m *jobMedia) set(job *PublishJob) error {
+	if len(m.files) != len(job.Upload.Files) {
+		return fmt.Errorf("Media of job %s has %d files, expected %d", job.ID, len(m.files), len(job.Upload.Files))
+	}
+	for i, f := range m.files {
+		if f.Media != nil {
+			job.Upload.Files[i].Media = io.NewSectionReader(f.Media, 0, f.Size)
+			job.Upload.Files[i].Size = f.Size
+		}
+	}
+	job.Upload.Thumbnail = m.thumbnail
+	return nil
+}
+
+// FileJobStore stores every job as JSON file in a directory, and its media
+//   as raw files in a directory next to it
+type FileJobStore struct {
+	Dir string
+}
+
+// NewFileJobStore creates a file job store, and the directory if needed
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileJobStore{Dir: dir}, nil
+}
+
+func (s *FileJobStore) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("Invalid job ID %q", id)
+	}
+	return filepath.Join(s.Dir, id+".json"), nil
+}
+
+func (s *FileJobStore) mediaPath(id string) (string, error) {
+	path, err := s.path(id)
+	return strings.TrimSuffix(path, ".json") + ".media", err
+}
+
+// Load reads the job of id from its file
+func (s *FileJobStore) Load(id string) (*PublishJob, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	job := &PublishJob{}
+	if err := json.Unmarshal(b, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Save writes the job to its file, which is replaced atomically
+func (s *FileJobStore) Save(job *PublishJob) error {
+	path, err := s.path(job.ID)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// Delete removes the job file of id, and its media
+func (s *FileJobStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	media, _ := s.mediaPath(id)
+	return os.RemoveAll(media)
+}
+
+// SaveMedia writes the media of the job to its directory. The files are
+//   copied from their Media one after another, so that videos are never read
+//   into memory. The directory is replaced atomically.
+func (s *FileJobStore) SaveMedia(job *PublishJob) error {
+	path, err := s.mediaPath(job.ID)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(s.Dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, f := range job.Upload.Files {
+		if f.Path != "" {
+			continue
+		}
+		r := io.NewSectionReader(f.Media, 0, f.Size)
+		if err := writeMediaFile(filepath.Join(tmp, strconv.Itoa(i)), r); err != nil {
+			return err
+		}
+	}
+	if job.Upload.Thumbnail != nil {
+		r := bytes.NewReader(job.Upload.Thumbnail)
+		if err := writeMediaFile(filepath.Join(tmp, "thumbnail"), r); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeMediaFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadMedia opens the media files of the job, which are closed by the
+//   Publisher after the attempt. The thumbnail is read into memory.
+func (s *FileJobStore) LoadMedia(job *PublishJob) error {
+	path, err := s.mediaPath(job.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ErrJobNotFound
+	} else if err != nil {
+		return err
+	}
+
+	for i := range job.Upload.Files {
+		f := &job.Upload.Files[i]
+		if f.Path != "" {
+			continue
+		}
+		file, err := os.Open(filepath.Join(path, strconv.Itoa(i)))
+		if err != nil {
+			job.Upload.closeMedia()
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			job.Upload.closeMedia()
+			return err
+		}
+		f.Media, f.Size = file, info.Size()
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(path, "thumbnail"))
+	if err != nil && !os.IsNotExist(err) {
+		job.Upload.closeMedia()
+		return err
+	}
+	job.Upload.Thumbnail = b
+	return nil
+}
+
+// List reads all jobs stored in the directory
+func (s *FileJobStore) List() ([]*PublishJob, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*PublishJob
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		job, err := s.Load(strings.TrimSuffix(name, ".json"))
+		if err == ErrJobNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// MemoryJobStore keeps jobs in memory. Jobs do not survive restarts, it is
+//   mostly useful for tests.
+type MemoryJobStore struct {
+	mu    sync.Mutex
+	jobs  map[string][]byte
+	media map[string]*jobMedia
+}
+
+// NewMemoryJobStore creates an empty memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string][]byte{}, media: map[string]*jobMedia{}}
+}
+
+// Load returns a copy of the job stored for id
+func (s *MemoryJobStore) Load(id string) (*PublishJob, error) {
+	s.mu.Lock()
+	b, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	job := &PublishJob{}
+	if err := json.Unmarshal(b, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Save stores a copy of the job
+func (s *MemoryJobStore) Save(job *PublishJob) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = b
+	return nil
+}
+
+// Delete removes the job stored for id, and its media
+func (s *MemoryJobStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	delete(s.media, id)
+	return nil
+}
+
+// SaveMedia stores the media of the job. It is not copied, and has to stay
+//   readable and unchanged until the job has been published.
+func (s *MemoryJobStore) SaveMedia(job *PublishJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.media[job.ID] = newJobMedia(job)
+	return nil
+}
+
+// LoadMedia sets the media stored for the job
+func (s *MemoryJobStore) LoadMedia(job *PublishJob) error {
+	s.mu.Lock()
+	m, ok := s.media[job.ID]
+	s.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+	return m.set(job)
+}
+
+// List returns copies of all stored jobs
+func (s *MemoryJobStore) List() ([]*PublishJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*PublishJob, 0, len(s.jobs))
+	for _, b := range s.jobs {
+		job := &PublishJob{}
+		if err := json.Unmarshal(b, job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,237 @@
+package goinsta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Action is a class of requests, which share a common rate limit budget.
+type Action string
+
+const (
+	ActionFollow  Action = "follow"
+	ActionLike    Action = "like"
+	ActionComment Action = "comment"
+	ActionDM      Action = "dm"
+	ActionRead    Action = "read"
+)
+
+// Budget describes how many actions of one class can be performed.
+//
+// PerHour is enforced with a token bucket, that refills continuously at a rate
+//   of PerHour tokens per hour. Burst is the size of the bucket, which defaults
+//   to PerHour if not set. PerDay is a hard cap per rolling 24h window.
+// A value of 0 means no limit.
+type Budget struct {
+	PerHour int `json:"per_hour"`
+	PerDay  int `json:"per_day"`
+	Burst   int `json:"burst"`
+}
+
+// DefaultBudgets are conservative budgets, which should keep most accounts
+//   clear of action blocks.
+var DefaultBudgets = map[Action]Budget{
+	ActionFollow:  {PerHour: 20, PerDay: 150, Burst: 5},
+	ActionLike:    {PerHour: 60, PerDay: 500, Burst: 10},
+	ActionComment: {PerHour: 15, PerDay: 100, Burst: 3},
+	ActionDM:      {PerHour: 20, PerDay: 100, Burst: 5},
+	ActionRead:    {PerHour: 600, Burst: 60},
+}
+
+// ErrBudgetExceeded is returned if the rate limit budget of an action has been
+//   used up, and the rate limiter is not set to wait. Wait is the time until
+//   the next action of this class will be allowed.
+type ErrBudgetExceeded struct {
+	Action Action
+	Wait   time.Duration
+}
+
+func (e ErrBudgetExceeded) Error() string {
+	return fmt.Sprintf(
+		"Rate limit budget for %s actions exceeded, next action possible in %s",
+		e.Action, e.Wait.Round(time.Second),
+	)
+}
+
+// RateLimiter paces requests per action class, to prevent action blocks.
+// Use Instagram.SetRateLimiter to enable it. The limiter, including the current
+//   state of all budgets, is stored with Export, and restored on Import.
+//
+// If Block is true, requests will wait until budget is available, otherwise
+//   ErrBudgetExceeded is returned.
+type RateLimiter struct {
+	mu sync.Mutex
+
+	Budgets map[Action]Budget
+	Block   bool
+
+	buckets map[Action]*bucket
+}
+
+type bucket struct {
+	Tokens   float64   `json:"tokens"`
+	Updated  time.Time `json:"updated"`
+	DayStart time.Time `json:"day_start"`
+	DayCount int       `json:"day_count"`
+}
+
+// NewRateLimiter creates a new rate limiter with the provided budgets. If no
+//   budgets are provided, DefaultBudgets will be used.
+func NewRateLimiter(budgets map[Action]Budget, block bool) *RateLimiter {
+	if budgets == nil {
+		budgets = map[Action]Budget{}
+		for k, v := range DefaultBudgets {
+			budgets[k] = v
+		}
+	}
+	return &RateLimiter{
+		Budgets: budgets,
+		Block:   block,
+		buckets: map[Action]*bucket{},
+	}
+}
+
+// SetRateLimiter sets the rate limiter used for all requests. Pass nil to
+//   disable rate limiting, which is the default.
+func (insta *Instagram) SetRateLimiter(l *RateLimiter) {
+	insta.limiter = l
+}
+
+// RateLimiter returns the current rate limiter, nil if not set.
+func (insta *Instagram) RateLimiter() *RateLimiter {
+	return insta.limiter
+}
+
+// Remaining returns how many actions of a class can be performed right now.
+//   -1 means unlimited.
+func (l *RateLimiter) Remaining(a Action) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	budget, ok := l.Budgets[a]
+	if !ok {
+		return -1
+	}
+	b := l.refill(a, budget, time.Now())
+	r := -1
+	if budget.PerHour > 0 {
+		r = int(math.Floor(b.Tokens))
+	}
+	if budget.PerDay > 0 {
+		if d := budget.PerDay - b.DayCount; r == -1 || d < r {
+			r = d
+		}
+	}
+	return r
+}
+
+// take consumes one token of action a. If no token is available, it returns
+//   the time to wait until the next one is.
+func (l *RateLimiter) take(a Action) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	budget, ok := l.Budgets[a]
+	if !ok {
+		return 0, true
+	}
+	now := time.Now()
+	b := l.refill(a, budget, now)
+
+	if budget.PerDay > 0 && b.DayCount >= budget.PerDay {
+		return b.DayStart.Add(24 * time.Hour).Sub(now), false
+	}
+	if budget.PerHour > 0 {
+		if b.Tokens < 1 {
+			rate := float64(budget.PerHour) / float64(time.Hour)
+			return time.Duration((1 - b.Tokens) / rate), false
+		}
+		b.Tokens--
+	}
+	b.DayCount++
+	return 0, true
+}
+
+// refill updates the bucket of action a to time now, and returns it
+func (l *RateLimiter) refill(a Action, budget Budget, now time.Time) *bucket {
+	if l.buckets == nil {
+		l.buckets = map[Action]*bucket{}
+	}
+	burst := float64(budget.Burst)
+	if burst <= 0 {
+		burst = float64(budget.PerHour)
+	}
+
+	b, ok := l.buckets[a]
+	if !ok {
+		b = &bucket{Tokens: burst, Updated: now, DayStart: now}
+		l.buckets[a] = b
+	}
+
+	if budget.PerHour > 0 {
+		elapsed := now.Sub(b.Updated)
+		b.Tokens += float64(budget.PerHour) * elapsed.Hours()
+		b.Tokens = math.Min(b.Tokens, burst)
+	}
+	b.Updated = now
+
+	if now.Sub(b.DayStart) >= 24*time.Hour {
+		b.DayStart = now
+		b.DayCount = 0
+	}
+	return b
+}
+
+// wait blocks until budget for action a is available, or returns
+//   ErrBudgetExceeded if the limiter is not set to block.
+func (l *RateLimiter) wait(ctx context.Context, a Action) error {
+	for {
+		d, ok := l.take(a)
+		if ok {
+			return nil
+		}
+		if !l.Block {
+			return ErrBudgetExceeded{Action: a, Wait: d}
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+type rateLimiterJSON struct {
+	Budgets map[Action]Budget  `json:"budgets"`
+	Block   bool               `json:"block"`
+	Buckets map[Action]*bucket `json:"buckets"`
+}
+
+func (l *RateLimiter) MarshalJSON() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return json.Marshal(rateLimiterJSON{
+		Budgets: l.Budgets,
+		Block:   l.Block,
+		Buckets: l.buckets,
+	})
+}
+
+func (l *RateLimiter) UnmarshalJSON(b []byte) error {
+	tmp := rateLimiterJSON{}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Budgets = tmp.Budgets
+	l.Block = tmp.Block
+	l.buckets = tmp.Buckets
+	if l.buckets == nil {
+		l.buckets = map[Action]*bucket{}
+	}
+	return nil
+}
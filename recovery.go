@@ -0,0 +1,218 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UliSotschok/goinsta/utilities"
+)
+
+// Methods to receive a password reset
+const (
+	PasswordResetEmail = "email"
+	PasswordResetSMS   = "sms"
+)
+
+// PasswordReset is a pending password reset, started with
+//   Instagram.RequestPasswordReset.
+//
+// A reset link is sent by email, which has to be passed to SubmitLink. A code
+//   is sent by SMS, which has to be passed to SubmitCode. Afterwards the new
+//   password can be set with SetPassword.
+type PasswordReset struct {
+	insta *Instagram
+
+	// Query is the username, email address or phone number of the account
+	Query string
+	// Method is how the reset has been sent, PasswordResetEmail or
+	//   PasswordResetSMS
+	Method string
+	// Contact is the obfuscated email address or phone number, the reset has
+	//   been sent to
+	Contact string
+	UserID  int64
+	// Token authorizes to set a new password, and is set by SubmitCode or
+	//   SubmitLink
+	Token string
+
+	waterfallID string
+}
+
+type lookupResp struct {
+	User struct {
+		ID       int64  `json:"pk"`
+		Username string `json:"username"`
+	} `json:"user"`
+	CanEmailReset   bool   `json:"can_email_reset"`
+	CanSMSReset     bool   `json:"can_sms_reset"`
+	ObfuscatedEmail string `json:"obfuscated_email"`
+	ObfuscatedPhone string `json:"obfuscated_phone"`
+	Status          string `json:"status"`
+}
+
+// RequestPasswordReset looks up the account by username, email address or
+//   phone number, and sends a password reset. A reset by SMS is preferred if
+//   the query is a phone number, otherwise by email if possible.
+//
+// The password reset is sent before login, so insta can be a new instance.
+func (insta *Instagram) RequestPasswordReset(query string) (*PasswordReset, error) {
+	return insta.RequestPasswordResetCtx(context.Background(), query)
+}
+
+// RequestPasswordResetCtx is like RequestPasswordReset, but can be canceled
+//   with ctx.
+func (insta *Instagram) RequestPasswordResetCtx(ctx context.Context, query string) (*PasswordReset, error) {
+	if err := insta.prelogin(ctx); err != nil {
+		return nil, err
+	}
+
+	r := &PasswordReset{
+		insta:       insta,
+		Query:       query,
+		waterfallID: generateUUID(),
+	}
+	lookup := lookupResp{}
+	err := insta.signedRequest(ctx, urlLookup, map[string]string{
+		"q":                query,
+		"directly_sign_in": "true",
+		"phone_id":         insta.fID,
+		"guid":             insta.uuid,
+		"device_id":        insta.dID,
+		"waterfall_id":     r.waterfallID,
+	}, &lookup)
+	if err != nil {
+		return nil, err
+	}
+	r.UserID = lookup.User.ID
+
+	isPhone := strings.Trim(query, "+0123456789 -") == ""
+	switch {
+	case lookup.CanSMSReset && (isPhone || !lookup.CanEmailReset):
+		r.Method = PasswordResetSMS
+		r.Contact = lookup.ObfuscatedPhone
+		var resp struct {
+			Status string `json:"status"`
+		}
+		err = insta.signedRequest(ctx, urlSendRecoverySMS, map[string]string{
+			"supports_sms_code": "true",
+			"query":             query,
+			"use_whatsapp":      "false",
+			"guid":              insta.uuid,
+			"device_id":         insta.dID,
+			"waterfall_id":      r.waterfallID,
+		}, &resp)
+	case lookup.CanEmailReset:
+		r.Method = PasswordResetEmail
+		r.Contact = lookup.ObfuscatedEmail
+		var resp struct {
+			EmailSent bool   `json:"email_sent"`
+			Status    string `json:"status"`
+		}
+		err = insta.signedRequest(ctx, urlSendRecoveryEmail, map[string]string{
+			"query":        query,
+			"adid":         insta.adid,
+			"guid":         insta.uuid,
+			"device_id":    insta.dID,
+			"waterfall_id": r.waterfallID,
+		}, &resp)
+	default:
+		return nil, fmt.Errorf("Account %s can not be recovered by email or SMS", query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SubmitCode verifies the code received by SMS
+func (r *PasswordReset) SubmitCode(code string) error {
+	return r.SubmitCodeCtx(context.Background(), code)
+}
+
+// SubmitCodeCtx is like SubmitCode, but the request can be canceled with ctx.
+func (r *PasswordReset) SubmitCodeCtx(ctx context.Context, code string) error {
+	insta := r.insta
+	resp := struct {
+		UserID int64  `json:"user_id"`
+		Token  string `json:"token"`
+		Status string `json:"status"`
+	}{}
+	err := insta.signedRequest(ctx, urlRecoveryCodeVerify, map[string]string{
+		"recover_code": code,
+		"query":        r.Query,
+		"guid":         insta.uuid,
+		"device_id":    insta.dID,
+		"waterfall_id": r.waterfallID,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Token == "" {
+		return errors.New("Recovery code has not been accepted")
+	}
+	r.UserID = resp.UserID
+	r.Token = resp.Token
+	return nil
+}
+
+// SubmitLink extracts the user and token from the reset link received by
+//   email, e.g. https://instagram.com/accounts/password/reset/confirm/?uidb36=...&token=...
+func (r *PasswordReset) SubmitLink(link string) error {
+	u, err := neturl.Parse(link)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	uid, err := strconv.ParseInt(q.Get("uidb36"), 36, 64)
+	if err != nil || q.Get("token") == "" {
+		return fmt.Errorf("Invalid password reset link: %s", link)
+	}
+	r.UserID = uid
+	r.Token = q.Get("token")
+	return nil
+}
+
+// SetPassword sets the new password, and logs in
+func (r *PasswordReset) SetPassword(password string) error {
+	return r.SetPasswordCtx(context.Background(), password)
+}
+
+// SetPasswordCtx is like SetPassword, but the request can be canceled with ctx.
+func (r *PasswordReset) SetPasswordCtx(ctx context.Context, password string) error {
+	insta := r.insta
+	if r.Token == "" {
+		return errors.New("Submit the recovery code or link before setting the password")
+	}
+	timestamp := strconv.Itoa(int(time.Now().Unix()))
+	encrypted, err := utilities.EncryptPassword(password, insta.pubKey, insta.pubKeyID, timestamp)
+	if err != nil {
+		return err
+	}
+
+	resp := accountResp{}
+	err = insta.signedRequest(ctx, urlPasswordReset, map[string]string{
+		"enc_new_password1": encrypted,
+		"enc_new_password2": encrypted,
+		"user_id":           strconv.FormatInt(r.UserID, 10),
+		"token":             r.Token,
+		"guid":              insta.uuid,
+		"device_id":         insta.dID,
+		"waterfall_id":      r.waterfallID,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Account.ID == 0 {
+		return errors.New("Password has been reset, but Instagram did not log in")
+	}
+
+	insta.user = resp.Account.Username
+	insta.pass = ""
+	insta.loggedIn(&resp.Account)
+	return nil
+}
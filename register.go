@@ -0,0 +1,432 @@
+package goinsta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UliSotschok/goinsta/utilities"
+)
+
+// RegisterOptions are the details of the account created by Register. The
+//   username and password are the ones passed to New.
+type RegisterOptions struct {
+	// Email or PhoneNumber is confirmed with a code, one of them is required.
+	//   PhoneNumber includes the country code, e.g. +12025550123
+	Email       string
+	PhoneNumber string
+	FullName    string
+	// Birthday is required, if Instagram asks for the age of the user
+	Birthday time.Time
+	// ConfirmationCode is called after the confirmation code has been sent to
+	//   contact, which is the email address or phone number. It returns the
+	//   code received.
+	ConfirmationCode func(ctx context.Context, contact string) (string, error)
+}
+
+type signupConfig struct {
+	AgeRequired bool   `json:"age_required"`
+	TosVersion  string `json:"tos_version"`
+	Status      string `json:"status"`
+}
+
+type createAccountResp struct {
+	AccountCreated bool                `json:"account_created"`
+	CreatedUser    Account             `json:"created_user"`
+	Errors         map[string][]string `json:"errors"`
+	ErrorType      string              `json:"error_type"`
+	Status         string              `json:"status"`
+}
+
+// registration holds the state of a sign up
+type registration struct {
+	insta       *Instagram
+	opts        *RegisterOptions
+	waterfallID string
+	// signupCode is returned when the email confirmation code is checked
+	signupCode string
+	// smsCode is the confirmation code sent to the phone number
+	smsCode    string
+	tosVersion string
+}
+
+// Register creates a new account in close resemblance to the android apk, and
+//   logs in. If no username has been passed to New, the first username
+//   suggested by Instagram is used.
+//
+// Password will be deleted after registration
+func (insta *Instagram) Register(o *RegisterOptions) error {
+	return insta.RegisterCtx(context.Background(), o)
+}
+
+// RegisterCtx is like Register, but the sign up can be canceled with ctx.
+func (insta *Instagram) RegisterCtx(ctx context.Context, o *RegisterOptions) error {
+	switch {
+	case o.Email == "" && o.PhoneNumber == "":
+		return errors.New("An email address or phone number is required to register")
+	case o.ConfirmationCode == nil:
+		return errors.New("ConfirmationCode is required to register")
+	case insta.pass == "":
+		return errors.New("A password is required to register")
+	}
+
+	err := insta.prelogin(ctx)
+	if err != nil {
+		return err
+	}
+
+	r := &registration{
+		insta:       insta,
+		opts:        o,
+		waterfallID: generateUUID(),
+		tosVersion:  "row",
+	}
+	if o.Email != "" {
+		err = r.confirmEmail(ctx)
+	} else {
+		err = r.confirmPhone(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkAge(ctx); err != nil {
+		return err
+	}
+
+	if insta.user == "" {
+		suggestions, err := insta.usernameSuggestions(ctx, o.FullName, o.Email, r.waterfallID)
+		if err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return errors.New("No username has been set, and Instagram did not suggest any")
+		}
+		insta.user = suggestions[0]
+	} else {
+		available, err := insta.checkUsername(ctx, insta.user)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return fmt.Errorf("Username %s is not available", insta.user)
+		}
+	}
+
+	return r.create(ctx)
+}
+
+// UsernameSuggestions returns available usernames, based on the name and
+//   email address of a new account.
+func (insta *Instagram) UsernameSuggestions(name, email string) ([]string, error) {
+	return insta.usernameSuggestions(context.Background(), name, email, generateUUID())
+}
+
+// CheckUsername reports whether a username is available for a new account
+func (insta *Instagram) CheckUsername(username string) (bool, error) {
+	return insta.checkUsername(context.Background(), username)
+}
+
+func (insta *Instagram) usernameSuggestions(ctx context.Context, name, email, waterfallID string) ([]string, error) {
+	resp := struct {
+		Suggestions struct {
+			Suggestions []struct {
+				Username   string  `json:"username"`
+				Prototype  string  `json:"prototype"`
+				Confidence float64 `json:"confidence"`
+			} `json:"suggestions"`
+		} `json:"suggestions_with_metadata"`
+		Status string `json:"status"`
+	}{}
+	err := insta.signedRequest(ctx, urlUsernameSuggestions, map[string]string{
+		"phone_id":     insta.fID,
+		"guid":         insta.uuid,
+		"name":         name,
+		"device_id":    insta.dID,
+		"email":        email,
+		"waterfall_id": waterfallID,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := resp.Suggestions.Suggestions
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Confidence > suggestions[j].Confidence
+	})
+	usernames := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		usernames[i] = s.Username
+	}
+	return usernames, nil
+}
+
+func (insta *Instagram) checkUsername(ctx context.Context, username string) (bool, error) {
+	resp := struct {
+		Username  string `json:"username"`
+		Available bool   `json:"available"`
+		ErrorType string `json:"error_type"`
+		Status    string `json:"status"`
+	}{}
+	err := insta.signedRequest(ctx, urlCheckUsername, map[string]string{
+		"username": username,
+		"_uuid":    insta.uuid,
+	}, &resp)
+	return resp.Available, err
+}
+
+// signedRequest sends a signed POST request, and decodes the response into
+//   resp. It is used by the sign up and account recovery flows. The requests
+//   are never retried, as they send codes, or create accounts.
+func (insta *Instagram) signedRequest(ctx context.Context, endpoint string, data map[string]string, resp interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: endpoint,
+			IsPost:   true,
+			NoRetry:  true,
+			Query:    generateSignature(b),
+			Context:  ctx,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, resp)
+}
+
+func (r *registration) confirmEmail(ctx context.Context) error {
+	insta := r.insta
+	check := struct {
+		Valid     bool   `json:"valid"`
+		Available bool   `json:"available"`
+		ErrorType string `json:"error_type"`
+		Status    string `json:"status"`
+	}{}
+	err := insta.signedRequest(ctx, urlCheckEmail, map[string]string{
+		"android_device_id": insta.dID,
+		"login_nonce_map":   "{}",
+		"login_nonces":      "[]",
+		"email":             r.opts.Email,
+		"qe_id":             insta.uuid,
+		"waterfall_id":      r.waterfallID,
+	}, &check)
+	if err != nil {
+		return err
+	}
+	if !check.Valid || !check.Available {
+		return fmt.Errorf("Email %s can not be used to register: %s", r.opts.Email, check.ErrorType)
+	}
+
+	sent := struct {
+		EmailSent bool   `json:"email_sent"`
+		Status    string `json:"status"`
+	}{}
+	err = insta.signedRequest(ctx, urlSendVerifyEmail, map[string]string{
+		"phone_id":          insta.fID,
+		"device_id":         insta.dID,
+		"email":             r.opts.Email,
+		"waterfall_id":      r.waterfallID,
+		"auto_confirm_only": "false",
+	}, &sent)
+	if err != nil {
+		return err
+	}
+
+	code, err := r.opts.ConfirmationCode(ctx, r.opts.Email)
+	if err != nil {
+		return err
+	}
+	confirmed := struct {
+		SignupCode string `json:"signup_code"`
+		Status     string `json:"status"`
+	}{}
+	err = insta.signedRequest(ctx, urlCheckConfirmationCode, map[string]string{
+		"code":         code,
+		"device_id":    insta.dID,
+		"email":        r.opts.Email,
+		"waterfall_id": r.waterfallID,
+	}, &confirmed)
+	if err != nil {
+		return err
+	}
+	if confirmed.SignupCode == "" {
+		return errors.New("Confirmation code has not been accepted")
+	}
+	r.signupCode = confirmed.SignupCode
+	return nil
+}
+
+func (r *registration) confirmPhone(ctx context.Context) error {
+	insta := r.insta
+	var check struct {
+		Status string `json:"status"`
+	}
+	err := insta.signedRequest(ctx, urlCheckPhoneNumber, map[string]string{
+		"phone_id":        insta.fID,
+		"login_nonce_map": "{}",
+		"phone_number":    r.opts.PhoneNumber,
+		"guid":            insta.uuid,
+		"device_id":       insta.dID,
+		"prefill_shown":   "False",
+	}, &check)
+	if err != nil {
+		return err
+	}
+
+	err = insta.signedRequest(ctx, urlSendSignupSMSCode, map[string]string{
+		"phone_id":           insta.fID,
+		"phone_number":       r.opts.PhoneNumber,
+		"guid":               insta.uuid,
+		"device_id":          insta.dID,
+		"android_build_type": "release",
+		"waterfall_id":       r.waterfallID,
+	}, &check)
+	if err != nil {
+		return err
+	}
+
+	code, err := r.opts.ConfirmationCode(ctx, r.opts.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	validated := struct {
+		Verified bool   `json:"verified"`
+		Status   string `json:"status"`
+	}{}
+	err = insta.signedRequest(ctx, urlValidateSignupSMSCode, map[string]string{
+		"verification_code": code,
+		"phone_number":      r.opts.PhoneNumber,
+		"guid":              insta.uuid,
+		"device_id":         insta.dID,
+		"waterfall_id":      r.waterfallID,
+	}, &validated)
+	if err != nil {
+		return err
+	}
+	if !validated.Verified {
+		return errors.New("Confirmation code has not been accepted")
+	}
+	r.smsCode = code
+	return nil
+}
+
+// checkAge fetches the consent steps, and checks whether the birthday is
+//   eligible to register, if Instagram asks for it.
+func (r *registration) checkAge(ctx context.Context) error {
+	insta := r.insta
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: urlSignupConfig,
+			Query: map[string]string{
+				"guid":                  insta.uuid,
+				"main_account_selected": "false",
+			},
+			Context: ctx,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	config := signupConfig{}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return err
+	}
+	if config.TosVersion != "" {
+		r.tosVersion = config.TosVersion
+	}
+	if !config.AgeRequired {
+		return nil
+	}
+
+	b := r.opts.Birthday
+	if b.IsZero() {
+		return errors.New("Instagram asks for the birthday to register, but none has been set")
+	}
+	eligible := struct {
+		EligibleToRegister bool   `json:"eligible_to_register"`
+		Status             string `json:"status"`
+	}{}
+	err = insta.signedRequest(ctx, urlCheckAgeEligibility, map[string]string{
+		"day":   strconv.Itoa(b.Day()),
+		"month": strconv.Itoa(int(b.Month())),
+		"year":  strconv.Itoa(b.Year()),
+	}, &eligible)
+	if err != nil {
+		return err
+	}
+	if !eligible.EligibleToRegister {
+		return errors.New("Not eligible to register, because of the age")
+	}
+	return nil
+}
+
+func (r *registration) create(ctx context.Context) error {
+	insta := r.insta
+	timestamp := strconv.Itoa(int(time.Now().Unix()))
+	encrypted, err := utilities.EncryptPassword(insta.pass, insta.pubKey, insta.pubKeyID, timestamp)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"is_secondary_account_creation":          "false",
+		"jazoest":                                jazoest(insta.dID),
+		"tos_version":                            r.tosVersion,
+		"suggestedUsername":                      "",
+		"sn_result":                              "API_ERROR: null",
+		"do_not_auto_login_if_credentials_match": "true",
+		"phone_id":                               insta.fID,
+		"enc_password":                           encrypted,
+		"username":                               insta.user,
+		"first_name":                             r.opts.FullName,
+		"adid":                                   insta.adid,
+		"guid":                                   insta.uuid,
+		"device_id":                              insta.dID,
+		"waterfall_id":                           r.waterfallID,
+		"one_tap_opt_in":                         "true",
+		"has_sms_consent":                        "true",
+	}
+	if b := r.opts.Birthday; !b.IsZero() {
+		data["day"] = strconv.Itoa(b.Day())
+		data["month"] = strconv.Itoa(int(b.Month()))
+		data["year"] = strconv.Itoa(b.Year())
+	}
+
+	endpoint := urlCreateAccount
+	if r.opts.Email != "" {
+		data["email"] = r.opts.Email
+		data["force_sign_up_code"] = r.signupCode
+	} else {
+		endpoint = urlCreateValidatedAccount
+		data["phone_number"] = r.opts.PhoneNumber
+		data["verification_code"] = r.smsCode
+		data["force_sign_up_code"] = ""
+	}
+
+	resp := createAccountResp{}
+	err = insta.signedRequest(ctx, endpoint, data, &resp)
+	if err != nil {
+		return err
+	}
+	if !resp.AccountCreated {
+		var problems []string
+		for field, errs := range resp.Errors {
+			problems = append(problems, field+": "+strings.Join(errs, ", "))
+		}
+		sort.Strings(problems)
+		return fmt.Errorf("Failed to create account: %s", strings.Join(problems, "; "))
+	}
+
+	insta.pass = ""
+	insta.loggedIn(&resp.CreatedUser)
+	return nil
+}
@@ -0,0 +1,154 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ReloginOptions configures the automatic login, which is performed if a
+//   request fails because the session has expired (login_required).
+//
+// The device identity (device ID, UUID, phone ID, ...) of the session is kept,
+//   only the authorization is renewed. The failed request is retried once after
+//   a successful login.
+type ReloginOptions struct {
+	// Password is used to login again with the username of the session. If
+	//   not set, the password passed to New is used, which is kept in memory
+	//   once auto relogin has been enabled.
+	Password string
+	// Credentials is called to get the username and password, if set. It takes
+	//   precedence over Password, and can be used to fetch the credentials
+	//   from e.g. a secret store.
+	Credentials func(ctx context.Context) (username, password string, err error)
+	// OpenApp runs the OpenApp sequence after the login, like the app does.
+	//   Errors during OpenApp are passed to the WarnHandler, as the login
+	//   itself succeeded. Requests waiting for the login are retried once
+	//   OpenApp has finished.
+	OpenApp bool
+	// Save is called with the refreshed session after a successful login, and
+	//   can be used to persist it, e.g. with Instagram.Export.
+	Save func(insta *Instagram) error
+}
+
+// SetAutoRelogin enables the automatic login, if a request fails with
+//   ErrLoginRequired. Pass nil to disable it, which is the default.
+//
+// If no password and no credentials function have been set in o, the password
+//   passed to New is used. Call this before Login in that case, as the password
+//   is cleared after the login otherwise.
+func (insta *Instagram) SetAutoRelogin(o *ReloginOptions) {
+	if o != nil {
+		c := *o
+		if c.Password == "" && c.Credentials == nil {
+			c.Password = insta.pass
+		}
+		o = &c
+	}
+	insta.relogin = o
+}
+
+// reloginCall is an automatic login in progress. done is closed once it has
+//   finished, err is its result.
+type reloginCall struct {
+	done chan struct{}
+	err  error
+}
+
+// reloginKey marks the context of the requests sent by an automatic login
+type reloginKey struct{}
+
+// shouldRelogin reports whether the request failed with err is worth a new
+//   login, after which it can be retried. Requests of the automatic login
+//   itself are never retried, which prevents loops if the login fails.
+func (insta *Instagram) shouldRelogin(o *reqOptions, err error) bool {
+	return insta.relogin != nil &&
+		!o.noRelogin &&
+		o.Endpoint != urlLogin &&
+		o.Endpoint != urlLogout &&
+		o.Context.Value(reloginKey{}) == nil &&
+		errors.Is(err, ErrLoginRequired)
+}
+
+// reloginOnce logs in again, unless another login has succeeded since the
+//   failed request has been sent (gen is the session generation at that time).
+//   Requests failing with ErrLoginRequired while a login is in progress wait
+//   for it, and share its result.
+func (insta *Instagram) reloginOnce(ctx context.Context, gen uint32) error {
+	insta.reloginMu.Lock()
+	if atomic.LoadUint32(&insta.sessionGen) != gen {
+		insta.reloginMu.Unlock()
+		return nil
+	}
+	if c := insta.reloginCall; c != nil {
+		insta.reloginMu.Unlock()
+		select {
+		case <-c.done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c := &reloginCall{done: make(chan struct{})}
+	insta.reloginCall = c
+	insta.reloginMu.Unlock()
+
+	// The requests of the login and of OpenApp are marked, so they do not
+	//   start another login if they fail with ErrLoginRequired.
+	c.err = insta.reloginSteps(context.WithValue(ctx, reloginKey{}, true))
+
+	insta.reloginMu.Lock()
+	insta.reloginCall = nil
+	insta.reloginMu.Unlock()
+	close(c.done)
+	return c.err
+}
+
+// reloginSteps logs in again, and runs the steps after the login set in the
+//   relogin options.
+func (insta *Instagram) reloginSteps(ctx context.Context) error {
+	if err := insta.loginAgain(ctx); err != nil {
+		return err
+	}
+
+	o := insta.relogin
+	if o.OpenApp {
+		if err := insta.OpenAppCtx(ctx); err != nil {
+			insta.WarnHandler("Non fatal error while opening app after login:", err)
+		}
+	}
+	if o.Save != nil {
+		if err := o.Save(insta); err != nil {
+			insta.WarnHandler("Failed to save session after login:", err)
+		}
+	}
+	return nil
+}
+
+// loginAgain logs in with the credentials of the relogin options
+func (insta *Instagram) loginAgain(ctx context.Context) error {
+	o := insta.relogin
+	user, pass := insta.user, o.Password
+	if o.Credentials != nil {
+		var err error
+		user, pass, err = o.Credentials(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if pass == "" {
+		return errors.New("No password available to login again")
+	}
+
+	insta.InfoHandler(fmt.Sprintf("Session of %s has expired, logging in again", user))
+	insta.user = user
+	insta.pass = pass
+	insta.headerOptions.Delete("Authorization")
+
+	if err := insta.LoginCtx(ctx); err != nil {
+		insta.pass = ""
+		return err
+	}
+	return nil
+}
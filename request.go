@@ -3,6 +3,7 @@ package goinsta
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -11,6 +12,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -55,6 +57,25 @@ type reqOptions struct {
 
 	// Timestamp
 	Timestamp string
+
+	// NoRetry disables retries for this request, even if a retry policy has
+	//   been set.
+	NoRetry bool
+
+	// Idempotent allows a POST request to be retried, as sending it twice has
+	//   no side effects, e.g. syncs and feeds. Other POST requests are never
+	//   retried, to avoid duplicate actions.
+	Idempotent bool
+
+	// Action is the rate limit class of the request. If not set, GET requests
+	//   will be counted as ActionRead.
+	Action Action
+
+	// Context of the request, context.Background() if not set
+	Context context.Context
+
+	// noRelogin is set when the request is retried after an automatic login
+	noRelogin bool
 }
 
 func (insta *Instagram) sendSimpleRequest(uri string, a ...interface{}) (body []byte, err error) {
@@ -70,7 +91,52 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 	if insta == nil {
 		return nil, nil, fmt.Errorf("Error while calling %s: %s", o.Endpoint, ErrInstaNotDefined)
 	}
-	insta.checkXmidExpiry()
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+
+	// keep the raw data, as the buffer is drained by sending it
+	var data []byte
+	if o.DataBytes != nil {
+		data = o.DataBytes.Bytes()
+	}
+	gen := atomic.LoadUint32(&insta.sessionGen)
+
+	body, h, err = insta.sendRequestOnce(o)
+	if err == nil || !insta.shouldRelogin(o, err) {
+		return body, h, err
+	}
+	if lerr := insta.reloginOnce(o.Context, gen); lerr != nil {
+		return body, h, fmt.Errorf("%w (login again failed: %s)", err, lerr)
+	}
+
+	o.noRelogin = true
+	if data != nil {
+		o.DataBytes = bytes.NewBuffer(data)
+	}
+	return insta.sendRequestOnce(o)
+}
+
+// sendRequestOnce builds and sends the request, without logging in again if
+//   the session has expired.
+func (insta *Instagram) sendRequestOnce(o *reqOptions) (body []byte, h http.Header, err error) {
+	insta.checkXmidExpiry(o.Context)
+
+	if err := insta.checkHealth(o); err != nil {
+		return nil, nil, err
+	}
+
+	if insta.limiter != nil {
+		action := o.Action
+		if action == "" && !o.IsPost {
+			action = ActionRead
+		}
+		if action != "" {
+			if err := insta.limiter.wait(o.Context, action); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 
 	method := "GET"
 	if o.IsPost {
@@ -145,7 +211,7 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 	}
 
 	var req *http.Request
-	req, err = http.NewRequest(method, u.String(), bf)
+	req, err = http.NewRequestWithContext(o.Context, method, u.String(), bf)
 	if err != nil {
 		return
 	}
@@ -188,7 +254,7 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		"X-Ig-Family-Device-Id":       insta.fID,
 		"X-Ig-Android-Id":             insta.dID,
 		"X-Ig-Timezone-Offset":        timeOffset,
-		"X-Ig-Capabilities":           igCapabilities,
+		"X-Ig-Capabilities":           insta.appVersion.Capabilities,
 		"X-Ig-Connection-Type":        connType,
 		"X-Pigeon-Session-Id":         insta.psID,
 		"X-Pigeon-Rawclienttime":      fmt.Sprintf("%s.%d", o.Timestamp, random(100, 900)),
@@ -196,7 +262,7 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		"X-Ig-Bandwidth-TotalBytes-B": strconv.Itoa(random(1000000, 5000000)),
 		"X-Ig-Bandwidth-Totaltime-Ms": strconv.Itoa(random(200, 800)),
 		"X-Ig-App-Startup-Country":    "unkown",
-		"X-Bloks-Version-Id":          bloksVerID,
+		"X-Bloks-Version-Id":          insta.appVersion.BloksVersionID,
 		"X-Bloks-Is-Layout-Rtl":       "false",
 		"X-Bloks-Is-Panorama-Enabled": "true",
 		"X-Fb-Http-Engine":            "Liger",
@@ -216,20 +282,100 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 	setHeaders(o.ExtraHeaders)
 	insta.headerOptions.Range(setHeadersAsync)
 
+	// the session is saved, if the response changes the cookies
+	var cookies string
+	if insta.store != nil {
+		cookies = insta.cookieState()
+	}
+
+	resp := insta.send(o, req)
+	insta.updateHealth(o, resp.Err)
+	if resp.Err != nil {
+		insta.trackChallenge(resp.Err)
+		// the body of error responses is returned as well, as it can contain
+		//   details, e.g. the two factor info on login
+		return resp.Body, resp.Header.Clone(), resp.Err
+	}
+	if insta.extractHeaders(resp.Header) || (insta.store != nil && insta.cookieState() != cookies) {
+		insta.autoSave()
+	}
+	return resp.Body, resp.Header.Clone(), nil
+}
+
+// send sends the prepared request through the middleware chain, and retries it
+//   according to the retry policy, if one has been set.
+func (insta *Instagram) send(o *reqOptions, req *http.Request) *ResponseInfo {
+	attempts := insta.retryPolicy.attempts(o)
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return &ResponseInfo{Err: err}
+				}
+				r.Body = body
+			}
+		}
+
+		info := &RequestInfo{
+			Endpoint: o.Endpoint,
+			Method:   r.Method,
+			Query:    o.Query,
+			Header:   r.Header,
+			Request:  r,
+			Account:  insta.Account,
+			Attempt:  attempt,
+		}
+		resp := &ResponseInfo{Request: info}
+
+		start := time.Now()
+		sent := false
+		resp.Err = insta.beforeRequest(info)
+		if resp.Err == nil {
+			sent = true
+			var status string
+			resp.StatusCode, status, resp.Header, resp.Body, resp.Err = insta.do(r)
+			if resp.Err == nil {
+				resp.Err = isError(resp.StatusCode, resp.Body, status, o.Endpoint, resp.Header)
+			}
+		}
+		resp.Duration = time.Since(start)
+		insta.afterResponse(resp)
+
+		if resp.Err == nil || attempt >= attempts || !shouldRetry(resp, sent) {
+			return resp
+		}
+
+		after, _ := retryAfter(resp.Header)
+		delay, ok := insta.retryPolicy.delay(attempt, after)
+		if !ok {
+			return resp
+		}
+		insta.WarnHandler(
+			fmt.Sprintf("Request to %s failed (attempt %d/%d), retrying in %s: %s",
+				o.Endpoint, attempt, attempts, delay.Round(time.Millisecond), resp.Err),
+		)
+		if err := sleep(o.Context, delay); err != nil {
+			return &ResponseInfo{Request: info, Err: err}
+		}
+	}
+}
+
+// do sends the request, and returns the status code, status, header and the
+//   decompressed body of the response.
+func (insta *Instagram) do(req *http.Request) (int, string, http.Header, []byte, error) {
 	resp, err := insta.c.Do(req)
 	if err != nil {
-		return nil, nil, err
+		return 0, "", nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err = ioutil.ReadAll(resp.Body)
-	if err == nil {
-		err = isError(resp.StatusCode, body, resp.Status, o.Endpoint)
-	}
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, nil, err
+		return 0, "", nil, nil, err
 	}
-	insta.extractHeaders(resp.Header)
 
 	// Decode gzip encoded responses
 	encoding := resp.Header.Get("Content-Encoding")
@@ -237,27 +383,29 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		buf := bytes.NewBuffer(body)
 		zr, err := gzip.NewReader(buf)
 		if err != nil {
-			return nil, nil, err
+			return 0, "", nil, nil, err
 		}
 		body, err = ioutil.ReadAll(zr)
 		if err != nil {
-			return nil, nil, err
+			return 0, "", nil, nil, err
 		}
 		if err := zr.Close(); err != nil {
-			return nil, nil, err
+			return 0, "", nil, nil, err
 		}
 	}
-	return body, resp.Header.Clone(), err
+	return resp.StatusCode, resp.Status, resp.Header, body, nil
 }
 
-func (insta *Instagram) checkXmidExpiry() {
+func (insta *Instagram) checkXmidExpiry(ctx context.Context) {
 	if insta.xmidExpiry != -1 && time.Now().Unix() > insta.xmidExpiry-10 {
 		insta.xmidExpiry = -1
-		insta.zrToken()
+		insta.zrToken(ctx)
 	}
 }
 
-func (insta *Instagram) extractHeaders(h http.Header) {
+// extractHeaders stores the headers set by Instagram, and reports whether the
+//   authorization or X-Mid header has changed.
+func (insta *Instagram) extractHeaders(h http.Header) (rotated bool) {
 	extract := func(in string, out string) {
 		x := h[in]
 		if len(x) > 0 && x[0] != "" {
@@ -273,6 +421,10 @@ func (insta *Instagram) extractHeaders(h http.Header) {
 
 				}
 			}
+			old, _ := insta.headerOptions.Load(out)
+			if (out == "Authorization" || out == "X-Mid") && old != x[0] {
+				rotated = true
+			}
 			insta.headerOptions.Store(out, x[0])
 		}
 	}
@@ -285,53 +437,53 @@ func (insta *Instagram) extractHeaders(h http.Header) {
 	extract("Ig-Set-Ig-U-Shbts", "Ig-U-Shbts")
 	extract("Ig-Set-Ig-U-Rur", "Ig-U-Rur")
 	extract("Ig-Set-Ig-U-Ds-User-Id", "Ig-U-Ds-User-Id")
+	return rotated
 }
 
-func isError(code int, body []byte, status, endpoint string) (err error) {
+func isError(code int, body []byte, status, endpoint string, h http.Header) (err error) {
 	switch code {
 	case 200:
 	case 202:
-	case 400:
-		ierr := Error400{Endpoint: endpoint}
-		err = json.Unmarshal(body, &ierr)
-		if err == nil {
-			switch ierr.Message {
-			case "Sorry, this media has been deleted":
-				return ErrMediaDeleted
-			case "challenge_required":
-				return ierr.ChallengeError
-			default:
-				return ierr
-			}
+	case 400, 403:
+		ierr := Error400{Code: code, Endpoint: endpoint}
+		if err := json.Unmarshal(body, &ierr); err != nil {
+			ierr.ChallengeError.Message = string(body)
+			return ierr
 		}
-
-	case 403:
-		ierr := Error400{
-			Code:     403,
-			Endpoint: endpoint,
+		switch ierr.ChallengeError.Message {
+		case "challenge_required":
+			return ierr.ChallengeError
+		case ErrMediaDeleted.Error():
+			// kept as sentinel, to be comparable with ==
+			return ErrMediaDeleted
 		}
-		ierr.Message = string(body)
 		return ierr
 	case 429:
-		return ErrTooManyRequests
+		ierr := RateLimitError{Endpoint: endpoint}
+		ierr.Wait, _ = retryAfter(h)
+		return ierr
 	case 500:
 		ierr := ErrorN{
 			Endpoint:  endpoint,
 			Status:    "500",
 			Message:   string(body),
 			ErrorType: status,
+			Code:      code,
 		}
 		return ierr
 	case 503:
-		return Error503{
+		ierr := Error503{
 			Message: "Instagram API error. Try it later.",
 		}
+		ierr.Wait, _ = retryAfter(h)
+		return ierr
 	default:
 		ierr := ErrorN{
 			Endpoint:  endpoint,
 			Status:    strconv.Itoa(code),
 			Message:   string(body),
 			ErrorType: status,
+			Code:      code,
 		}
 		err = json.Unmarshal(body, &ierr)
 		if ierr.Message == "Transcode not finished yet." {
@@ -375,6 +527,19 @@ func (insta *Instagram) prepareDataQuery(other ...map[string]interface{}) map[st
 	return data
 }
 
+// sleep pauses for the duration d, or until the context is done, in which
+//   case the context error is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func random(min, max int) int {
 	rand.Seed(time.Now().UnixNano())
 	return rand.Intn(max-min) + min
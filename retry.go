@@ -0,0 +1,126 @@
+package goinsta
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are retried. Requests are retried
+//   if the returned error is retryable (see IsRetryable), e.g. on HTTP 429
+//   (too many requests) and HTTP 5xx, and on transient network errors, such
+//   as timeouts or connection resets.
+//
+// Between two attempts goinsta waits with an exponential backoff:
+//   BaseDelay * 2^(attempt-1), capped at MaxDelay. A random jitter of +/- Jitter
+//   (a fraction, e.g. 0.2 for 20%) is applied to every delay. If Instagram sends
+//   a Retry-After header, it will be used instead of the calculated delay. If
+//   it exceeds MaxDelay, the request is not retried.
+//
+// POST requests, such as likes, comments, follows, and sending messages, are
+//   never retried, to avoid duplicate actions. Only POST requests without
+//   side effects, like syncs and feeds, are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+	// BaseDelay is the delay after the first failed attempt
+	BaseDelay time.Duration
+	// MaxDelay is the upper limit for a single delay
+	MaxDelay time.Duration
+	// Jitter is the fraction of random jitter to add or subtract from a delay
+	Jitter float64
+}
+
+// DefaultRetryPolicy is a sensible retry policy, which can be used with
+//   Instagram.SetRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    2 * time.Minute,
+	Jitter:      0.2,
+}
+
+// SetRetryPolicy sets the retry policy used for all requests. Pass nil to
+//   disable retries, which is the default.
+func (insta *Instagram) SetRetryPolicy(p *RetryPolicy) {
+	insta.retryPolicy = p
+}
+
+// attempts returns the max number of attempts for a request
+func (p *RetryPolicy) attempts(o *reqOptions) int {
+	if p == nil || o.NoRetry || (o.IsPost && !o.Idempotent) || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// delay calculates how long to wait after the attempt n has failed, honoring
+//   the Retry-After duration after, if set. If it exceeds MaxDelay, false is
+//   returned, as a retry within MaxDelay would be too early.
+func (p *RetryPolicy) delay(n int, after time.Duration) (time.Duration, bool) {
+	if after > 0 {
+		return after, p.MaxDelay == 0 || after <= p.MaxDelay
+	}
+
+	d := float64(p.BaseDelay) * math.Pow(2, float64(n-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d), true
+}
+
+// shouldRetry reports whether a request can be retried, based on its result
+func shouldRetry(r *ResponseInfo, sent bool) bool {
+	if !sent {
+		// aborted by a middleware
+		return false
+	}
+	if r.StatusCode == 0 {
+		return isTransientErr(r.Err)
+	}
+	return IsRetryable(r.Err)
+}
+
+// retryAfter parses the Retry-After header, which can either be a number of
+//   seconds, or a http date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func isTransientErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"time"
@@ -89,23 +90,55 @@ func (insta *Instagram) Search(query string) (*SearchResult, error) {
 	return insta.Searchbar.Search(query)
 }
 
+// SearchCtx is a wrapper for insta.Searchbar.SearchCtx()
+func (insta *Instagram) SearchCtx(ctx context.Context, query string) (*SearchResult, error) {
+	return insta.Searchbar.SearchCtx(ctx, query)
+}
+
 func (sb *Search) Search(query string) (*SearchResult, error) {
-	return sb.search(query, sb.topsearch)
+	return sb.SearchCtx(context.Background(), query)
+}
+
+// SearchCtx is like Search, but the search can be canceled with ctx.
+func (sb *Search) SearchCtx(ctx context.Context, query string) (*SearchResult, error) {
+	return sb.search(ctx, query, sb.topsearch)
 }
 
 func (sb *Search) SearchUser(query string) (*SearchResult, error) {
-	return sb.search(query, sb.user)
+	return sb.SearchUserCtx(context.Background(), query)
+}
+
+// SearchUserCtx is like SearchUser, but the search can be canceled with ctx.
+func (sb *Search) SearchUserCtx(ctx context.Context, query string) (*SearchResult, error) {
+	return sb.search(ctx, query, sb.user)
 }
 
 func (sb *Search) SearchHashtag(query string) (*SearchResult, error) {
-	return sb.search(query, sb.tags)
+	return sb.SearchHashtagCtx(context.Background(), query)
+}
+
+// SearchHashtagCtx is like SearchHashtag, but the search can be canceled
+//   with ctx.
+func (sb *Search) SearchHashtagCtx(ctx context.Context, query string) (*SearchResult, error) {
+	return sb.search(ctx, query, sb.tags)
 }
 
 func (sb *Search) SearchLocation(query string) (*SearchResult, error) {
-	return sb.search(query, sb.places)
+	return sb.SearchLocationCtx(context.Background(), query)
+}
+
+// SearchLocationCtx is like SearchLocation, but the search can be canceled
+//   with ctx.
+func (sb *Search) SearchLocationCtx(ctx context.Context, query string) (*SearchResult, error) {
+	return sb.search(ctx, query, sb.places)
 }
 
 func (sr *SearchResult) Next() bool {
+	return sr.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (sr *SearchResult) NextCtx(ctx context.Context) bool {
 	if !sr.HasMore || sr.RankToken == "" || sr.PageToken == "" {
 		sr.err = errors.New("No more results available, or rank or page token have not been set")
 		return false
@@ -131,6 +164,7 @@ func (sr *SearchResult) Next() bool {
 		&reqOptions{
 			Endpoint: urlSearchTop,
 			Query:    query,
+			Context:  ctx,
 		},
 	)
 	if err != nil {
@@ -152,12 +186,17 @@ func (sr *SearchResult) Next() bool {
 }
 
 func (sb *Search) History() (*[]SearchHistory, error) {
-	sb.insta.Discover.Next()
-	h, err := sb.history()
+	return sb.HistoryCtx(context.Background())
+}
+
+// HistoryCtx is like History, but the requests can be canceled with ctx.
+func (sb *Search) HistoryCtx(ctx context.Context) (*[]SearchHistory, error) {
+	sb.insta.Discover.NextCtx(ctx)
+	h, err := sb.history(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if err := sb.NullState(); err != nil {
+	if err := sb.NullStateCtx(ctx); err != nil {
 		sb.insta.WarnHandler("Non fatal error while setting search null state", err)
 	}
 	return h, nil
@@ -272,18 +311,18 @@ func (insta *Instagram) sendSearchRegisterRequest(query map[string]string) error
 	return err
 }
 
-func (sb *Search) search(query string, fn func(string) (*SearchResult, error)) (*SearchResult, error) {
+func (sb *Search) search(ctx context.Context, query string, fn func(context.Context, string) (*SearchResult, error)) (*SearchResult, error) {
 	insta := sb.insta
 
 	if insta.Discover.NumResults == 0 {
-		sb.insta.Discover.Next()
+		sb.insta.Discover.NextCtx(ctx)
 	}
-	h, err := sb.history()
+	h, err := sb.history(ctx)
 	if err != nil {
 		sb.insta.WarnHandler("Non fatal error while fetcihng recent search results",
 			err)
 	}
-	if err := sb.NullState(); err != nil {
+	if err := sb.NullStateCtx(ctx); err != nil {
 		sb.insta.WarnHandler("Non fatal error while setting search null state", err)
 	}
 
@@ -291,18 +330,20 @@ func (sb *Search) search(query string, fn func(string) (*SearchResult, error)) (
 	var q string
 	for _, char := range query {
 		q += string(char)
-		result, err = fn(q)
+		result, err = fn(ctx, q)
 		if err != nil {
 			return nil, err
 		}
 		s := random(150, 500)
-		time.Sleep(time.Duration(s) * time.Millisecond)
+		if err := sleep(ctx, time.Duration(s)*time.Millisecond); err != nil {
+			return nil, err
+		}
 	}
 	result.History = *h
 	return result, nil
 }
 
-func (search *Search) topsearch(query string) (*SearchResult, error) {
+func (search *Search) topsearch(ctx context.Context, query string) (*SearchResult, error) {
 	insta := search.insta
 	res := &SearchResult{
 		insta:         insta,
@@ -314,6 +355,7 @@ func (search *Search) topsearch(query string) (*SearchResult, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlSearchTop,
+			Context:  ctx,
 			Query: map[string]string{
 				"search_surface":  res.SearchSurface,
 				"timezone_offset": timeOffset,
@@ -365,7 +407,7 @@ func (sr *SearchResult) setValues() {
 	}
 }
 
-func (search *Search) user(user string) (*SearchResult, error) {
+func (search *Search) user(ctx context.Context, user string) (*SearchResult, error) {
 	insta := search.insta
 	res := &SearchResult{
 		insta:         insta,
@@ -376,6 +418,7 @@ func (search *Search) user(user string) (*SearchResult, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlSearchUser,
+			Context:  ctx,
 			Query: map[string]string{
 				"search_surface":  res.SearchSurface,
 				"timezone_offset": timeOffset,
@@ -393,7 +436,7 @@ func (search *Search) user(user string) (*SearchResult, error) {
 	return res, err
 }
 
-func (search *Search) tags(tag string) (*SearchResult, error) {
+func (search *Search) tags(ctx context.Context, tag string) (*SearchResult, error) {
 	insta := search.insta
 	res := &SearchResult{
 		insta:         insta,
@@ -404,6 +447,7 @@ func (search *Search) tags(tag string) (*SearchResult, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlSearchTag,
+			Context:  ctx,
 			Query: map[string]string{
 				"search_surface":  res.SearchSurface,
 				"timezone_offset": timeOffset,
@@ -424,7 +468,7 @@ func (search *Search) tags(tag string) (*SearchResult, error) {
 	return res, nil
 }
 
-func (search *Search) places(location string) (*SearchResult, error) {
+func (search *Search) places(ctx context.Context, location string) (*SearchResult, error) {
 	insta := search.insta
 	res := &SearchResult{
 		insta:         insta,
@@ -436,6 +480,7 @@ func (search *Search) places(location string) (*SearchResult, error) {
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: urlSearchLocation,
+			Context:  ctx,
 			Query: map[string]string{
 				"search_surface":  res.SearchSurface,
 				"timezone_offset": timeOffset,
@@ -459,15 +504,24 @@ func (search *Search) places(location string) (*SearchResult, error) {
 }
 
 func (search *Search) NullState() error {
+	return search.NullStateCtx(context.Background())
+}
+
+// NullStateCtx is like NullState, but the request can be canceled with ctx.
+func (search *Search) NullStateCtx(ctx context.Context) error {
 	_, _, err := search.insta.sendRequest(&reqOptions{
 		Endpoint: urlSearchNullState,
 		Query:    map[string]string{"type": "blended"},
+		Context:  ctx,
 	})
 	return err
 }
 
-func (search *Search) history() (*[]SearchHistory, error) {
-	body, err := search.insta.sendSimpleRequest(urlSearchRecent)
+func (search *Search) history(ctx context.Context) (*[]SearchHistory, error) {
+	body, _, err := search.insta.sendRequest(&reqOptions{
+		Endpoint: urlSearchRecent,
+		Context:  ctx,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,278 @@
+package goinsta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrSessionNotFound is returned by a SessionStore if no session has been
+//   stored for a key.
+var ErrSessionNotFound = errors.New("Session not found")
+
+// SessionStore persists sessions, identified by a key, usually the username.
+//   Implement it to store sessions in e.g. a database.
+//
+// Implementations must be safe for concurrent use, as sessions are saved
+//   automatically after requests.
+type SessionStore interface {
+	// Load returns the session stored for key, or ErrSessionNotFound
+	Load(key string) (*ConfigFile, error)
+	// Save stores the session for key, replacing the previous one
+	Save(key string, config *ConfigFile) error
+	// Delete removes the session stored for key. It is not an error if there
+	//   is no session.
+	Delete(key string) error
+}
+
+// SessionLister is implemented by session stores, which can list the keys of
+//   all stored sessions, e.g. FileStore and MemoryStore.
+type SessionLister interface {
+	Keys() ([]string, error)
+}
+
+// SetSessionStore sets the store used to persist the session. Pass nil to
+//   disable it.
+//
+// Once set, the session is saved automatically after every login, and every
+//   time Instagram rotates the authorization, X-Mid header or cookies. Logout
+//   deletes the session from the store. If key is empty, the username is used.
+func (insta *Instagram) SetSessionStore(store SessionStore, key string) {
+	insta.store = store
+	insta.storeKey = key
+}
+
+// SaveSession saves the session to the session store
+func (insta *Instagram) SaveSession() error {
+	if insta.store == nil {
+		return errors.New("No session store has been set")
+	}
+	config, err := insta.ExportConfig()
+	if err != nil {
+		return err
+	}
+	return insta.store.Save(insta.sessionKey(), &config)
+}
+
+// ImportFromStore imports the session stored for key, and sets the store as
+//   session store of the returned instance.
+//
+// This function does not set proxy automatically. Use SetProxy after this call.
+func ImportFromStore(store SessionStore, key string, args ...interface{}) (*Instagram, error) {
+	config, err := store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	insta, err := ImportConfig(*config, args...)
+	if err != nil {
+		return nil, err
+	}
+	insta.SetSessionStore(store, key)
+	return insta, nil
+}
+
+func (insta *Instagram) sessionKey() string {
+	if insta.storeKey != "" {
+		return insta.storeKey
+	}
+	return insta.user
+}
+
+// autoSave saves the session, if a store has been set and the user is logged
+//   in. Errors are passed to the WarnHandler.
+func (insta *Instagram) autoSave() {
+	if insta.store == nil || insta.Account == nil {
+		return
+	}
+	if err := insta.SaveSession(); err != nil {
+		insta.WarnHandler("Failed to save session:", err)
+	}
+}
+
+// cookieState returns the names and values of the cookies in the jar, to
+//   detect whether a response has changed them. Instagram sets cookies on
+//   almost every response, mostly with unchanged values.
+func (insta *Instagram) cookieState() string {
+	u, err := neturl.Parse(instaAPIUrl)
+	if err != nil || insta.c.Jar == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range insta.c.Jar.Cookies(u) {
+		b.WriteString(c.Name + "=" + c.Value + ";")
+	}
+	return b.String()
+}
+
+// FileStore stores every session as JSON file in a directory. The files are
+//   only readable by the owner, as they contain the session tokens.
+type FileStore struct {
+	Dir string
+	// KeyProvider encrypts the sessions, if set. Plain text sessions can
+	//   still be loaded.
+	KeyProvider KeyProvider
+}
+
+// NewFileStore creates a file store, and the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("Invalid session key %q", key)
+	}
+	return filepath.Join(s.Dir, key+".json"), nil
+}
+
+// Load reads the session of key from its file
+func (s *FileStore) Load(key string) (*ConfigFile, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return DecryptConfig(b, s.KeyProvider)
+}
+
+// Save writes the session of key to its file. The file is replaced atomically,
+//   so that a crash never leaves a partially written session behind.
+func (s *FileStore) Save(key string, config *ConfigFile) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	if s.KeyProvider != nil {
+		b, err = EncryptConfig(*config, s.KeyProvider)
+	} else {
+		b, err = json.Marshal(config)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// writeFileAtomic writes b to a temporary file, which then replaces the file
+//   at path, so that a crash never leaves a partially written file behind.
+//   The file is only readable by the owner.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Delete removes the session file of key
+func (s *FileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Keys returns the keys of all sessions stored in the directory
+func (s *FileStore) Keys() ([]string, error) {
+	files, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(name, ".json"))
+	}
+	return keys, nil
+}
+
+// MemoryStore keeps sessions in memory. It is mostly useful for tests, or as a
+//   cache in front of another store.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string][]byte{}}
+}
+
+// Load returns a copy of the session stored for key
+func (s *MemoryStore) Load(key string) (*ConfigFile, error) {
+	s.mu.Lock()
+	b, ok := s.sessions[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	config := &ConfigFile{}
+	if err := json.Unmarshal(b, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Save stores a copy of the session for key
+func (s *MemoryStore) Save(key string, config *ConfigFile) error {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[key] = b
+	return nil
+}
+
+// Delete removes the session stored for key
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, key)
+	return nil
+}
+
+// Keys returns the keys of all stored sessions
+func (s *MemoryStore) Keys() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.sessions))
+	for k := range s.sessions {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
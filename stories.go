@@ -250,9 +250,10 @@ func (media *Reel) Sync() error {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlReelMedia,
-			IsPost:   true,
-			Query:    generateSignature(data),
+			Endpoint:   urlReelMedia,
+			IsPost:     true,
+			Idempotent: true,
+			Query:      generateSignature(data),
 		},
 	)
 	if err == nil {
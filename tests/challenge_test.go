@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+// codeResolver solves challenges with a code read from the fake server
+type codeResolver struct {
+	code     func() string
+	choice   string
+	password string
+}
+
+func (r *codeResolver) SelectMethod(ctx context.Context, c *goinsta.Challenge) (string, error) {
+	return r.choice, nil
+}
+
+func (r *codeResolver) SecurityCode(ctx context.Context, c *goinsta.Challenge) (string, error) {
+	code := r.code()
+	if code == "" {
+		return "", errors.New("no code received yet")
+	}
+	return code, nil
+}
+
+func (r *codeResolver) PhoneNumber(ctx context.Context, c *goinsta.Challenge) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (r *codeResolver) NewPassword(ctx context.Context, c *goinsta.Challenge) (string, error) {
+	if r.password == "" {
+		return "", errors.New("not supported")
+	}
+	return r.password, nil
+}
+
+func TestChallenge(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{
+		Username:   "alice",
+		Password:   "secret",
+		Checkpoint: true,
+	})
+
+	// The code is not available yet, the challenge is left pending
+	insta := srv.NewInstagram("alice", "secret")
+	insta.SetChallengeResolver(&codeResolver{
+		choice: "1",
+		code:   func() string { return "" },
+	})
+	err := insta.Login()
+	if err == nil {
+		t.Fatal("Expected login to fail without security code")
+	}
+	if !insta.Challenge.Pending() || insta.Challenge.StepName != "verify_email" {
+		t.Fatalf("Unexpected challenge state: %s", insta.Challenge.StepName)
+	}
+	if insta.Health().State != goinsta.HealthCheckpoint {
+		t.Fatalf("Expected checkpoint, got %s", insta.Health())
+	}
+
+	// The challenge can be resumed after export and import
+	buf := new(bytes.Buffer)
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported.SetHTTPTransport(srv.Transport())
+	imported.SetInfoHandler(func(...interface{}) {})
+	imported.SetWarnHandler(func(...interface{}) {})
+	if !imported.Challenge.Pending() {
+		t.Fatal("Challenge has not been imported")
+	}
+
+	err = imported.Challenge.Resolve(context.Background(), &codeResolver{
+		code: func() string { return srv.ChallengeCode(alice.ID) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Account == nil || imported.Account.ID != alice.ID {
+		t.Fatalf("Expected to be logged in as %d, got %+v", alice.ID, imported.Account)
+	}
+	if imported.Challenge.Pending() || !imported.Health().OK() {
+		t.Fatalf("Expected challenge to be solved, health is %s", imported.Health())
+	}
+	if _, err := imported.Profiles.ByName("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new password can be set after import, although the public key used to
+	//   encrypt it is not exported
+	bob := srv.AddUser(goinstatest.User{
+		Username:       "bob",
+		Password:       "hunter2",
+		Checkpoint:     true,
+		ChangePassword: true,
+	})
+	insta = srv.NewInstagram("bob", "hunter2")
+	insta.SetChallengeResolver(&codeResolver{
+		choice: "1",
+		code:   func() string { return "" },
+	})
+	if err := insta.Login(); err == nil {
+		t.Fatal("Expected login to fail without security code")
+	}
+	buf.Reset()
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err = goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported.SetHTTPTransport(srv.Transport())
+	imported.SetInfoHandler(func(...interface{}) {})
+	imported.SetWarnHandler(func(...interface{}) {})
+	err = imported.Challenge.Resolve(context.Background(), &codeResolver{
+		code:     func() string { return srv.ChallengeCode(bob.ID) },
+		password: "correct horse",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Account == nil || imported.Account.ID != bob.ID {
+		t.Fatalf("Expected to be logged in as %d, got %+v", bob.ID, imported.Account)
+	}
+	if err := srv.NewInstagram("bob", "correct horse").Login(); err != nil {
+		t.Fatal(err)
+	}
+}
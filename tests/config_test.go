@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+func TestConfigMigration(t *testing.T) {
+	// A config exported by an older version, without version, device,
+	//   family ID and phone ID
+	legacy := `{
+		"id": 1,
+		"username": "someone",
+		"device_id": "android-0123456789abcdef",
+		"uuid": "8493f2d1-1233-4312-8123-512387654321",
+		"xmid_expiry": -1,
+		"header_options": {"authorization": "Bearer IGT:2:token"},
+		"cookies": [{"Name": "csrftoken", "Value": "abc"}]
+	}`
+	legacyConfig := goinsta.ConfigFile{}
+	if err := json.Unmarshal([]byte(legacy), &legacyConfig); err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := legacyConfig.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 5 {
+		t.Fatalf("Expected warnings about the device, app version, family, phone and ad ID, got %q", warnings)
+	}
+	insta, err := goinsta.ImportReader(strings.NewReader(legacy), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account == nil || insta.Account.ID != 1 {
+		t.Fatalf("Expected account 1, got %+v", insta.Account)
+	}
+
+	config, err := insta.ExportConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != goinsta.ConfigVersion {
+		t.Fatalf("Expected version %d, got %d", goinsta.ConfigVersion, config.Version)
+	}
+	if config.HeaderOptions["Authorization"] != "Bearer IGT:2:token" {
+		t.Fatalf("Authorization has not been migrated: %v", config.HeaderOptions)
+	}
+	if config.Device.Model == "" || config.FamilyID == "" || config.PhoneID == "" || config.AdID == "" {
+		t.Fatalf("Device identity has not been completed: %+v", config)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrated configs are imported unchanged
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goinsta.ImportReader(bytes.NewReader(b), true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrating does not modify the cookies of the caller
+	cookie := &http.Cookie{Name: "csrftoken", Value: "abc"}
+	cookies := []*http.Cookie{nil, cookie}
+	v0 := config
+	v0.Version = 0
+	v0.Cookies = cookies
+	if _, err := goinsta.ImportConfig(v0, true); err != nil {
+		t.Fatal(err)
+	}
+	if cookies[0] != nil || cookies[1] != cookie {
+		t.Fatalf("Cookies of the imported config have been modified: %v", cookies)
+	}
+
+	// Invalid configs are rejected with all problems
+	invalid := config
+	invalid.UUID = ""
+	invalid.HeaderOptions = map[string]string{}
+	invalid.Cookies = append(invalid.Cookies, nil)
+	_, err = goinsta.ImportConfig(invalid, true)
+	var cerr goinsta.ConfigError
+	if !errors.As(err, &cerr) || len(cerr.Problems) != 3 {
+		t.Fatalf("Expected 3 problems, got %v", err)
+	}
+
+	// IDs in an unusual format are imported with a warning
+	unusual := config
+	unusual.DeviceID = "android-0123456789ABCDEF0"
+	unusual.FamilyID = "family"
+	if err := unusual.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := unusual.Warnings(); len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %q", warnings)
+	}
+	if _, err := goinsta.ImportConfig(unusual, true); err != nil {
+		t.Fatal(err)
+	}
+	unusual.PhoneID = "phone id"
+	if _, err := goinsta.ImportConfig(unusual, true); !errors.As(err, &cerr) {
+		t.Fatalf("Expected malformed phone ID to be rejected, got %v", err)
+	}
+
+	// Configs of newer versions are not imported
+	newer := config
+	newer.Version = goinsta.ConfigVersion + 1
+	if _, err := goinsta.ImportConfig(newer, true); err == nil {
+		t.Fatal("Expected config of a newer version to be rejected")
+	}
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestFingerprint(t *testing.T) {
+	f := goinsta.NewFingerprint("alice")
+	if f != goinsta.NewFingerprint("alice") {
+		t.Fatal("Expected the same seed to result in the same fingerprint")
+	}
+	other := goinsta.NewFingerprint("bob")
+	if other.DeviceID == f.DeviceID || other.UUID == f.UUID || other.FamilyID == f.FamilyID {
+		t.Fatalf("Expected different seeds to result in different IDs, got %+v", other)
+	}
+	if f.UUID == f.PhoneID || f.UUID == f.FamilyID || f.PhoneID == f.AdID {
+		t.Fatalf("Expected all IDs to differ, got %+v", f)
+	}
+
+	found := false
+	for _, d := range goinsta.Devices {
+		found = found || d == f.Device
+	}
+	if !found {
+		t.Fatalf("Device %+v is not in the catalog", f.Device)
+	}
+
+	// Adding profiles to the catalog does not change existing fingerprints
+	seeds := []string{"alice", "bob", "carol", "dave", "eve"}
+	var before []goinsta.Fingerprint
+	for _, seed := range seeds {
+		before = append(before, goinsta.NewFingerprint(seed))
+	}
+	devices := goinsta.Devices
+	defer func() { goinsta.Devices = devices }()
+	goinsta.Devices = append(append([]goinsta.Device{}, devices...), goinsta.Device{Model: "custom"})
+	for i, seed := range seeds {
+		if f := goinsta.NewFingerprint(seed); f != before[i] {
+			t.Fatalf("Fingerprint of %s changed with the catalog: %+v", seed, f)
+		}
+	}
+
+	// The fingerprint is kept in exported sessions
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	insta.SetFingerprint(f)
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	config, err := insta.ExportConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportConfig(config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := imported.Fingerprint()
+	if got != f {
+		t.Fatalf("Fingerprint changed on import: %+v, expected %+v", got, f)
+	}
+}
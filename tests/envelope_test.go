@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestPBKDF2Iterations(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	kp := goinsta.NewPassphraseKeyProvider("passphrase", goinsta.MinPBKDF2Iterations)
+	buf := new(bytes.Buffer)
+	if err := insta.ExportEncryptedIO(buf, kp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tampered iterations are rejected before a key is derived
+	for _, n := range []int{1, math.MaxInt32} {
+		var env map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+			t.Fatal(err)
+		}
+		env["key_id"] = "pbkdf2-sha256:" + strconv.Itoa(n)
+		b, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = goinsta.ImportEncryptedReader(bytes.NewReader(b), kp, true)
+		if err == nil || !strings.Contains(err.Error(), "PBKDF2 iterations") {
+			t.Fatalf("Expected %d iterations to be rejected, got %v", n, err)
+		}
+	}
+
+	// Weak keys are not used for exports
+	weak := goinsta.NewPassphraseKeyProvider("passphrase", 1000)
+	if err := insta.ExportEncryptedIO(new(bytes.Buffer), weak); err == nil {
+		t.Fatal("Expected export with 1000 iterations to fail")
+	}
+}
+
+func TestEncryptedExport(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	config, err := insta.ExportConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.TrimPrefix(config.HeaderOptions["Authorization"], "Bearer IGT:2:")
+
+	kp := goinsta.NewPassphraseKeyProvider("correct horse battery staple", goinsta.MinPBKDF2Iterations)
+	buf := new(bytes.Buffer)
+	if err := insta.ExportEncryptedIO(buf, kp); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), token) || strings.Contains(buf.String(), "alice") {
+		t.Fatal("Exported config contains plain text session data")
+	}
+
+	encrypted := buf.Bytes()
+	imported, err := goinsta.ImportEncryptedReader(bytes.NewReader(encrypted), kp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Account.ID != insta.Account.ID {
+		t.Fatalf("Imported account %d, expected %d", imported.Account.ID, insta.Account.ID)
+	}
+
+	wrong := goinsta.NewPassphraseKeyProvider("wrong", goinsta.MinPBKDF2Iterations)
+	if _, err := goinsta.ImportEncryptedReader(bytes.NewReader(encrypted), wrong, true); err == nil {
+		t.Fatal("Expected import with wrong passphrase to fail")
+	}
+
+	// Plain text configs still import
+	plain := new(bytes.Buffer)
+	if err := insta.ExportIO(plain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goinsta.ImportEncryptedReader(plain, kp, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Encrypted files are only readable by the owner
+	path := filepath.Join(t.TempDir(), "session")
+	if err := insta.ExportEncrypted(path, kp); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected session file to be private, got %s", info.Mode())
+	}
+	if _, err := goinsta.ImportEncrypted(path, kp, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file store encrypts sessions with a key provider
+	store, err := goinsta.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.KeyProvider = kp
+	if err := store.Save("alice", &config); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(store.Dir, "alice.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), token) {
+		t.Fatal("Stored session contains the plain text token")
+	}
+	if _, err := store.Load("alice"); err != nil {
+		t.Fatal(err)
+	}
+}
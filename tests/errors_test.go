@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+func TestErrorTaxonomy(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		body      string
+		header    http.Header
+		sentinel  error
+		retryable bool
+		wait      time.Duration
+	}{
+		{
+			name:     "login required",
+			code:     403,
+			body:     `{"message":"login_required","logout_reason":2,"status":"fail"}`,
+			sentinel: goinsta.ErrLoginRequired,
+		},
+		{
+			name:     "challenge",
+			code:     400,
+			body:     `{"message":"challenge_required","challenge":{"api_path":"/challenge/1/abc/"},"status":"fail"}`,
+			sentinel: goinsta.ErrChallengeRequired,
+		},
+		{
+			name:     "checkpoint",
+			code:     400,
+			body:     `{"message":"checkpoint required","error_type":"checkpoint_challenge_required","status":"fail"}`,
+			sentinel: goinsta.ErrChallengeRequired,
+		},
+		{
+			name:     "action blocked",
+			code:     400,
+			body:     `{"message":"feedback_required","spam":true,"feedback_title":"Try Again Later","status":"fail"}`,
+			sentinel: goinsta.ErrFeedbackRequired,
+		},
+		{
+			name:      "rate limited",
+			code:      429,
+			body:      `{"message":"Please wait a few minutes before you try again.","status":"fail"}`,
+			header:    http.Header{"Retry-After": []string{"7"}},
+			sentinel:  goinsta.ErrTooManyRequests,
+			retryable: true,
+			wait:      7 * time.Second,
+		},
+		{
+			name:     "media deleted",
+			code:     400,
+			body:     `{"message":"Sorry, this media has been deleted","status":"fail"}`,
+			sentinel: goinsta.ErrMediaDeleted,
+		},
+		{
+			name:     "private account",
+			code:     400,
+			body:     `{"message":"Not authorized to view user","status":"fail"}`,
+			sentinel: goinsta.ErrPrivateAccount,
+		},
+		{
+			name:     "not found",
+			code:     404,
+			body:     `{"message":"User not found","status":"fail"}`,
+			sentinel: goinsta.ErrNotFound,
+		},
+		{
+			name:     "not found with a generic message",
+			code:     404,
+			body:     `{"message":"Page not found","status":"fail"}`,
+			sentinel: goinsta.ErrNotFound,
+		},
+		{
+			name:      "server error",
+			code:      502,
+			body:      `Bad Gateway`,
+			retryable: true,
+		},
+		{
+			name:      "server error with a JSON body",
+			code:      502,
+			body:      `{"message":"Please try again","status":"fail"}`,
+			retryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+				resp := jsonResponse(tt.code, tt.body)
+				for k, v := range tt.header {
+					resp.Header[k] = v
+				}
+				return resp, nil
+			})
+			_, err := insta.Profiles.ByName("someone")
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("Expected %v to match %v", err, tt.sentinel)
+			}
+			if goinsta.IsRetryable(err) != tt.retryable {
+				t.Errorf("Expected retryable to be %v for %v", tt.retryable, err)
+			}
+			if d := goinsta.RetryAfter(err); d != tt.wait {
+				t.Errorf("Expected retry after %s, got %s", tt.wait, d)
+			}
+		})
+	}
+
+	// ErrMediaDeleted is returned as is, and can be compared with ==
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(400, `{"message":"Sorry, this media has been deleted","status":"fail"}`), nil
+	})
+	if _, err := insta.Profiles.ByName("someone"); err != goinsta.ErrMediaDeleted {
+		t.Fatalf("Expected ErrMediaDeleted, got %#v", err)
+	}
+
+	// Typed errors give access to the response details
+	insta = newStubInsta(func(req *http.Request) (*http.Response, error) {
+		return jsonResponse(400, `{"message":"feedback_required","feedback_title":"Try Again Later","status":"fail"}`), nil
+	})
+	_, err := insta.Profiles.ByName("someone")
+	var ierr goinsta.Error400
+	if !errors.As(err, &ierr) || ierr.FeedbackTitle != "Try Again Later" {
+		t.Fatalf("Expected Error400 with feedback title, got %#v", err)
+	}
+}
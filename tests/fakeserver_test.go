@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestFakeServer(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	bob := srv.AddUser(goinstatest.User{Username: "bob", Password: "hunter2"})
+	post := srv.AddMedia(bob.ID, "first post")
+
+	if err := srv.NewInstagram("alice", "wrong").Login(); err == nil {
+		t.Fatal("Expected login with a wrong password to fail")
+	}
+
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account.ID != alice.ID {
+		t.Fatalf("Logged in as %d, expected %d", insta.Account.ID, alice.ID)
+	}
+
+	// Follow, and see the user in the following list
+	user, err := insta.Profiles.ByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := user.Follow(); err != nil {
+		t.Fatal(err)
+	}
+	if !user.Friendship.Following || !srv.Follows(alice.ID, bob.ID) {
+		t.Fatal("Follow has not been stored")
+	}
+	following := insta.Account.Following()
+	following.Next()
+	if len(following.Users) != 1 || following.Users[0].ID != bob.ID {
+		t.Fatalf("Expected bob in following, got %+v", following.Users)
+	}
+
+	// Like a post from the user feed
+	feed := user.Feed()
+	feed.Next()
+	if len(feed.Items) != 1 || feed.Items[0].ID != post {
+		t.Fatalf("Unexpected feed items: %+v", feed.Items)
+	}
+	if err := feed.Items[0].Like(); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.Liked(alice.ID, post) {
+		t.Fatal("Like has not been stored")
+	}
+
+	// Send a message, and see it in the conversation
+	conv, err := insta.Inbox.New(user, "hi bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conv.Send("how are you?"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conv.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Items) != 2 || conv.Items[0].Text != "how are you?" {
+		t.Fatalf("Unexpected conversation items: %+v", conv.Items)
+	}
+	if msgs := srv.Messages(alice.ID, bob.ID); len(msgs) != 2 {
+		t.Fatalf("Expected 2 stored messages, got %v", msgs)
+	}
+
+	// Upload a photo, and see it in the own feed
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 64, 64)), nil); err != nil {
+		t.Fatal(err)
+	}
+	item, err := insta.Upload(&goinsta.UploadOptions{File: buf, Caption: "uploaded"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownFeed := insta.Account.Feed()
+	ownFeed.Next()
+	if len(ownFeed.Items) != 1 || ownFeed.Items[0].ID != item.ID ||
+		ownFeed.Items[0].Caption.Text != "uploaded" {
+		t.Fatalf("Uploaded photo not found in feed: %+v", ownFeed.Items)
+	}
+
+	// Requests without a session are rejected
+	anon := srv.NewInstagram("bob", "hunter2")
+	if _, err := anon.Profiles.ByName("alice"); err == nil {
+		t.Fatal("Expected login_required error without a session")
+	}
+}
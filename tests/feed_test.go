@@ -4,12 +4,10 @@ import (
 	"math/rand"
 	"testing"
 	"time"
-
-	"github.com/UliSotschok/goinsta"
 )
 
 func TestFeedUser(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,7 +40,7 @@ outside:
 }
 
 func TestFeedDiscover(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -70,7 +68,7 @@ outside:
 }
 
 func TestFeedTagLike(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,7 +102,7 @@ func TestFeedTagLike(t *testing.T) {
 }
 
 func TestFeedTagNextOld(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -133,7 +131,7 @@ func TestFeedTagNextOld(t *testing.T) {
 }
 
 func TestFeedTagNext(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -168,7 +166,7 @@ func TestFeedTagNext(t *testing.T) {
 }
 
 func TestFeedTagNextRecent(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
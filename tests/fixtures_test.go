@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+// fixtureMode is set with the GOINSTA_FIXTURES env variable:
+//   record - run tests against Instagram, and record all requests in fixtures/
+//   replay - run tests offline, by replaying the recorded fixtures. Tests
+//            without a recorded fixture are skipped.
+// If not set, tests run against Instagram without recording anything.
+var fixtureMode = os.Getenv("GOINSTA_FIXTURES")
+
+const fixtureDir = "fixtures"
+
+// mediaClient is used to download test media, so the downloads are recorded
+//   and replayed together with the Instagram requests.
+var mediaClient = http.DefaultClient
+
+var (
+	fixtureMu sync.Mutex
+	// fixtures holds the transport of each test, as some tests use multiple
+	//   accounts.
+	fixtures = map[string]*fixture{}
+)
+
+type fixture struct {
+	transport http.RoundTripper
+	sessions  int
+}
+
+// testRand is used for random choices, which influence the requests being
+//   sent. With fixtures it is seeded deterministically, so a replay makes the
+//   same choices as the recording.
+var testRand = newTestRand()
+
+func newTestRand() *rand.Rand {
+	if fixtureMode != "" {
+		return rand.New(rand.NewSource(1))
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// envAccount returns a random account from the env, like goinsta.EnvRandAcc.
+// In record mode, a redacted copy of the session is stored with the fixtures,
+//   in replay mode the stored session is used instead of the env.
+func envAccount(t *testing.T) (*goinsta.Instagram, error) {
+	f, err := getFixture(t)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fixturePath(t, fmt.Sprintf("session%d.json", f.sessions))
+	f.sessions++
+
+	var insta *goinsta.Instagram
+	switch fixtureMode {
+	case "replay":
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		config := goinsta.ConfigFile{}
+		if err := json.Unmarshal(b, &config); err != nil {
+			return nil, err
+		}
+		insta, err = goinsta.ImportConfig(config, true)
+		if err != nil {
+			return nil, err
+		}
+	case "record":
+		insta, err = goinsta.EnvRandAcc()
+		if err != nil {
+			return nil, err
+		}
+		if err := saveSession(insta, path); err != nil {
+			return nil, err
+		}
+	default:
+		return goinsta.EnvRandAcc()
+	}
+	insta.SetHTTPTransport(f.transport)
+	return insta, nil
+}
+
+// envLogin returns random login credentials from the env. In replay mode
+//   placeholder credentials are returned, as the login is replayed.
+func envLogin(t *testing.T) (string, string, error) {
+	if fixtureMode == "replay" {
+		return "replay_user", "replay_pass", nil
+	}
+	return goinsta.EnvRandLogin()
+}
+
+// newEnvInsta creates a new instance with the fixture transport of the test
+func newEnvInsta(t *testing.T, user, pass string) (*goinsta.Instagram, error) {
+	insta := goinsta.New(user, pass)
+	if fixtureMode == "" {
+		return insta, nil
+	}
+	f, err := getFixture(t)
+	if err != nil {
+		return nil, err
+	}
+	insta.SetHTTPTransport(f.transport)
+	return insta, nil
+}
+
+// getFixture returns the fixture of the current test, creating its recorder
+//   or replayer on first use. In replay mode, the test is skipped if no
+//   fixture has been recorded for it.
+func getFixture(t *testing.T) (*fixture, error) {
+	fixtureMu.Lock()
+	defer fixtureMu.Unlock()
+
+	if f, ok := fixtures[t.Name()]; ok {
+		return f, nil
+	}
+
+	f := &fixture{transport: http.DefaultTransport}
+	path := fixturePath(t, "requests.jsonl")
+	switch fixtureMode {
+	case "replay":
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			t.Skipf("No fixture recorded in %s", path)
+		}
+		r, err := goinstatest.NewReplayer(path)
+		if err != nil {
+			return nil, err
+		}
+		f.transport = r
+	case "record":
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		r, err := goinstatest.NewRecorder(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { r.Close() })
+		f.transport = r
+	}
+
+	fixtures[t.Name()] = f
+	if fixtureMode != "" {
+		mediaClient = &http.Client{Transport: f.transport}
+		t.Cleanup(func() {
+			fixtureMu.Lock()
+			defer fixtureMu.Unlock()
+			delete(fixtures, t.Name())
+			mediaClient = http.DefaultClient
+		})
+	}
+	return f, nil
+}
+
+func fixturePath(t *testing.T, name string) string {
+	test := strings.ReplaceAll(t.Name(), "/", "_")
+	return filepath.Join(fixtureDir, test, name)
+}
+
+func saveSession(insta *goinsta.Instagram, path string) error {
+	buf := new(strings.Builder)
+	if err := insta.ExportIO(buf); err != nil {
+		return err
+	}
+	config := goinsta.ConfigFile{}
+	if err := json.Unmarshal([]byte(buf.String()), &config); err != nil {
+		return err
+	}
+	goinstatest.RedactConfig(&config)
+
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0o600)
+}
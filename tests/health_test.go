@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+func TestHealth(t *testing.T) {
+	var status int
+	var body string
+	sent := 0
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		if strings.Contains(req.URL.Path, "usernameinfo") {
+			return jsonResponse(200, `{"status":"ok","user":{"pk":2,"username":"someone"}}`), nil
+		}
+		return jsonResponse(status, body), nil
+	})
+	insta.Account = &goinsta.Account{ID: 1}
+
+	var changes []goinsta.HealthState
+	insta.SetHealthHandler(func(old, new goinsta.Health) {
+		changes = append(changes, new.State)
+	})
+	insta.SetHealthPolicy(goinsta.HealthPolicy{
+		FailFast:      true,
+		BlockDuration: time.Hour,
+	})
+
+	user, err := insta.Profiles.ByName("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An action block is detected, and further writes fail fast
+	status = 400
+	body = `{"message":"feedback_required","spam":true,"feedback_message":"We restrict certain activity","status":"fail"}`
+	if err := user.Follow(); !errors.Is(err, goinsta.ErrFeedbackRequired) {
+		t.Fatalf("Expected feedback required, got %v", err)
+	}
+	h := insta.Health()
+	if h.State != goinsta.HealthActionBlocked || h.Until.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("Unexpected health: %s", h)
+	}
+	if h.Reason != "We restrict certain activity" {
+		t.Fatalf("Unexpected reason: %s", h.Reason)
+	}
+
+	sentBefore := sent
+	err = user.Follow()
+	var unhealthy goinsta.ErrAccountUnhealthy
+	if !errors.As(err, &unhealthy) || !errors.Is(err, goinsta.ErrFeedbackRequired) {
+		t.Fatalf("Expected fail fast error, got %v", err)
+	}
+	if sent != sentBefore {
+		t.Fatal("Write action has been sent while blocked")
+	}
+
+	// Reads are still allowed
+	if _, err := insta.Profiles.ByName("someone"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The health state is kept on export
+	buf := new(bytes.Buffer)
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Health().State != goinsta.HealthActionBlocked {
+		t.Fatalf("Health has not been imported: %s", imported.Health())
+	}
+
+	// Rate limits expire, and are cleared by a successful request
+	insta.ResetHealth()
+	status = 429
+	body = `{"message":"Please wait a few minutes before you try again.","status":"fail"}`
+	if err := user.Follow(); !errors.Is(err, goinsta.ErrTooManyRequests) {
+		t.Fatalf("Expected too many requests, got %v", err)
+	}
+	if insta.Health().State != goinsta.HealthRateLimited {
+		t.Fatalf("Expected rate limited, got %s", insta.Health())
+	}
+	insta.SetHealthPolicy(goinsta.HealthPolicy{})
+	status = 200
+	body = `{"status":"ok","friendship_status":{"following":true}}`
+	if err := user.Follow(); err != nil {
+		t.Fatal(err)
+	}
+	if !insta.Health().OK() {
+		t.Fatalf("Expected health to be ok, got %s", insta.Health())
+	}
+
+	// Logged out sessions are detected
+	status = 403
+	body = `{"message":"login_required","logout_reason":2,"status":"fail"}`
+	user.Follow()
+	if insta.Health().State != goinsta.HealthLoggedOut {
+		t.Fatalf("Expected logged out, got %s", insta.Health())
+	}
+
+	expected := []goinsta.HealthState{
+		goinsta.HealthActionBlocked,
+		goinsta.HealthOK,
+		goinsta.HealthRateLimited,
+		goinsta.HealthOK,
+		goinsta.HealthLoggedOut,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected health changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatalf("Expected health changes %v, got %v", expected, changes)
+		}
+	}
+}
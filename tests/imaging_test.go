@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestImagePreprocessing(t *testing.T) {
+	// A panorama is too wide for the feed
+	wide := encodePNG(t, 2000, 500)
+	for _, fit := range []goinsta.ImageFit{goinsta.FitCrop, goinsta.FitSmartCrop, goinsta.FitPad, goinsta.FitBlur} {
+		b, err := goinsta.ProcessImage(wide, goinsta.ImageOptions{Fit: fit}, goinsta.MinFeedAspectRatio, goinsta.MaxFeedAspectRatio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, h := decodeSize(t, b)
+		if w < goinsta.MinImageWidth || w > goinsta.MaxImageWidth {
+			t.Fatalf("Fit %d: width %d is out of range", fit, w)
+		}
+		if ratio := float64(w) / float64(h); math.Abs(ratio-goinsta.MaxFeedAspectRatio) > 0.01 {
+			t.Fatalf("Fit %d: expected aspect ratio %.2f, got %dx%d", fit, goinsta.MaxFeedAspectRatio, w, h)
+		}
+	}
+
+	// Stories are fit into 9:16, small images are upscaled
+	b, err := goinsta.ProcessImage(wide, goinsta.ImageOptions{Fit: goinsta.FitPad}, goinsta.StoryAspectRatio, goinsta.StoryAspectRatio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, b); w != 1080 || math.Abs(float64(h)-1920) > 2 {
+		t.Fatalf("Expected a 1080x1920 story, got %dx%d", w, h)
+	}
+	b, err = goinsta.ProcessImage(encodePNG(t, 100, 100), goinsta.ImageOptions{}, goinsta.MinFeedAspectRatio, goinsta.MaxFeedAspectRatio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, b); w != goinsta.MinImageWidth || h != goinsta.MinImageWidth {
+		t.Fatalf("Expected a %dx%[1]d image, got %dx%d", goinsta.MinImageWidth, w, h)
+	}
+
+	// Images are rotated according to their EXIF orientation
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 600, 400)), nil); err != nil {
+		t.Fatal(err)
+	}
+	b, err = goinsta.ProcessImage(withOrientation(buf.Bytes(), 6), goinsta.ImageOptions{}, goinsta.MinFeedAspectRatio, goinsta.MaxFeedAspectRatio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, b); w != 400 || h != 500 {
+		t.Fatalf("Expected the rotated 400x600 image to be cropped to 400x500, got %dx%d", w, h)
+	}
+
+	// WebP images are decoded, HEIC images are rejected with a clear error
+	webp, err := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = goinsta.ProcessImage(webp, goinsta.ImageOptions{}, goinsta.MinFeedAspectRatio, goinsta.MaxFeedAspectRatio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, b); w != goinsta.MinImageWidth || h != goinsta.MinImageWidth {
+		t.Fatalf("Expected a %dx%[1]d image, got %dx%d", goinsta.MinImageWidth, w, h)
+	}
+	heic := append([]byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), make([]byte, 64)...)
+	_, err = goinsta.ProcessImage(heic, goinsta.ImageOptions{}, goinsta.MinFeedAspectRatio, goinsta.MaxFeedAspectRatio)
+	if !errors.Is(err, goinsta.ErrInvalidFormat) || !strings.Contains(err.Error(), "HEIC") {
+		t.Fatalf("Expected ErrInvalidFormat for a HEIC image, got %v", err)
+	}
+
+	// Images in other formats are uploaded, if preprocessing is enabled
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := insta.Upload(&goinsta.UploadOptions{File: bytes.NewReader(wide)}); !errors.Is(err, goinsta.ErrInvalidFormat) {
+		t.Fatalf("Expected ErrInvalidFormat without preprocessing, got %v", err)
+	}
+	_, err = insta.Upload(&goinsta.UploadOptions{
+		File:       bytes.NewReader(wide),
+		Caption:    "panorama",
+		Preprocess: &goinsta.ImageOptions{Fit: goinsta.FitBlur},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeSize(t *testing.T, b []byte) (int, int) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" {
+		t.Fatalf("Expected a jpeg, got %s", format)
+	}
+	return cfg.Width, cfg.Height
+}
+
+// withOrientation inserts an EXIF segment with the orientation after the SOI
+//   marker of a JPEG.
+func withOrientation(b []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+
+	out := append([]byte{}, b[:2]...)
+	out = append(out, app1...)
+	return append(out, b[2:]...)
+}
@@ -1,11 +1,7 @@
 package tests
 
 import (
-	"math/rand"
 	"testing"
-	"time"
-
-	"github.com/UliSotschok/goinsta"
 )
 
 // Random big accounts used for story reply and DM tests
@@ -26,7 +22,7 @@ var possibleUsers = []string{
 }
 
 func TestStoryReply(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -57,7 +53,7 @@ func TestStoryReply(t *testing.T) {
 }
 
 func TestInboxSync(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -76,15 +72,14 @@ func TestInboxSync(t *testing.T) {
 }
 
 func TestInboxNew(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Logf("Logged in as %s\n", insta.Account.Username)
 	insta.SetWarnHandler(t.Log)
 
-	rand.Seed(time.Now().UnixNano())
-	randUser := possibleUsers[rand.Intn(len(possibleUsers))]
+	randUser := possibleUsers[testRand.Intn(len(possibleUsers))]
 	user, err := insta.Profiles.ByName(randUser)
 	if err != nil {
 		t.Fatal(err)
@@ -8,12 +8,15 @@ import (
 
 func TestImportAccount(t *testing.T) {
 	// Test Login
-	user, pass, err := goinsta.EnvRandLogin()
+	user, pass, err := envLogin(t)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	insta := goinsta.New(user, pass)
+	insta, err := newEnvInsta(t, user, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = insta.Login()
 	if err != nil {
 		t.Fatal(err)
@@ -22,7 +25,7 @@ func TestImportAccount(t *testing.T) {
 	logPosts(t, insta)
 
 	// Test Import
-	insta, err = goinsta.EnvRandAcc()
+	insta, err = envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestLoginActivity(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.NewInstagram("alice", "secret").Login(); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddSuspiciousLogin(alice.ID, "iPhone 12", "Paris, France")
+	srv.AddSuspiciousLogin(alice.ID, "Pixel 5", "Berlin, Germany")
+
+	activity, err := insta.Account.LoginActivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity.Sessions) != 4 || len(activity.SuspiciousLogins) != 2 {
+		t.Fatalf("Expected 4 sessions and 2 suspicious logins, got %+v", activity)
+	}
+	current := 0
+	for _, s := range activity.Sessions {
+		if s.IsCurrent {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Fatalf("Expected exactly one current session, got %d", current)
+	}
+
+	// This was me, this wasn't me
+	for _, login := range activity.SuspiciousLogins {
+		if login.Location == "Paris, France" {
+			err = insta.Account.ConfirmLogin(login)
+		} else {
+			err = insta.Account.DenyLogin(login)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	activity, err = insta.Account.LoginActivity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity.Sessions) != 3 || len(activity.SuspiciousLogins) != 0 {
+		t.Fatalf("Expected 3 sessions and no suspicious logins, got %+v", activity)
+	}
+
+	if err := insta.Account.LogoutOtherSessions(); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Sessions(alice.ID); n != 1 {
+		t.Fatalf("Expected only the current session to be left, got %d", n)
+	}
+	if err := insta.Account.Sync(); err != nil {
+		t.Fatalf("Current session has been logged out: %s", err)
+	}
+}
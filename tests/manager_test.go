@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestAccountManager(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	store := goinsta.NewMemoryStore()
+	users := []string{"alice", "bob", "carol"}
+	ids := map[string]int64{}
+	for _, name := range users {
+		ids[name] = srv.AddUser(goinstatest.User{Username: name, Password: "secret"}).ID
+		insta := srv.NewInstagram(name, "secret")
+		insta.SetSessionStore(store, "")
+		if err := insta.Login(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := goinsta.NewAccountManager(store)
+	m.Cooldown = time.Hour
+	m.Configure = func(key string, insta *goinsta.Instagram) error {
+		insta.SetHTTPTransport(srv.Transport())
+		insta.SetWarnHandler(func(...interface{}) {})
+		return nil
+	}
+	// A corrupt session is skipped, the others are loaded
+	if err := store.Save("broken", &goinsta.ConfigFile{User: "broken"}); err != nil {
+		t.Fatal(err)
+	}
+	var warned []interface{}
+	m.WarnHandler = func(args ...interface{}) { warned = append(warned, args...) }
+	err := m.LoadAll()
+	var lerr goinsta.LoadError
+	if !errors.As(err, &lerr) || len(lerr.Errors) != 1 || lerr.Errors["broken"] == nil {
+		t.Fatalf("Expected the broken session to fail, got %v", err)
+	}
+	if len(warned) == 0 {
+		t.Fatal("Expected the broken session to be passed to the WarnHandler")
+	}
+	if m.Len() != len(users) {
+		t.Fatalf("Expected %d accounts, got %d", len(users), m.Len())
+	}
+
+	// Every account is handed out once, before the cooldown blocks them
+	seen := map[*goinsta.Instagram]bool{}
+	for range users {
+		insta, err := m.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[insta] {
+			t.Fatal("Account handed out twice during its cooldown")
+		}
+		seen[insta] = true
+	}
+	if _, err := m.Next(); !errors.Is(err, goinsta.ErrNoAccountAvailable) {
+		t.Fatalf("Expected ErrNoAccountAvailable, got %v", err)
+	}
+
+	// Logged out accounts are skipped, and Wait returns cooled down accounts
+	m.Cooldown = 50 * time.Millisecond
+	srv.ExpireSessions(ids["alice"])
+	if err := m.Account("alice").Account.Sync(); err == nil {
+		t.Fatal("Expected sync of an expired session to fail")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 4; i++ {
+		insta, err := m.Wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if insta == m.Account("alice") {
+			t.Fatal("Logged out account has been handed out")
+		}
+	}
+
+	for _, s := range m.Status() {
+		if s.Key == "alice" && (s.Available || s.Health.State != goinsta.HealthLoggedOut) {
+			t.Fatalf("Expected alice to be logged out, got %+v", s)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestReadVideoInfo(t *testing.T) {
+	cases := []struct {
+		name  string
+		video testVideo
+		info  goinsta.VideoInfo
+	}{
+		{
+			name:  "h264 with moov at the end",
+			video: testVideo{codec: "avc1", width: 1920, height: 1080, audio: true, largeMdat: true, size: 4000},
+			info:  goinsta.VideoInfo{Codec: "avc1", Width: 1920, Height: 1080, HasAudio: true},
+		},
+		{
+			name:  "rotated hevc with 64 bit times",
+			video: testVideo{codec: "hvc1", width: 1920, height: 1080, rotation: 90, version: 1, moovFirst: true, size: 4000},
+			info:  goinsta.VideoInfo{Codec: "hvc1", Width: 1080, Height: 1920, Rotation: 90},
+		},
+		{
+			name:  "upside down with multiple video tracks",
+			video: testVideo{codec: "avc1", width: 720, height: 720, rotation: 180, tracks: 2, audio: true, size: 4000},
+			info:  goinsta.VideoInfo{Codec: "avc1", Width: 720, Height: 720, Rotation: 180, HasAudio: true},
+		},
+	}
+	for _, c := range cases {
+		b := c.video.encode()
+		info, err := goinsta.ReadVideoInfo(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if math.Abs(info.FrameRate-30) > 0.01 || info.Duration != 5*time.Second ||
+			info.Bitrate != int64(len(b))*8/5 {
+			t.Fatalf("%s: unexpected timing: %+v", c.name, info)
+		}
+		info.FrameRate, info.Duration, info.Bitrate = 0, 0, 0
+		if *info != c.info {
+			t.Fatalf("%s: expected %+v, got %+v", c.name, c.info, *info)
+		}
+	}
+
+	// Invalid files are rejected
+	b := testVideo{codec: "avc1", width: 640, height: 360, size: 4000}.encode()
+	for _, invalid := range [][]byte{b[:len(b)-10], b[:100], []byte("no video")} {
+		if _, err := goinsta.ReadVideoInfo(bytes.NewReader(invalid), int64(len(invalid))); !errors.Is(err, goinsta.ErrInvalidFormat) {
+			t.Fatalf("Expected ErrInvalidFormat, got %v", err)
+		}
+	}
+
+	// Upload rejects unsupported codecs
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	b = testVideo{codec: "mp4v", width: 640, height: 360, size: 4000}.encode()
+	if _, err := insta.Upload(&goinsta.UploadOptions{File: bytes.NewReader(b)}); !errors.Is(err, goinsta.ErrInvalidFormat) {
+		t.Fatalf("Expected ErrInvalidFormat for mp4v, got %v", err)
+	}
+}
+
+// testVideo creates a mp4 file, which is 5 seconds long with 30 fps.
+type testVideo struct {
+	codec         string
+	width, height uint16
+	rotation      int
+	audio         bool
+	// number of video tracks, 1 by default
+	tracks int
+	// version of the header boxes, 1 uses 64 bit times
+	version byte
+	// position of the moov box, and whether mdat has a 64 bit size
+	moovFirst bool
+	largeMdat bool
+	// size of the media data
+	size int
+	// major brand of the ftyp box, mp42 by default, "qt  " for QuickTime
+	brand string
+}
+
+func (v testVideo) encode() []byte {
+	mvhd := v.header(100, 112, 1000, 5000)
+
+	var traks [][]byte
+	tracks := v.tracks
+	if tracks == 0 {
+		tracks = 1
+	}
+	for i := 0; i < tracks; i++ {
+		entry := make([]byte, 78)
+		binary.BigEndian.PutUint16(entry[24:], v.width)
+		binary.BigEndian.PutUint16(entry[26:], v.height)
+		stts := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 150, 0, 0, 0x03, 0xE8}
+		traks = append(traks, mp4Box("trak",
+			v.trackHeader(),
+			v.media("vide", mp4Box(v.codec, entry), stts),
+		))
+		// only the first track has the requested size
+		v.width, v.height, v.rotation = 100, 100, 0
+	}
+	if v.audio {
+		traks = append(traks, mp4Box("trak", v.media("soun", mp4Box("mp4a", make([]byte, 28)), nil)))
+	}
+	moov := mp4Box("moov", append([][]byte{mp4Box("mvhd", mvhd)}, traks...)...)
+
+	data := make([]byte, v.size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	mdat := mp4Box("mdat", data)
+	if v.largeMdat {
+		mdat = make([]byte, 16)
+		binary.BigEndian.PutUint32(mdat, 1)
+		copy(mdat[4:], "mdat")
+		binary.BigEndian.PutUint64(mdat[8:], uint64(16+len(data)))
+		mdat = append(mdat, data...)
+	}
+
+	brand := v.brand
+	if brand == "" {
+		brand = "mp42"
+	}
+	b := mp4Box("ftyp", []byte(brand+"\x00\x00\x00\x00isom"+brand))
+	if v.moovFirst {
+		return append(append(b, moov...), mdat...)
+	}
+	return append(append(b, mdat...), moov...)
+}
+
+// header creates a mvhd or mdhd payload of the given version
+func (v testVideo) header(size0, size1 int, timescale uint32, duration uint64) []byte {
+	if v.version == 1 {
+		b := make([]byte, size1)
+		b[0] = 1
+		binary.BigEndian.PutUint32(b[20:], timescale)
+		binary.BigEndian.PutUint64(b[24:], duration)
+		return b
+	}
+	b := make([]byte, size0)
+	binary.BigEndian.PutUint32(b[12:], timescale)
+	binary.BigEndian.PutUint32(b[16:], uint32(duration))
+	return b
+}
+
+func (v testVideo) trackHeader() []byte {
+	offset, b := 40, make([]byte, 84)
+	if v.version == 1 {
+		offset, b = 52, make([]byte, 96)
+		b[0] = 1
+	}
+	rad := float64(v.rotation) * math.Pi / 180
+	fixed := func(f float64) uint32 { return uint32(int32(math.Round(f * 0x10000))) }
+	m := b[offset:]
+	binary.BigEndian.PutUint32(m, fixed(math.Cos(rad)))
+	binary.BigEndian.PutUint32(m[4:], fixed(math.Sin(rad)))
+	binary.BigEndian.PutUint32(m[12:], fixed(-math.Sin(rad)))
+	binary.BigEndian.PutUint32(m[16:], fixed(math.Cos(rad)))
+	binary.BigEndian.PutUint32(m[32:], 0x40000000)
+	binary.BigEndian.PutUint32(m[36:], uint32(v.width)<<16)
+	binary.BigEndian.PutUint32(m[40:], uint32(v.height)<<16)
+	return mp4Box("tkhd", b)
+}
+
+func (v testVideo) media(handler string, entry, stts []byte) []byte {
+	hdlr := make([]byte, 25)
+	copy(hdlr[8:], handler)
+	stbl := [][]byte{mp4Box("stsd", append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, entry...))}
+	if stts != nil {
+		stbl = append(stbl, mp4Box("stts", stts))
+	}
+	return mp4Box("mdia",
+		mp4Box("mdhd", v.header(24, 36, 30000, 150000)),
+		mp4Box("hdlr", hdlr),
+		mp4Box("minf", mp4Box("stbl", stbl...)),
+	)
+}
+
+func mp4Box(typ string, payload ...[]byte) []byte {
+	b := make([]byte, 8)
+	copy(b[4:], typ)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
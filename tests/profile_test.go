@@ -2,12 +2,10 @@ package tests
 
 import (
 	"testing"
-
-	"github.com/UliSotschok/goinsta"
 )
 
 func TestProfileVisit(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -26,7 +24,7 @@ func TestProfileVisit(t *testing.T) {
 }
 
 func TestProfilesByName(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,7 +37,7 @@ func TestProfilesByName(t *testing.T) {
 }
 
 func TestProfilesByID(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -52,7 +50,7 @@ func TestProfilesByID(t *testing.T) {
 }
 
 func TestProfilesBlocked(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
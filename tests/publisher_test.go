@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestPublisher(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	accounts := func(key string) *goinsta.Instagram {
+		if key == "alice" {
+			return insta
+		}
+		return nil
+	}
+
+	dir := t.TempDir()
+	store, err := goinsta.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Jobs are persisted, and published after a restart
+	p := goinsta.NewPublisher(store, accounts)
+	job, err := p.Schedule("alice", time.Now(), &goinsta.UploadOptions{
+		File:    bytes.NewReader(encodeJPEG(t, 500, 500)),
+		Caption: "scheduled",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published := make(chan *goinsta.Item, 1)
+	failed := make(chan error, 1)
+	p = goinsta.NewPublisher(store, accounts)
+	p.Retry = &goinsta.RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond}
+	p.TranscodeWait = 10 * time.Millisecond
+	p.KeepFinished = true
+	p.OnPublished = func(job *goinsta.PublishJob, item *goinsta.Item) { published <- item }
+	p.OnFailed = func(job *goinsta.PublishJob, err error) { failed <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- p.Run(ctx) }()
+
+	item := awaitPublished(t, published, failed)
+	if item.Caption.Text != "scheduled" {
+		t.Fatalf("Expected the scheduled caption, got %q", item.Caption.Text)
+	}
+	job, err = p.Job(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != goinsta.JobPublished || job.MediaID != item.ID || job.Attempts != 1 {
+		t.Fatalf("Unexpected job: %+v", job)
+	}
+
+	// Videos are referenced by path, and retried if Instagram asks for a
+	//   reupload more than once
+	video := testVideo{codec: "avc1", width: 640, height: 640, size: 4000}.encode()
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := ioutil.WriteFile(path, video, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.DelayTranscode(1)
+	srv.RequestReupload(2)
+	job, err = p.Schedule("alice", time.Now().Add(50*time.Millisecond), &goinsta.UploadOptions{File: f, Caption: "video"})
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Upload.Files) != 1 || job.Upload.Files[0].Path != path {
+		t.Fatalf("Expected the video to be referenced by path: %+v", job.Upload.Files)
+	}
+
+	item = awaitPublished(t, published, failed)
+	if !bytes.Equal(srv.UploadedVideo(item.ID), video) {
+		t.Fatal("Uploaded video differs from the file")
+	}
+	job, err = p.Job(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != goinsta.JobPublished || job.Attempts != 2 || job.Error != "" {
+		t.Fatalf("Expected the job to be published with the second attempt: %+v", job)
+	}
+
+	// Posts are not retried, if configure failed after the media was created
+	srv.FailConfigure(1)
+	job, err = p.Schedule("alice", time.Now(), &goinsta.UploadOptions{
+		File:    bytes.NewReader(encodeJPEG(t, 500, 500)),
+		Caption: "configured",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item = awaitPublished(t, published, failed)
+	if job, err = p.Job(job.ID); err != nil || job.MediaID != item.ID || job.Attempts != 1 {
+		t.Fatalf("Expected the job to be published with the first attempt: %+v, %v", job, err)
+	}
+	feed := insta.Account.Feed()
+	feed.Next()
+	posts := 0
+	for _, it := range feed.Items {
+		if it.Caption.Text == "configured" {
+			posts++
+		}
+	}
+	if posts != 1 {
+		t.Fatalf("Expected the post to be published once, got %d", posts)
+	}
+
+	// Errors which can not be retried are reported
+	job, err = p.Schedule("bob", time.Time{}, &goinsta.UploadOptions{File: bytes.NewReader(encodeJPEG(t, 500, 500))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+	case <-published:
+		t.Fatal("Expected the job of an unknown account to fail")
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timeout")
+	}
+	if job, err = p.Job(job.ID); err != nil || job.Status != goinsta.JobFailed {
+		t.Fatalf("Expected a failed job, got %+v, %v", job, err)
+	}
+
+	// Invalid uploads are not scheduled, pending jobs can be canceled
+	_, err = p.Schedule("alice", time.Now(), &goinsta.UploadOptions{File: bytes.NewReader(encodeJPEG(t, 1000, 400))})
+	if !errors.Is(err, goinsta.ErrAspectRatio) {
+		t.Fatalf("Expected ErrAspectRatio, got %v", err)
+	}
+	job, err = p.Schedule("alice", time.Now().Add(time.Hour), &goinsta.UploadOptions{File: bytes.NewReader(encodeJPEG(t, 500, 500))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Job(job.ID); err != goinsta.ErrJobNotFound {
+		t.Fatalf("Expected ErrJobNotFound, got %v", err)
+	}
+	jobs, err := p.Jobs()
+	if err != nil || len(jobs) != 4 {
+		t.Fatalf("Expected 4 jobs, got %d, %v", len(jobs), err)
+	}
+
+	cancel()
+	if err := <-stopped; err != context.Canceled {
+		t.Fatalf("Expected Run to stop with context.Canceled, got %v", err)
+	}
+
+	// The media is stored as raw files separately from the job, finished jobs
+	//   are deleted by default
+	p = goinsta.NewPublisher(store, accounts)
+	p.OnPublished = func(job *goinsta.PublishJob, item *goinsta.Item) { published <- item }
+	p.OnFailed = func(job *goinsta.PublishJob, err error) { failed <- err }
+	job, err = p.Schedule("alice", time.Now(), &goinsta.UploadOptions{File: bytes.NewReader(video)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, job.ID+".json"))
+	if err != nil || len(b) > 1024 {
+		t.Fatalf("Expected the job to be stored without its media, got %d bytes, %v", len(b), err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, job.ID+".media", "0"))
+	if err != nil || !bytes.Equal(b, video) {
+		t.Fatalf("Expected the video to be stored as is, got %d bytes, %v", len(b), err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() { stopped <- p.Run(ctx) }()
+	if item := awaitPublished(t, published, failed); !bytes.Equal(srv.UploadedVideo(item.ID), video) {
+		t.Fatal("Uploaded video differs from the stored one")
+	}
+	cancel()
+	<-stopped
+	if _, err := p.Job(job.ID); err != goinsta.ErrJobNotFound {
+		t.Fatalf("Expected the published job to be deleted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, job.ID+".media")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the media of the published job to be deleted, got %v", err)
+	}
+
+	// Jobs interrupted after configure has been sent are looked up by their
+	//   upload ID, instead of being published again
+	job, err = p.Schedule("alice", time.Now().Add(time.Hour), &goinsta.UploadOptions{File: bytes.NewReader(encodeJPEG(t, 500, 500))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Status = goinsta.JobRunning
+	job.UploadID = item.UploadID
+	if err := store.Save(job); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() { stopped <- p.Run(ctx) }()
+	if found := awaitPublished(t, published, failed); found.ID != item.ID {
+		t.Fatalf("Expected the interrupted job to be found as %s, got %s", item.ID, found.ID)
+	}
+	cancel()
+	<-stopped
+}
+
+func awaitPublished(t *testing.T, published chan *goinsta.Item, failed chan error) *goinsta.Item {
+	select {
+	case item := <-published:
+		return item
+	case err := <-failed:
+		t.Fatal(err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timeout")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+func TestRateLimiter(t *testing.T) {
+	sent := 0
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return jsonResponse(200, `{"status":"ok","friendship_status":{"following":true}}`), nil
+	})
+	insta.Account = &goinsta.Account{ID: 1}
+	insta.SetRateLimiter(goinsta.NewRateLimiter(map[goinsta.Action]goinsta.Budget{
+		goinsta.ActionFollow: {PerHour: 2, PerDay: 10},
+	}, false))
+
+	user := insta.NewUser()
+	user.ID = 2
+	for i := 0; i < 2; i++ {
+		if err := user.Follow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := user.Follow()
+	var budgetErr goinsta.ErrBudgetExceeded
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected budget exceeded error, got %v", err)
+	}
+	if budgetErr.Action != goinsta.ActionFollow || budgetErr.Wait <= 0 {
+		t.Fatalf("Unexpected budget error: %+v", budgetErr)
+	}
+	if sent != 2 {
+		t.Fatalf("Expected 2 requests to be sent, got %d", sent)
+	}
+
+	// Actions without a budget are not limited
+	if _, err := insta.Profiles.ByName("someone"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Budgets survive an export and import
+	buf := new(bytes.Buffer)
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := imported.RateLimiter()
+	if l == nil {
+		t.Fatal("Rate limiter has not been imported")
+	}
+	if r := l.Remaining(goinsta.ActionFollow); r != 0 {
+		t.Fatalf("Expected no remaining follows after import, got %d", r)
+	}
+	if r := l.Remaining(goinsta.ActionLike); r != -1 {
+		t.Fatalf("Expected unlimited likes, got %d", r)
+	}
+}
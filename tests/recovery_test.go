@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestPasswordReset(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "lost", Email: "alice@example.com"})
+	bob := srv.AddUser(goinstatest.User{Username: "bob", Password: "lost", PhoneNumber: "+12025550123"})
+
+	// Reset by email, with the link received
+	insta := srv.NewInstagram("", "")
+	reset, err := insta.RequestPasswordReset("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.Method != goinsta.PasswordResetEmail {
+		t.Fatalf("Expected reset by email, got %s", reset.Method)
+	}
+	if err := reset.SetPassword("new"); err == nil {
+		t.Fatal("Expected setting the password without link to fail")
+	}
+	if err := reset.SubmitLink(srv.PasswordResetLink(alice.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reset.SetPassword("new"); err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account == nil || insta.Account.ID != alice.ID {
+		t.Fatalf("Expected to be logged in as alice, got %+v", insta.Account)
+	}
+	if err := srv.NewInstagram("alice", "new").Login(); err != nil {
+		t.Fatalf("Failed to login with the new password: %s", err)
+	}
+
+	// Reset by SMS, with the code received
+	insta = srv.NewInstagram("", "")
+	reset, err = insta.RequestPasswordReset("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.Method != goinsta.PasswordResetSMS {
+		t.Fatalf("Expected reset by SMS, got %s", reset.Method)
+	}
+	if err := reset.SubmitCode("000000x"); err == nil {
+		t.Fatal("Expected a wrong code to be rejected")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := reset.SubmitCodeCtx(ctx, srv.RecoveryCode(bob.ID)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if err := reset.SubmitCode(srv.RecoveryCode(bob.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reset.SetPassword("new"); err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account == nil || insta.Account.Username != "bob" {
+		t.Fatalf("Expected to be logged in as bob, got %+v", insta.Account)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestRegister(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser(goinstatest.User{Username: "taken", Password: "secret"})
+
+	codes := func(ctx context.Context, contact string) (string, error) {
+		return srv.ConfirmationCode(contact), nil
+	}
+	birthday := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	// Register with an email address
+	insta := srv.NewInstagram("newbie", "s3cret!")
+	err := insta.Register(&goinsta.RegisterOptions{
+		Email:            "newbie@example.com",
+		FullName:         "New Bie",
+		Birthday:         birthday,
+		ConfirmationCode: codes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account == nil || insta.Account.Username != "newbie" {
+		t.Fatalf("Expected to be logged in as newbie, got %+v", insta.Account)
+	}
+
+	login := srv.NewInstagram("newbie", "s3cret!")
+	if err := login.Login(); err != nil {
+		t.Fatalf("Failed to login with the registered account: %s", err)
+	}
+
+	// Register with a phone number, and a suggested username
+	insta = srv.NewInstagram("", "s3cret!")
+	err = insta.Register(&goinsta.RegisterOptions{
+		PhoneNumber:      "+12025550123",
+		FullName:         "Phone User",
+		Birthday:         birthday,
+		ConfirmationCode: codes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account.Username != "phone.user" {
+		t.Fatalf("Expected the suggested username, got %s", insta.Account.Username)
+	}
+
+	// Taken usernames and users below 13 are rejected
+	insta = srv.NewInstagram("taken", "s3cret!")
+	err = insta.Register(&goinsta.RegisterOptions{
+		Email:            "other@example.com",
+		Birthday:         birthday,
+		ConfirmationCode: codes,
+	})
+	if err == nil {
+		t.Fatal("Expected registration with a taken username to fail")
+	}
+	insta = srv.NewInstagram("kid", "s3cret!")
+	err = insta.Register(&goinsta.RegisterOptions{
+		Email:            "kid@example.com",
+		Birthday:         time.Now().AddDate(-10, 0, 0),
+		ConfirmationCode: codes,
+	})
+	if err == nil {
+		t.Fatal("Expected registration of a 10 year old to fail")
+	}
+}
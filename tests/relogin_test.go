@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestAutoRelogin(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	srv.AddUser(goinstatest.User{Username: "bob", Password: "hunter2"})
+
+	insta := srv.NewInstagram("alice", "secret")
+	saved := 0
+	insta.SetAutoRelogin(&goinsta.ReloginOptions{
+		Save: func(insta *goinsta.Instagram) error {
+			saved++
+			return nil
+		},
+	})
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired session is renewed, and the request retried
+	srv.ExpireSessions(alice.ID)
+	if _, err := insta.Profiles.ByName("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if saved != 1 {
+		t.Fatalf("Expected the session to be saved once, got %d", saved)
+	}
+	if !insta.Health().OK() {
+		t.Fatalf("Expected health to be ok, got %s", insta.Health())
+	}
+
+	// Concurrent requests wait for the login in progress, and are retried
+	var logins int32
+	insta.SetAutoRelogin(&goinsta.ReloginOptions{
+		Credentials: func(ctx context.Context) (string, string, error) {
+			atomic.AddInt32(&logins, 1)
+			time.Sleep(50 * time.Millisecond)
+			return "alice", "secret", nil
+		},
+	})
+	srv.ExpireSessions(alice.ID)
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := insta.Profiles.ByName("bob")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 1 {
+		t.Fatalf("Expected one login for concurrent requests, got %d", logins)
+	}
+
+	// Requests of OpenApp after the login do not start another login
+	logins = 0
+	insta.SetAutoRelogin(&goinsta.ReloginOptions{
+		Credentials: func(ctx context.Context) (string, string, error) {
+			atomic.AddInt32(&logins, 1)
+			return "alice", "secret", nil
+		},
+		OpenApp: true,
+	})
+	insta.Use(goinsta.Middleware{
+		AfterResponse: func(info *goinsta.ResponseInfo) {
+			if info.Request.Endpoint == "multiple_accounts/get_account_family/" {
+				info.Err = goinsta.ErrLoginRequired
+			}
+		},
+	})
+	srv.ExpireSessions(alice.ID)
+	if _, err := insta.Profiles.ByName("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if logins != 1 {
+		t.Fatalf("Expected one login, got %d", logins)
+	}
+	insta.ClearMiddleware()
+
+	// A failing login is not retried over and over
+	calls := 0
+	insta.SetAutoRelogin(&goinsta.ReloginOptions{
+		Credentials: func(ctx context.Context) (string, string, error) {
+			calls++
+			return "alice", "wrong", nil
+		},
+	})
+	srv.ExpireSessions(alice.ID)
+	_, err := insta.Profiles.ByName("bob")
+	if !errors.Is(err, goinsta.ErrLoginRequired) {
+		t.Fatalf("Expected login required, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected one login attempt, got %d", calls)
+	}
+
+	// Without auto relogin the error is returned as is
+	insta.SetAutoRelogin(nil)
+	if _, err := insta.Profiles.ByName("bob"); !errors.Is(err, goinsta.ErrLoginRequired) {
+		t.Fatalf("Expected login required, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	stub := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := jsonResponse(200, `{"status":"ok","user":{"pk":1,"username":"someone"}}`)
+		resp.Header.Set("Ig-Set-Authorization", "Bearer IGT:2:c2VjcmV0")
+		resp.Header.Add("Set-Cookie", "sessionid=secret")
+		return resp, nil
+	})
+	rec, err := goinstatest.NewRecorder(path, stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insta := newStubInsta(nil)
+	insta.SetHTTPTransport(rec)
+	if _, err := insta.Profiles.ByName("someone"); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"username": {"someone"}, "enc_password": {"secret"}}
+	req, _ := http.NewRequest("POST", "https://i.instagram.com/api/v1/accounts/login/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", "sessionid=secret")
+	resp, err := (&http.Client{Transport: rec}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Credentials must not end up in the fixture
+	interactions, err := goinstatest.ReadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(interactions) != 2 {
+		t.Fatalf("Expected 2 recorded interactions, got %d", len(interactions))
+	}
+	login := interactions[1]
+	if login.Request.Form["enc_password"] != goinstatest.Redacted {
+		t.Errorf("Password has not been redacted: %v", login.Request.Form)
+	}
+	if login.Request.Form["username"] != "someone" {
+		t.Errorf("Username should be kept: %v", login.Request.Form)
+	}
+	for _, i := range interactions {
+		if v := i.Request.Header.Get("Cookie"); v != "" && v != goinstatest.Redacted {
+			t.Errorf("Cookie has not been redacted: %s", v)
+		}
+		if v := i.Response.Header.Get("Set-Cookie"); v != goinstatest.Redacted {
+			t.Errorf("Set-Cookie has not been redacted: %s", v)
+		}
+		if v := i.Response.Header.Get("Ig-Set-Authorization"); strings.Contains(v, "c2VjcmV0") {
+			t.Errorf("Authorization has not been redacted: %s", v)
+		}
+	}
+
+	// Replay the recording without a network connection
+	replayer, err := goinstatest.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer.Strict = true
+	insta = newStubInsta(nil)
+	insta.SetHTTPTransport(replayer)
+	user, err := insta.Profiles.ByName("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 {
+		t.Fatalf("Unexpected replayed user: %+v", user)
+	}
+	if _, err := insta.Profiles.ByName("someone"); err == nil {
+		t.Fatal("Expected an error, after all strict fixtures have been used")
+	}
+	if n := replayer.Unused(); n != 1 {
+		t.Fatalf("Expected 1 unused interaction, got %d", n)
+	}
+}
@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+)
+
+// roundTripFunc allows to use a simple function as http transport
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// jsonResponse creates a response, which also authorizes the session, so that
+//   stub sessions can be exported and imported like logged in ones.
+func jsonResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Status:     http.StatusText(code),
+		Header: http.Header{
+			"Content-Type":         []string{"application/json"},
+			"Ig-Set-Authorization": []string{"Bearer IGT:2:stub"},
+		},
+		Body: ioutil.NopCloser(bytes.NewBufferString(body)),
+	}
+}
+
+func newStubInsta(f roundTripFunc) *goinsta.Instagram {
+	insta := goinsta.New("stub_user", "stub_pass")
+	insta.SetInfoHandler(func(...interface{}) {})
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetHTTPTransport(f)
+	return insta
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Test") != "1" {
+			t.Errorf("Header set by middleware not found in request")
+		}
+		return jsonResponse(200, `{"status":"ok","user":{"pk":1,"username":"someone"}}`), nil
+	})
+
+	var calls []string
+	insta.Use(
+		goinsta.Middleware{
+			BeforeRequest: func(info *goinsta.RequestInfo) error {
+				calls = append(calls, "before1:"+info.Endpoint)
+				info.Header.Set("X-Test", "1")
+				return nil
+			},
+			AfterResponse: func(info *goinsta.ResponseInfo) {
+				calls = append(calls, "after1")
+			},
+		},
+		goinsta.Middleware{
+			BeforeRequest: func(info *goinsta.RequestInfo) error {
+				calls = append(calls, "before2")
+				return nil
+			},
+			AfterResponse: func(info *goinsta.ResponseInfo) {
+				if info.StatusCode != 200 || info.Err != nil {
+					t.Errorf("Unexpected response: %d, %v", info.StatusCode, info.Err)
+				}
+				calls = append(calls, "after2")
+			},
+		},
+	)
+
+	user, err := insta.Profiles.ByName("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "someone" {
+		t.Fatalf("Unexpected username %s", user.Username)
+	}
+
+	expected := []string{"before1:users/someone/usernameinfo/", "before2", "after2", "after1"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Expected calls %v, got %v", expected, calls)
+		}
+	}
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	sent := 0
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return jsonResponse(200, `{"status":"ok"}`), nil
+	})
+
+	errAbort := errors.New("aborted by middleware")
+	insta.Use(goinsta.Middleware{
+		BeforeRequest: func(info *goinsta.RequestInfo) error {
+			return errAbort
+		},
+		AfterResponse: func(info *goinsta.ResponseInfo) {
+			if info.Err != errAbort {
+				t.Errorf("Expected abort error in response info, got %v", info.Err)
+			}
+		},
+	})
+	if _, err := insta.Profiles.ByName("someone"); err != errAbort {
+		t.Fatalf("Expected abort error, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatal("Request has been sent, even though a middleware aborted it")
+	}
+
+	errInjected := errors.New("injected fault")
+	insta.ClearMiddleware()
+	insta.Use(goinsta.Middleware{
+		AfterResponse: func(info *goinsta.ResponseInfo) {
+			info.Err = errInjected
+		},
+	})
+	if _, err := insta.Profiles.ByName("someone"); err != errInjected {
+		t.Fatalf("Expected injected error, got %v", err)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	sent := 0
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		switch sent {
+		case 1:
+			return jsonResponse(503, `Service Unavailable`), nil
+		case 2:
+			resp := jsonResponse(429, `{"status":"fail"}`)
+			resp.Header.Set("Retry-After", "0")
+			return resp, nil
+		}
+		return jsonResponse(200, `{"status":"ok","user":{"pk":1,"username":"someone"}}`), nil
+	})
+
+	// Without a retry policy, the first error is returned
+	if _, err := insta.Profiles.ByName("someone"); err == nil {
+		t.Fatal("Expected an error without retry policy")
+	}
+
+	sent = 0
+	insta.SetRetryPolicy(&goinsta.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	})
+	if _, err := insta.Profiles.ByName("someone"); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", sent)
+	}
+	// Requests are not retried, if Retry-After exceeds MaxDelay
+	sent = 0
+	insta = newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		resp := jsonResponse(429, `{"status":"fail"}`)
+		resp.Header.Set("Retry-After", "60")
+		return resp, nil
+	})
+	insta.SetRetryPolicy(&goinsta.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	start := time.Now()
+	if _, err := insta.Profiles.ByName("someone"); !errors.Is(err, goinsta.ErrTooManyRequests) {
+		t.Fatalf("Expected ErrTooManyRequests, got %v", err)
+	}
+	if sent != 1 || time.Since(start) > time.Second {
+		t.Fatalf("Expected a single attempt without waiting, got %d in %s", sent, time.Since(start))
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	sent := 0
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return jsonResponse(500, `Internal Server Error`), nil
+	})
+	insta.Account = &goinsta.Account{ID: 1}
+	insta.SetRetryPolicy(&goinsta.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond})
+
+	user := insta.NewUser()
+	user.ID = 2
+	if err := user.Follow(); err == nil {
+		t.Fatal("Expected follow to fail")
+	}
+	if sent != 1 {
+		t.Fatalf("Follow should not be retried, but was sent %d times", sent)
+	}
+	// POST requests are not retried by default
+	sent = 0
+	if err := user.Block(false); err == nil {
+		t.Fatal("Expected block to fail")
+	}
+	if sent != 1 {
+		t.Fatalf("Block should not be retried, but was sent %d times", sent)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	insta := newStubInsta(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return jsonResponse(200, `{"status":"ok","users":[],"big_list":false}`), nil
+	})
+	insta.Account = &goinsta.Account{ID: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	users := insta.NewUser().Followers()
+	if users.NextCtx(ctx) {
+		t.Fatal("Expected NextCtx to fail with a canceled context")
+	}
+	if !errors.Is(users.Error(), context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", users.Error())
+	}
+	if err := insta.Inbox.SyncPendingCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from SyncPendingCtx, got %v", err)
+	}
+	if _, err := insta.SearchCtx(ctx, "goinsta"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from SearchCtx, got %v", err)
+	}
+	if err := insta.OpenAppCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from OpenAppCtx, got %v", err)
+	}
+
+	// A blocking rate limiter stops waiting once the context is done
+	insta.SetRateLimiter(goinsta.NewRateLimiter(map[goinsta.Action]goinsta.Budget{
+		goinsta.ActionRead: {PerHour: 1},
+	}, true))
+	users = insta.NewUser().Followers()
+	if !users.NextCtx(context.Background()) {
+		t.Fatal(users.Error())
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	users = insta.NewUser().Followers()
+	if users.NextCtx(ctx) {
+		t.Fatal("Expected NextCtx to fail, as rate limit budget has been used up")
+	}
+	if !errors.Is(users.Error(), context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", users.Error())
+	}
+}
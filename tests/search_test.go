@@ -2,14 +2,13 @@ package tests
 
 import (
 	"errors"
-	"math/rand"
 	"testing"
 
 	"github.com/UliSotschok/goinsta"
 )
 
 func TestSearchUser(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -26,7 +25,7 @@ func TestSearchUser(t *testing.T) {
 	t.Logf("Result length is %d", len(result.Users))
 
 	// Select a random user
-	user := result.Users[rand.Intn(len(result.Users))]
+	user := result.Users[testRand.Intn(len(result.Users))]
 	err = result.RegisterUserClick(user)
 	if err != nil {
 		t.Fatal(err)
@@ -51,7 +50,7 @@ func TestSearchUser(t *testing.T) {
 		t.Fatal("Failed to fetch any posts while the user does have posts")
 	} else if len(feed.Items) != 0 {
 		// Like a random post to make sure the insta pointer is set and working
-		post := feed.Items[rand.Intn(len(feed.Items))]
+		post := feed.Items[testRand.Intn(len(feed.Items))]
 		err := post.Like()
 		if err != nil {
 			t.Fatal(err)
@@ -60,7 +59,7 @@ func TestSearchUser(t *testing.T) {
 }
 
 func TestSearchHashtag(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -105,7 +104,7 @@ func TestSearchHashtag(t *testing.T) {
 }
 
 func TestSearchLocation(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
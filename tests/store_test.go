@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestSessionStore(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	srv.AddUser(goinstatest.User{Username: "bob", Password: "hunter2"})
+
+	// The session is saved automatically after login
+	store := goinsta.NewMemoryStore()
+	insta := srv.NewInstagram("alice", "secret")
+	insta.SetSessionStore(store, "")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	config, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ID != alice.ID || config.HeaderOptions["Authorization"] == "" {
+		t.Fatalf("Unexpected session stored: %+v", config)
+	}
+
+	// And can be imported again, also after it has been renewed
+	imported, err := goinsta.ImportFromStore(store, "alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported.SetHTTPTransport(srv.Transport())
+	imported.SetInfoHandler(func(...interface{}) {})
+	imported.SetWarnHandler(func(...interface{}) {})
+	if _, err := imported.Profiles.ByName("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	imported.SetAutoRelogin(&goinsta.ReloginOptions{Password: "secret"})
+	srv.ExpireSessions(alice.ID)
+	if _, err := imported.Profiles.ByName("bob"); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.HeaderOptions["Authorization"] == config.HeaderOptions["Authorization"] {
+		t.Fatal("Renewed session has not been saved")
+	}
+
+	// Logout removes the session
+	if err := imported.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("alice"); !errors.Is(err, goinsta.ErrSessionNotFound) {
+		t.Fatalf("Expected session to be deleted, got %v", err)
+	}
+
+	// File store
+	dir := t.TempDir()
+	files, err := goinsta.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := files.Save("alice", renewed); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "alice.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected session file to be private, got %s", info.Mode())
+	}
+	loaded, err := files.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.HeaderOptions["Authorization"] != renewed.HeaderOptions["Authorization"] {
+		t.Fatal("Loaded session differs from the saved one")
+	}
+	if err := files.Delete("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.Load("alice"); !errors.Is(err, goinsta.ErrSessionNotFound) {
+		t.Fatalf("Expected session to be deleted, got %v", err)
+	}
+	if err := files.Save("../alice", renewed); err == nil {
+		t.Fatal("Expected invalid key to be rejected")
+	}
+}
+
+func TestSessionStoreAutoSave(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	srv.AddUser(goinstatest.User{Username: "bob", Password: "hunter2"})
+
+	// Every response sets the csrf token cookie, like Instagram does
+	csrf := "first"
+	insta := srv.NewInstagram("alice", "secret")
+	insta.SetHTTPTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := srv.Transport().RoundTrip(req)
+		if err == nil {
+			resp.Header.Add("Set-Cookie", "csrftoken="+csrf+"; Path=/")
+		}
+		return resp, err
+	}))
+	store := &countingStore{MemoryStore: goinsta.NewMemoryStore()}
+	insta.SetSessionStore(store, "")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session is only saved, if the cookies change
+	saves := atomic.LoadInt32(&store.saves)
+	for i := 0; i < 3; i++ {
+		if _, err := insta.Profiles.ByName("bob"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&store.saves); n != saves {
+		t.Fatalf("Expected no saves for unchanged cookies, got %d", n-saves)
+	}
+	csrf = "second"
+	if _, err := insta.Profiles.ByName("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&store.saves); n != saves+1 {
+		t.Fatalf("Expected one save for the changed cookie, got %d", n-saves)
+	}
+}
+
+// countingStore counts the saved sessions
+type countingStore struct {
+	*goinsta.MemoryStore
+	saves int32
+}
+
+func (s *countingStore) Save(key string, config *goinsta.ConfigFile) error {
+	atomic.AddInt32(&s.saves, 1)
+	return s.MemoryStore.Save(key, config)
+}
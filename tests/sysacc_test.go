@@ -6,14 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
-	"net/http"
 	"os"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
 )
 
 var errNoAPIKEY = errors.New("No API Key has been found. Please add one to .env")
@@ -96,6 +94,10 @@ func Error(t *testing.T, err error) {
 }
 
 func getPixabayAPIKey() (string, error) {
+	if fixtureMode == "replay" {
+		return goinstatest.Redacted, nil
+	}
+
 	environ, err := loadEnv()
 	if err != nil {
 		return "", err
@@ -121,7 +123,7 @@ func getVideo(o ...map[string]interface{}) ([]byte, error) {
 	url := fmt.Sprintf("https://pixabay.com/api/videos/?key=%s&per_page=200", key)
 
 	// Get video list
-	resp, err := http.Get(url)
+	resp, err := mediaClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -150,8 +152,7 @@ func getVideo(o ...map[string]interface{}) ([]byte, error) {
 	valid := false
 	var vid video
 	for !valid {
-		rand.Seed(time.Now().UnixNano())
-		r := rand.Intn(len(res.Hits))
+		r := testRand.Intn(len(res.Hits))
 		vid = res.Hits[r].Videos.Small
 		if max_length == 0 || res.Hits[r].Duration < max_length {
 			valid = true
@@ -159,7 +160,7 @@ func getVideo(o ...map[string]interface{}) ([]byte, error) {
 	}
 
 	// Download video
-	resp, err = http.Get(vid.URL)
+	resp, err = mediaClient.Get(vid.URL)
 	if err != nil {
 		return nil, err
 	}
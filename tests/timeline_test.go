@@ -5,12 +5,10 @@ import (
 	"strconv"
 	"testing"
 	"time"
-
-	"github.com/UliSotschok/goinsta"
 )
 
 func TestTimeline(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -34,7 +32,7 @@ outside:
 }
 
 func TestDownload(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,8 +46,7 @@ func TestDownload(t *testing.T) {
 		t.Fatal("No posts found")
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	randN := rand.Intn(len(posts))
+	randN := testRand.Intn(len(posts))
 	post := posts[randN]
 
 	folder := "downloads/" + strconv.FormatInt(time.Now().Unix(), 10)
@@ -58,7 +55,7 @@ func TestDownload(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	randN = rand.Intn(len(posts))
+	randN = testRand.Intn(len(posts))
 	post = posts[randN]
 	err = post.Download(folder, "testy")
 	if err != nil {
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestTOTP(t *testing.T) {
+	// Test vectors from RFC 6238, truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // 12345678901234567890
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range vectors {
+		code, err := goinsta.GenerateTOTP(secret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("Expected code %s at %d, got %s", expected, ts, code)
+		}
+	}
+	if _, err := goinsta.GenerateTOTP("not base32!", time.Now()); err == nil {
+		t.Error("Expected invalid secret to be rejected")
+	}
+}
+
+func TestTOTPLogin(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	secret := "JBSWY3DPEHPK3PXP"
+	alice := srv.AddUser(goinstatest.User{
+		Username:   "alice",
+		Password:   "secret",
+		TOTPSecret: secret,
+	})
+
+	// Without a secret, the two factor info is returned
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err == nil {
+		t.Fatal("Expected login to require two factor authentication")
+	}
+	if insta.TwoFactorInfo == nil || !insta.TwoFactorInfo.TotpTwoFactorOn {
+		t.Fatalf("Expected two factor info, got %+v", insta.TwoFactorInfo)
+	}
+
+	// With a secret, the login is completed, even if the clocks differ
+	srv.ClockSkew = 30 * time.Second
+	insta = srv.NewInstagram("alice", "secret")
+	if err := insta.SetTOTPSecret(secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account.ID != alice.ID {
+		t.Fatalf("Logged in as %d, expected %d", insta.Account.ID, alice.ID)
+	}
+
+	// Without a key, the secret is not exported
+	buf := new(bytes.Buffer)
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "totp_secret") {
+		t.Fatal("TOTP secret has been exported without a key")
+	}
+
+	// The secret is exported encrypted, and can be used after import, even if
+	//   the device has been changed
+	insta.SetTOTPEncryptionKey([]byte("key"))
+	buf.Reset()
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), secret) {
+		t.Fatal("TOTP secret has been exported in plain text")
+	}
+	imported, err := goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported.SetHTTPTransport(srv.Transport())
+	imported.SetInfoHandler(func(...interface{}) {})
+	imported.SetWarnHandler(func(...interface{}) {})
+	imported.SetTOTPEncryptionKey([]byte("key"))
+	imported.SetDeviceID("android-0123456789abcdef")
+	imported.SetAutoRelogin(&goinsta.ReloginOptions{Password: "secret"})
+
+	srv.ClockSkew = 0
+	srv.ExpireSessions(alice.ID)
+	if _, err := imported.Profiles.ByName("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret can be encrypted with a key provider as well
+	kp := goinsta.NewPassphraseKeyProvider("passphrase", goinsta.MinPBKDF2Iterations)
+	imported.SetTOTPKeyProvider(kp)
+	buf.Reset()
+	if err := imported.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), secret) {
+		t.Fatal("TOTP secret has been exported in plain text")
+	}
+	imported, err = goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported.SetHTTPTransport(srv.Transport())
+	imported.SetInfoHandler(func(...interface{}) {})
+	imported.SetWarnHandler(func(...interface{}) {})
+	imported.SetAutoRelogin(&goinsta.ReloginOptions{Password: "secret"})
+
+	// Without the key provider, the secret cannot be decrypted
+	srv.ExpireSessions(alice.ID)
+	_, err = imported.Profiles.ByName("alice")
+	if err == nil || !strings.Contains(err.Error(), goinsta.ErrNoTOTPKey.Error()) {
+		t.Fatalf("Expected ErrNoTOTPKey, got %v", err)
+	}
+	imported.SetTOTPKeyProvider(kp)
+	if _, err := imported.Profiles.ByName("alice"); err != nil {
+		t.Fatal(err)
+	}
+}
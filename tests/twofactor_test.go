@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestTwoFactorSettings(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	alice := srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enable TOTP
+	seed, err := insta.Account.GenerateTOTPSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := insta.Account.EnableTOTP(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) == 0 {
+		t.Fatal("Expected backup codes")
+	}
+	info, err := insta.Account.SecurityInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsTotpTwoFactorEnabled {
+		t.Fatal("Expected TOTP to be enabled")
+	}
+
+	// Backup codes can be regenerated
+	regenerated, err := insta.Account.RegenerateBackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := insta.Account.BackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) == 0 || current[0] != regenerated[0] || current[0] == codes[0] {
+		t.Fatalf("Unexpected backup codes %v, regenerated %v", current, regenerated)
+	}
+
+	// New logins require the TOTP code, which is generated from the seed
+	srv.ExpireSessions(alice.ID)
+	other := srv.NewInstagram("alice", "secret")
+	if err := other.SetTOTPSecret(seed); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Disable TOTP
+	if err := other.Account.DisableTOTP(); err != nil {
+		t.Fatal(err)
+	}
+	info, err = other.Account.SecurityInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IsTwoFactorEnabled {
+		t.Fatal("Expected two factor authentication to be disabled")
+	}
+}
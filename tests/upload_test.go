@@ -5,14 +5,13 @@ import (
 	"errors"
 	"io"
 	"log"
-	"net/http"
 	"testing"
 
 	"github.com/UliSotschok/goinsta"
 )
 
 func TestUploadPhoto(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,7 +19,7 @@ func TestUploadPhoto(t *testing.T) {
 	insta.SetWarnHandler(t.Log)
 
 	// Get random photo
-	resp, err := http.Get("https://picsum.photos/1400/1400")
+	resp, err := mediaClient.Get("https://picsum.photos/1400/1400")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -57,7 +56,7 @@ func TestUploadPhoto(t *testing.T) {
 }
 
 func TestUploadThumbVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,7 +71,7 @@ func TestUploadThumbVideo(t *testing.T) {
 	size := float64(len(video)) / 1000000.0
 	t.Logf("Video size: %.2f Mb", size)
 
-	resp, err := http.Get("https://picsum.photos/1400/1400")
+	resp, err := mediaClient.Get("https://picsum.photos/1400/1400")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -111,7 +110,7 @@ func TestUploadThumbVideo(t *testing.T) {
 }
 
 func TestUploadVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -158,7 +157,7 @@ func TestUploadVideo(t *testing.T) {
 }
 
 func TestUploadStoryPhoto(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -166,7 +165,7 @@ func TestUploadStoryPhoto(t *testing.T) {
 	insta.SetWarnHandler(t.Log)
 
 	// Get random photo
-	resp, err := http.Get("https://picsum.photos/1400/1400")
+	resp, err := mediaClient.Get("https://picsum.photos/1400/1400")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -185,7 +184,7 @@ func TestUploadStoryPhoto(t *testing.T) {
 }
 
 func TestUploadStoryVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -213,7 +212,7 @@ func TestUploadStoryVideo(t *testing.T) {
 }
 
 func TestUploadStoryMultiVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -245,7 +244,7 @@ func TestUploadStoryMultiVideo(t *testing.T) {
 }
 
 func TestUploadCarousel(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -255,7 +254,7 @@ func TestUploadCarousel(t *testing.T) {
 	// Get random photos
 	album := []io.Reader{}
 	for i := 0; i < 5; i++ {
-		resp, err := http.Get("https://picsum.photos/1400/1400")
+		resp, err := mediaClient.Get("https://picsum.photos/1400/1400")
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -311,7 +310,7 @@ func TestUploadCarousel(t *testing.T) {
 }
 
 func TestUploadIGTV(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := envAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
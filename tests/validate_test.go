@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestUploadValidation(t *testing.T) {
+	square := encodeJPEG(t, 500, 500)
+	wide := encodeJPEG(t, 1000, 400)
+	video := testVideo{codec: "avc1", width: 640, height: 640, size: 4000}.encode()
+	mov := testVideo{codec: "avc1", width: 640, height: 640, size: 4000, brand: "qt  "}.encode()
+
+	cases := []struct {
+		name string
+		opts goinsta.UploadOptions
+		errs []goinsta.Violation
+	}{
+		{
+			name: "caption and tags",
+			opts: goinsta.UploadOptions{
+				File:     bytes.NewReader(square),
+				Caption:  strings.Repeat("#tag ", 31) + strings.Repeat("@user ", 21) + strings.Repeat("x", 2200),
+				UserTags: &[]goinsta.UserTag{{}},
+			},
+			errs: []goinsta.Violation{
+				{Field: "Caption", Err: goinsta.ErrCaptionTooLong},
+				{Field: "Caption", Err: goinsta.ErrTooManyHashtags},
+				{Field: "Caption", Err: goinsta.ErrTooManyMentions},
+				{Field: "UserTags", Err: goinsta.ErrInvalidUserTag},
+			},
+		},
+		{
+			name: "aspect ratio and format",
+			opts: goinsta.UploadOptions{Album: []io.Reader{bytes.NewReader(wide), strings.NewReader("text")}},
+			errs: []goinsta.Violation{
+				{Field: "Album[0]", Err: goinsta.ErrAspectRatio},
+				{Field: "Album[1]", Err: goinsta.ErrInvalidFormat},
+			},
+		},
+		{
+			name: "carousel with a single item",
+			opts: goinsta.UploadOptions{Album: []io.Reader{bytes.NewReader(square)}},
+			errs: []goinsta.Violation{{Field: "Album", Err: goinsta.ErrCarouselTooFewItems}},
+		},
+		{
+			name: "multiple stories with an image",
+			opts: goinsta.UploadOptions{IsStory: true, Album: []io.Reader{bytes.NewReader(video), bytes.NewReader(square)}},
+			errs: []goinsta.Violation{{Field: "Album[1]", Err: goinsta.ErrStoryBadMediaType}},
+		},
+		{
+			name: "short IGTV video",
+			opts: goinsta.UploadOptions{IsIGTV: true, Title: "title", File: bytes.NewReader(video)},
+			errs: []goinsta.Violation{{Field: "File", Err: goinsta.ErrVideoTooShort}},
+		},
+		{
+			name: "short IGTV QuickTime video",
+			opts: goinsta.UploadOptions{IsIGTV: true, Title: "title", File: bytes.NewReader(mov)},
+			errs: []goinsta.Violation{{Field: "File", Err: goinsta.ErrVideoTooShort}},
+		},
+		{
+			name: "nothing to upload",
+			errs: []goinsta.Violation{{Field: "File", Err: goinsta.ErrNoUploadFile}},
+		},
+	}
+	for _, c := range cases {
+		err := c.opts.Validate()
+		var verr *goinsta.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("%s: expected a ValidationError, got %v", c.name, err)
+		}
+		if len(verr.Violations) != len(c.errs) {
+			t.Fatalf("%s: expected %d violations, got %s", c.name, len(c.errs), err)
+		}
+		for _, want := range c.errs {
+			found := false
+			for _, v := range verr.Violations {
+				found = found || (v.Field == want.Field && errors.Is(v.Err, want.Err))
+			}
+			if !found || !errors.Is(err, want.Err) {
+				t.Fatalf("%s: expected %s, got %s", c.name, want, err)
+			}
+		}
+	}
+
+	// QuickTime videos are detected by their ftyp box
+	if err := (&goinsta.UploadOptions{File: bytes.NewReader(mov)}).Validate(); err != nil {
+		t.Fatalf("Expected a valid QuickTime video, got %v", err)
+	}
+
+	// Images are processed again, if Preprocess is set after a failed
+	//   validation, although the reader has been read already
+	opts := &goinsta.UploadOptions{File: bytes.NewBuffer(wide)}
+	if err := opts.Validate(); !errors.Is(err, goinsta.ErrAspectRatio) {
+		t.Fatalf("Expected ErrAspectRatio, got %v", err)
+	}
+	opts.Preprocess = &goinsta.ImageOptions{Fit: goinsta.FitCrop}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts.Preprocess = nil
+	if err := opts.Validate(); !errors.Is(err, goinsta.ErrAspectRatio) {
+		t.Fatalf("Expected ErrAspectRatio without preprocessing, got %v", err)
+	}
+
+	// Invalid uploads fail before any request is sent, even without login
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	_, err := insta.Upload(&goinsta.UploadOptions{File: bytes.NewReader(wide)})
+	if !errors.Is(err, goinsta.ErrAspectRatio) {
+		t.Fatalf("Expected ErrAspectRatio, got %v", err)
+	}
+
+	// Files are read once, when validated before the upload
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	opts = &goinsta.UploadOptions{File: bytes.NewBuffer(square), Caption: "#valid"}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := insta.Upload(opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UliSotschok/goinsta"
+	"github.com/UliSotschok/goinsta/goinstatest"
+)
+
+func TestVideoUploadResume(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(goinstatest.User{Username: "alice", Password: "secret"})
+	insta := srv.NewInstagram("alice", "secret")
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Small videos are streamed from files in a single segment
+	small := testVideo{codec: "avc1", width: 640, height: 360, size: 1 << 20}.encode()
+	path := filepath.Join(t.TempDir(), "small.mp4")
+	if err := ioutil.WriteFile(path, small, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	item, err := insta.Upload(&goinsta.UploadOptions{File: f, Caption: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(srv.UploadedVideo(item.ID), small) {
+		t.Fatal("Uploaded video differs from the file")
+	}
+
+	// Large videos are uploaded in segments, interrupted transfers are resumed
+	//   with the backoff of the retry policy
+	large := testVideo{codec: "avc1", width: 1080, height: 1350, size: 9 << 20}.encode()
+	r := &maxReaderAt{Reader: bytes.NewReader(large)}
+	var events []goinsta.UploadProgress
+	srv.InterruptUploads(2)
+	insta.SetRetryPolicy(&goinsta.RetryPolicy{MaxAttempts: 1, BaseDelay: 50 * time.Millisecond})
+	start := time.Now()
+	item, err = insta.Upload(&goinsta.UploadOptions{
+		File:     r,
+		Caption:  "large",
+		Progress: func(p goinsta.UploadProgress) { events = append(events, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("Expected the resumed transfers to back off, took %s", d)
+	}
+	if !bytes.Equal(srv.UploadedVideo(item.ID), large) {
+		t.Fatal("Uploaded video differs from the file")
+	}
+	if r.max >= len(large)/2 {
+		t.Fatalf("Expected the video to be read in segments, read %d bytes at once", r.max)
+	}
+
+	resumed := 0
+	var sent int64
+	for _, p := range events {
+		if p.Total != int64(len(large)) || p.Sent < sent {
+			t.Fatalf("Unexpected progress %+v after %d bytes", p, sent)
+		}
+		sent = p.Sent
+		if p.Phase == goinsta.UploadResuming {
+			resumed++
+			if p.Err == nil {
+				t.Fatalf("Expected the error which interrupted the transfer: %+v", p)
+			}
+		}
+	}
+	last := events[len(events)-1]
+	if resumed != 2 || last.Phase != goinsta.UploadFinished || last.Sent != last.Total ||
+		last.Segments < 2 || last.Segment != last.Segments {
+		t.Fatalf("Unexpected progress: %+v", events)
+	}
+
+	// Resuming can be disabled
+	srv.InterruptUploads(1)
+	_, err = insta.Upload(&goinsta.UploadOptions{
+		File:           bytes.NewReader(small),
+		ResumeAttempts: -1,
+	})
+	if err == nil {
+		t.Fatal("Expected the interrupted upload to fail")
+	}
+}
+
+// maxReaderAt records the largest read
+type maxReaderAt struct {
+	*bytes.Reader
+	max int
+}
+
+func (r *maxReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) > r.max {
+		r.max = len(b)
+	}
+	return r.Reader.ReadAt(b, off)
+}
@@ -2,6 +2,7 @@ package goinsta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"math/rand"
 	"time"
@@ -80,6 +81,13 @@ func newTimeline(insta *Instagram) *Timeline {
 // if Timeline.Error() is ErrNoMore no problem have been occurred.
 // starts first request will be a cold start
 func (tl *Timeline) Next(p ...interface{}) bool {
+	return tl.NextCtx(context.Background(), p...)
+}
+
+// NextCtx is like Next, but the request, and the delay between two requests,
+//   can be canceled with ctx. If canceled, Timeline.Error() returns the
+//   context error.
+func (tl *Timeline) NextCtx(ctx context.Context, p ...interface{}) bool {
 	if tl.err != nil {
 		return false
 	}
@@ -99,7 +107,10 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 
 	if delta := time.Now().Unix() - tl.lastRequest; delta < th {
 		s := time.Duration(rand.Float64()*thR + float64(th-delta))
-		time.Sleep(s * time.Second)
+		if err := sleep(ctx, s*time.Second); err != nil {
+			tl.err = err
+			return false
+		}
 	}
 	t := time.Now().Unix()
 
@@ -111,7 +122,7 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 		"device_id":           insta.uuid,
 		"request_id":          generateUUID(),
 		"_uuid":               insta.uuid,
-		"bloks_versioning_id": bloksVerID,
+		"bloks_versioning_id": insta.appVersion.BloksVersionID,
 	}
 
 	var tWarm int64 = 10
@@ -119,15 +130,15 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 		reason = "pull_to_refresh"
 		isPullToRefresh = "1"
 		tl.sessionID = generateUUID()
-		go tl.fetchTray("pull_to_refresh")
+		go tl.fetchTray(ctx, "pull_to_refresh")
 	} else if tl.lastRequest == 0 || (tl.fetchExtra && tl.prevReason == "warm_start_fetch") {
 		reason = "cold_start_fetch"
 		tl.sessionID = generateUUID()
-		go tl.fetchTray("cold_start")
+		go tl.fetchTray(ctx, "cold_start")
 	} else if t-tl.lastRequest > tWarm*60 { // 10 min
 		reason = "warm_start_fetch"
 		tl.sessionID = generateUUID()
-		go tl.fetchTray("warm_start_with_feed")
+		go tl.fetchTray(ctx, "warm_start_with_feed")
 	} else if tl.fetchExtra || tl.MoreAvailable && tl.NextID != "" {
 		reason = "pagination"
 		query["max_id"] = tl.NextID
@@ -140,10 +151,12 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint: endpoint,
-			IsPost:   true,
-			Gzip:     true,
-			Query:    query,
+			Endpoint:   endpoint,
+			IsPost:     true,
+			Idempotent: true,
+			Gzip:       true,
+			Query:      query,
+			Context:    ctx,
 			ExtraHeaders: map[string]string{
 				"X-Ads-Opt-Out":  "0",
 				"X-Google-AD-ID": insta.adid,
@@ -195,7 +208,7 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 			// fetch more posts if not enough posts were returned, mimick apk behvaior
 			if tmp.NumResults < tmp.PreloadDistance && tmp.MoreAvailable {
 				tl.fetchExtra = true
-				tl.Next()
+				tl.NextCtx(ctx)
 			}
 
 			// Check if stories returned an error
@@ -237,11 +250,13 @@ func (tl *Timeline) ClearPosts() {
 	tl.Tray = &Tray{}
 }
 
-func (tl *Timeline) fetchTray(reason string) {
+func (tl *Timeline) fetchTray(ctx context.Context, reason string) {
 	body, _, err := tl.insta.sendRequest(
 		&reqOptions{
-			Endpoint: urlStories,
-			IsPost:   true,
+			Endpoint:   urlStories,
+			IsPost:     true,
+			Idempotent: true,
+			Context:    ctx,
 			Query: map[string]string{
 				"supported_capabilities_new": `[{"name":"SUPPORTED_SDK_VERSIONS","value":"100.0,101.0,102.0,103.0,104.0,105.0,106.0,107.0,108.0,109.0,110.0,111.0,112.0,113.0,114.0,115.0,116.0,117.0"},{"name":"FACE_TRACKER_VERSION","value":"14"},{"name":"segmentation","value":"segmentation_enabled"},{"name":"COMPRESSION","value":"ETC2_COMPRESSION"},{"name":"world_tracker","value":"world_tracker_enabled"},{"name":"gyroscope","value":"gyroscope_enabled"}]`,
 				"reason":                     reason,
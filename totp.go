@@ -0,0 +1,257 @@
+package goinsta
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// ErrNoTOTPSecret is returned if a TOTP code is needed, but no secret has been
+//   set with Instagram.SetTOTPSecret
+var ErrNoTOTPSecret = errors.New("No TOTP secret has been set")
+
+// ErrNoTOTPKey is returned if an imported TOTP secret is needed, but no key to
+//   decrypt it has been set with Instagram.SetTOTPEncryptionKey or
+//   Instagram.SetTOTPKeyProvider.
+var ErrNoTOTPKey = errors.New("No key to decrypt the TOTP secret has been set")
+
+// GenerateTOTP generates the RFC 6238 time based one time password for the
+//   base32 encoded secret at time t, as shown by authenticator apps.
+func GenerateTOTP(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/totpPeriod))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0xf
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, code%1000000), nil
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	s = strings.TrimRight(s, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid TOTP secret: %s", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("Invalid TOTP secret: empty")
+	}
+	return key, nil
+}
+
+// SetTOTPSecret sets the base32 encoded secret of the authenticator app
+//   (TOTP) two factor authentication. If set, logins requiring two factor
+//   authentication are completed automatically with a generated code.
+//
+// The secret is only exported with the session, if a key to encrypt it has
+//   been set with SetTOTPEncryptionKey or SetTOTPKeyProvider. Otherwise it is
+//   left out of the export, and a warning is passed to the WarnHandler.
+func (insta *Instagram) SetTOTPSecret(secret string) error {
+	if _, err := decodeTOTPSecret(secret); err != nil {
+		return err
+	}
+	insta.totpSecret = secret
+	insta.totpSealed = ""
+	return nil
+}
+
+// SetTOTPEncryptionKey sets the key used to encrypt the TOTP secret on export,
+//   and to decrypt it after import. Set it right after Import, before the
+//   secret is needed.
+func (insta *Instagram) SetTOTPEncryptionKey(key []byte) {
+	insta.totpKey = key
+}
+
+// SetTOTPKeyProvider sets the key provider used to encrypt the TOTP secret on
+//   export, and to decrypt it after import, like the key provider of
+//   ExportEncrypted. It takes precedence over SetTOTPEncryptionKey. Set it
+//   right after Import, before the secret is needed.
+func (insta *Instagram) SetTOTPKeyProvider(kp KeyProvider) {
+	insta.totpKP = kp
+}
+
+// totp returns the TOTP secret, decrypting an imported one if needed
+func (insta *Instagram) totp() (string, error) {
+	if insta.totpSecret != "" {
+		return insta.totpSecret, nil
+	}
+	if insta.totpSealed == "" {
+		return "", ErrNoTOTPSecret
+	}
+
+	secret, err := insta.openTOTP(insta.totpSealed)
+	if err != nil {
+		return "", fmt.Errorf("Failed to decrypt TOTP secret: %w", err)
+	}
+	insta.totpSecret = string(secret)
+	return insta.totpSecret, nil
+}
+
+// openTOTP decrypts a sealed TOTP secret. Secrets sealed with a key provider
+//   are stored as JSON envelope, secrets sealed with a key as base64.
+func (insta *Instagram) openTOTP(sealed string) ([]byte, error) {
+	if strings.HasPrefix(sealed, "{") {
+		if insta.totpKP == nil {
+			return nil, ErrNoTOTPKey
+		}
+		env := envelope{}
+		if err := json.Unmarshal([]byte(sealed), &env); err != nil {
+			return nil, err
+		}
+		return env.open(insta.totpKP)
+	}
+	if insta.totpKey == nil {
+		return nil, ErrNoTOTPKey
+	}
+
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := insta.totpCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("Encrypted TOTP secret too short")
+	}
+	return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+}
+
+// sealTOTP returns the encrypted TOTP secret for the config file. Without a
+//   key, the secret is not exported, which is only warned about once.
+func (insta *Instagram) sealTOTP() (string, error) {
+	if insta.totpSecret == "" {
+		return insta.totpSealed, nil
+	}
+	if insta.totpKP != nil {
+		env, err := sealEnvelope([]byte(insta.totpSecret), insta.totpKP)
+		if err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(env)
+		return string(b), err
+	}
+	if insta.totpKey == nil {
+		if !insta.totpWarned {
+			insta.WarnHandler("TOTP secret has not been exported, as no key to encrypt it has been set")
+			insta.totpWarned = true
+		}
+		return "", nil
+	}
+
+	gcm, err := insta.totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	b := gcm.Seal(nonce, nonce, []byte(insta.totpSecret), nil)
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func (insta *Instagram) totpCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256(insta.totpKey)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loginTOTP completes a two factor login with a generated code. As the clock
+//   of Instagram and the local one can differ, the codes of the previous and
+//   the next period are tried as well, if the current one is rejected.
+func (info *TwoFactorInfo) loginTOTP(ctx context.Context) error {
+	insta := info.insta
+	secret, err := insta.totp()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, skew := range []time.Duration{0, -totpPeriod * time.Second, totpPeriod * time.Second} {
+		var code string
+		code, err = GenerateTOTP(secret, now.Add(skew))
+		if err != nil {
+			return err
+		}
+		err = info.login2FA(ctx, code, "3")
+		if !isInvalidCode(err) {
+			return err
+		}
+		insta.WarnHandler("TOTP code has been rejected, trying again with clock skew")
+	}
+	return err
+}
+
+// isInvalidCode reports whether a two factor login failed due to a wrong code
+func isInvalidCode(err error) bool {
+	var ierr Error400
+	if !errors.As(err, &ierr) {
+		return false
+	}
+	return ierr.ErrorType == "sms_code_validation_code_invalid" ||
+		ierr.ErrorType == "invalid_verification_code"
+}
+
+func (info *TwoFactorInfo) login2FA(ctx context.Context, code, method string) error {
+	insta := info.insta
+	data, err := json.Marshal(
+		map[string]string{
+			"verification_code":     code,
+			"phone_id":              insta.fID,
+			"two_factor_identifier": info.TwoFactorIdentifier,
+			"username":              insta.user,
+			"trust_this_device":     "1",
+			"guid":                  insta.uuid,
+			"device_id":             insta.dID,
+			"waterfall_id":          generateUUID(),
+			"verification_method":   method,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: url2FALogin,
+			IsPost:   true,
+			Query:    generateSignature(data),
+			Context:  ctx,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return insta.verifyLogin(body)
+}
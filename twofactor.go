@@ -0,0 +1,198 @@
+package goinsta
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SecurityInfo describes the two factor authentication setup of an account
+type SecurityInfo struct {
+	IsPhoneConfirmed           bool            `json:"is_phone_confirmed"`
+	IsTwoFactorEnabled         bool            `json:"is_two_factor_enabled"`
+	IsTotpTwoFactorEnabled     bool            `json:"is_totp_two_factor_enabled"`
+	IsWhatsappTwoFactorEnabled bool            `json:"is_whatsapp_two_factor_enabled"`
+	PhoneNumber                string          `json:"phone_number"`
+	CountryCode                int             `json:"country_code"`
+	NationalNumber             int64           `json:"national_number"`
+	BackupCodes                []string        `json:"backup_codes"`
+	TrustedDevices             []TrustedDevice `json:"trusted_devices"`
+	Status                     string          `json:"status"`
+}
+
+// TrustedDevice is a device, which can login without two factor
+//   authentication
+type TrustedDevice struct {
+	DeviceGUID    string  `json:"device_guid"`
+	DeviceName    string  `json:"device_name"`
+	DeviceType    string  `json:"device_type"`
+	LastLoginTime int64   `json:"last_login_time"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+}
+
+// LastLogin returns the time of the last login with the device
+func (d TrustedDevice) LastLogin() time.Time {
+	return time.Unix(d.LastLoginTime, 0)
+}
+
+type backupCodesResp struct {
+	BackupCodes []string `json:"backup_codes"`
+	Status      string   `json:"status"`
+}
+
+// SecurityInfo fetches the two factor authentication setup of the account,
+//   including the backup codes and trusted devices.
+func (account *Account) SecurityInfo() (*SecurityInfo, error) {
+	info := &SecurityInfo{}
+	err := account.securityRequest(url2FASecurityInfo, nil, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GenerateTOTPSeed requests a new TOTP seed, which has to be confirmed with
+//   EnableTOTP to enable authenticator app two factor authentication.
+func (account *Account) GenerateTOTPSeed() (string, error) {
+	resp := struct {
+		TotpSeed string `json:"totp_seed"`
+		Status   string `json:"status"`
+	}{}
+	err := account.securityRequest(url2FAGenerateTOTPKey, nil, &resp)
+	return resp.TotpSeed, err
+}
+
+// EnableTOTP enables authenticator app (TOTP) two factor authentication, by
+//   confirming the seed from GenerateTOTPSeed with a generated code. The seed
+//   is set as TOTP secret of the session, see Instagram.SetTOTPSecret, so that
+//   future logins are completed automatically.
+//
+// The backup codes are returned, store them in a safe place.
+func (account *Account) EnableTOTP(seed string) ([]string, error) {
+	code, err := GenerateTOTP(seed, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := backupCodesResp{}
+	err = account.securityRequest(
+		url2FAEnableTOTP,
+		map[string]string{"verification_code": code},
+		&resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := account.insta.SetTOTPSecret(seed); err != nil {
+		return nil, err
+	}
+	return resp.BackupCodes, nil
+}
+
+// DisableTOTP disables authenticator app two factor authentication
+func (account *Account) DisableTOTP() error {
+	err := account.securityRequest(url2FADisableTOTP, nil, nil)
+	if err == nil {
+		account.insta.totpSecret = ""
+		account.insta.totpSealed = ""
+	}
+	return err
+}
+
+// SendTwoFactorEnableSMS sends a confirmation code to the phone number, which
+//   is needed for EnableSMSTwoFactor.
+func (account *Account) SendTwoFactorEnableSMS(phone string) error {
+	return account.securityRequest(
+		url2FASendEnableSMS,
+		map[string]string{"phone_number": phone},
+		nil,
+	)
+}
+
+// EnableSMSTwoFactor enables SMS two factor authentication, with the code
+//   sent by SendTwoFactorEnableSMS. The backup codes are returned.
+func (account *Account) EnableSMSTwoFactor(phone, code string) ([]string, error) {
+	resp := backupCodesResp{}
+	err := account.securityRequest(
+		url2FAEnableSMS,
+		map[string]string{
+			"phone_number":      phone,
+			"verification_code": code,
+		},
+		&resp,
+	)
+	return resp.BackupCodes, err
+}
+
+// DisableSMSTwoFactor disables SMS two factor authentication
+func (account *Account) DisableSMSTwoFactor() error {
+	return account.securityRequest(url2FADisableSMS, nil, nil)
+}
+
+// BackupCodes returns the current two factor backup codes
+func (account *Account) BackupCodes() ([]string, error) {
+	info, err := account.SecurityInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.BackupCodes, nil
+}
+
+// RegenerateBackupCodes invalidates the current backup codes, and returns new
+//   ones.
+func (account *Account) RegenerateBackupCodes() ([]string, error) {
+	resp := backupCodesResp{}
+	err := account.securityRequest(url2FARegenBackupCodes, nil, &resp)
+	return resp.BackupCodes, err
+}
+
+// TrustedDevices returns the devices, which can login without two factor
+//   authentication
+func (account *Account) TrustedDevices() ([]TrustedDevice, error) {
+	info, err := account.SecurityInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.TrustedDevices, nil
+}
+
+// RevokeTrustedDevice removes a device from the trusted devices, so that
+//   logins from it require two factor authentication again.
+func (account *Account) RevokeTrustedDevice(guid string) error {
+	return account.securityRequest(
+		url2FARemoveTrustedDevice,
+		map[string]string{"device_guid": guid},
+		nil,
+	)
+}
+
+// securityRequest sends a signed account security request, and decodes the
+//   response into resp, if not nil.
+func (account *Account) securityRequest(endpoint string, extra map[string]string, resp interface{}) error {
+	insta := account.insta
+	query := map[string]string{
+		"_uid":      toString(account.ID),
+		"_uuid":     insta.uuid,
+		"device_id": insta.dID,
+	}
+	for k, v := range extra {
+		query[k] = v
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: endpoint,
+			Query:    generateSignature(data),
+			IsPost:   true,
+			NoRetry:  true,
+		},
+	)
+	if err != nil || resp == nil {
+		return err
+	}
+	return json.Unmarshal(body, resp)
+}
@@ -2,12 +2,13 @@ package goinsta
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
 // ConfigFile is a structure to store the session information so that can be exported or imported.
 type ConfigFile struct {
+	// Version is the schema version, see ConfigVersion
+	Version       int               `json:"version"`
 	ID            int64             `json:"id"`
 	User          string            `json:"username"`
 	DeviceID      string            `json:"device_id"`
@@ -16,11 +17,29 @@ type ConfigFile struct {
 	RankToken     string            `json:"rank_token"`
 	Token         string            `json:"token"`
 	PhoneID       string            `json:"phone_id"`
+	AdID          string            `json:"ad_id"`
 	XmidExpiry    int64             `json:"xmid_expiry"`
 	HeaderOptions map[string]string `json:"header_options"`
 	Cookies       []*http.Cookie    `json:"cookies"`
 	Account       *Account          `json:"account"`
 	Device        Device            `json:"device"`
+	AppVersion    AppVersion        `json:"app_version"`
+	RateLimits    *RateLimiter      `json:"rate_limits,omitempty"`
+	Health        Health            `json:"health"`
+	// TOTPSecret is the encrypted TOTP secret, see Instagram.SetTOTPSecret
+	TOTPSecret string `json:"totp_secret,omitempty"`
+	// Challenge is the challenge, which is being solved
+	Challenge    *Challenge `json:"challenge,omitempty"`
+	ChallengeURL string     `json:"challenge_url,omitempty"`
+}
+
+// AppVersion is a version of the Instagram Android app, which is sent in the
+//   user agent and request headers.
+type AppVersion struct {
+	Version        string `json:"version"`
+	Code           string `json:"code"`
+	BloksVersionID string `json:"bloks_version_id"`
+	Capabilities   string `json:"capabilities"`
 }
 
 type Device struct {
@@ -44,90 +63,6 @@ type PicURLInfo struct {
 	Width  int    `json:"width"`
 }
 
-// ErrorN is general instagram error
-type ErrorN struct {
-	Message   string `json:"message"`
-	Endpoint  string `json:"endpoint"`
-	Status    string `json:"status"`
-	ErrorType string `json:"error_type"`
-}
-
-// Error503 is instagram API error
-type Error503 struct {
-	Message string
-}
-
-func (e Error503) Error() string {
-	return e.Message
-}
-
-func (e ErrorN) Error() string {
-	return fmt.Sprintf(
-		"Error while calling %s, status code %s: %s (%s)",
-		e.Endpoint, e.Status, e.Message, e.ErrorType,
-	)
-}
-
-// Error400 is error returned by HTTP 400 status code.
-type Error400 struct {
-	ChallengeError
-	Endpoint   string `json:"endpoint"`
-	Action     string `json:"action"`
-	StatusCode string `json:"status_code"`
-	Payload    struct {
-		ClientContext string `json:"client_context"`
-		Message       string `json:"message"`
-	} `json:"payload"`
-	DebugInfo struct {
-		Message   string `json:"string"`
-		Retriable bool   `json:"retriable"`
-		Type      string `json:"type"`
-	} `json:"debug_info"`
-	Code   int
-	Status string `json:"status"`
-}
-
-func (e Error400) Error() string {
-	var msg string
-	if e.Payload.Message != "" {
-		msg = e.Payload.Message
-	}
-	if e.DebugInfo.Message != "" {
-		msg = e.DebugInfo.Message
-	}
-	if e.ChallengeError.Message != "" {
-		if msg != "" {
-			msg += "; " + e.ChallengeError.Message
-		} else {
-			msg = e.ChallengeError.Message
-		}
-	}
-
-	if e.Code == 0 {
-		e.Code = 400
-	}
-	return fmt.Sprintf("Request Status Code %d: %s, %s", e.Code, e.Status, msg)
-}
-
-// ChallengeError is error returned by HTTP 400 status code.
-type ChallengeError struct {
-	Message   string `json:"message"`
-	Challenge struct {
-		URL               string `json:"url"`
-		APIPath           string `json:"api_path"`
-		HideWebviewHeader bool   `json:"hide_webview_header"`
-		Lock              bool   `json:"lock"`
-		Logout            bool   `json:"logout"`
-		NativeFlow        bool   `json:"native_flow"`
-	} `json:"challenge"`
-	Status    string `json:"status"`
-	ErrorType string `json:"error_type"`
-}
-
-func (e ChallengeError) Error() string {
-	return fmt.Sprintf("Challenge Required: %s, %s", e.Status, e.Message)
-}
-
 // Nametag is part of the account information.
 type Nametag struct {
 	Mode          int64       `json:"mode"`
@@ -2,13 +2,16 @@ package goinsta
 
 import (
 	"bytes"
+	"context"
 	cryptRand "crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -16,8 +19,11 @@ import (
 
 type UploadOptions struct {
 	insta *Instagram
+	ctx   context.Context
 
-	// File to upload, can be one of jpeg, jpg, mp4
+	// File to upload, can be one of jpeg, jpg, mp4. If File implements
+	//   io.ReaderAt and its size is known, e.g. *os.File or *bytes.Reader,
+	//   videos are streamed from it instead of being read into memory.
 	File io.Reader
 
 	// Thumbnail to use for videos, one of jpeg or jpg. If not set a thumbnail
@@ -33,6 +39,21 @@ type UploadOptions struct {
 	// Set to true if you want to post a story
 	IsStory bool
 
+	// Preprocess images before uploading, to fit them into the aspect ratio
+	//   and size accepted by Instagram. Images in other formats than jpeg are
+	//   converted. See ImageOptions for more details.
+	Preprocess *ImageOptions
+
+	// Progress is called while videos are uploaded, see UploadProgress
+	Progress func(UploadProgress)
+
+	// ResumeAttempts is how often an interrupted video transfer is resumed
+	//   from the last offset acknowledged by Instagram, 3 by default. Set it
+	//   to -1 to fail on the first error. Between two attempts goinsta waits
+	//   with the backoff of the retry policy (see SetRetryPolicy), or of
+	//   DefaultRetryPolicy if none is set.
+	ResumeAttempts int
+
 	// IGTV settings
 	IsIGTV      bool
 	Title       string
@@ -69,15 +90,35 @@ type UploadOptions struct {
 	startTime      string
 	videoGroupID   string // used for story multi-video upload
 	index          int    // used for story multi-video upload
-	offset         int
+	offset         int64
+	segment        int
+	segments       int
 	segmentType    int
 	isSidecar      bool
 	useXSharingIDs bool
 	isThumbnail    bool
 
-	// File buf
-	buf      *bytes.Buffer
-	bufAlbum []*bytes.Buffer
+	// Retries of configure, the wait is set by Publisher. configured is the
+	//   upload ID of the last configure request which has been sent.
+	//   onConfigure is called before it is sent, Publisher stores the upload
+	//   ID with it.
+	transcodeWait  time.Duration
+	transcodeWaits int
+	reuploaded     bool
+	configured     string
+	onConfigure    func(uploadID string) error
+
+	// File buf. sources are the files as read, files are the files as
+	//   processed with the loaded options.
+	buf     *bytes.Buffer
+	sources []*uploadFile
+	files   []*uploadFile
+	loaded  loadOptions
+
+	// Video source, streamed in segments
+	src   io.ReaderAt
+	size  int64
+	video *VideoInfo
 
 	// Formatted UserTags
 	userTags *postTags
@@ -101,6 +142,36 @@ type postTagUser struct {
 	Position [2]json.Number `json:"position"`
 }
 
+// UploadPhase is the step of a video upload, see UploadProgress
+type UploadPhase string
+
+// Phases of a video upload
+const (
+	// UploadStarted is reported before the first byte is sent
+	UploadStarted UploadPhase = "started"
+	// UploadTransferring is reported after every acknowledged segment
+	UploadTransferring UploadPhase = "transferring"
+	// UploadResuming is reported when an interrupted transfer is resumed
+	UploadResuming UploadPhase = "resuming"
+	// UploadFinished is reported once all bytes have been uploaded
+	UploadFinished UploadPhase = "finished"
+)
+
+// UploadProgress is passed to UploadOptions.Progress. Videos up to 8 MB are
+//   uploaded in a single segment.
+type UploadProgress struct {
+	Phase UploadPhase
+	// Sent is the number of bytes acknowledged by Instagram
+	Sent  int64
+	Total int64
+	// Segment is the segment being uploaded, starting at 1
+	Segment  int
+	Segments int
+	// Err is the error which interrupted the transfer, if Phase is
+	//   UploadResuming
+	Err error
+}
+
 // LocationTag represents a post location tag
 type LocationTag struct {
 	Name           string  `json:"name"`
@@ -115,12 +186,27 @@ type LocationTag struct {
 // You can specify the options of your upload with the single parameter &UploadOptions{}
 // See the UploadOptions struct for more details.
 //
+// The options are validated before any byte is sent, see
+//   UploadOptions.Validate.
+//
 func (insta *Instagram) Upload(o *UploadOptions) (*Item, error) {
+	return insta.UploadCtx(context.Background(), o)
+}
+
+// UploadCtx is like Upload, but the upload can be canceled with ctx.
+func (insta *Instagram) UploadCtx(ctx context.Context, o *UploadOptions) (*Item, error) {
 	o.insta = insta
+	o.ctx = ctx
 	o.startTime = toString(time.Now().Unix())
 
+	// Read and validate the files
+	err := o.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	// Format User & Location Tags
-	err := o.processTags()
+	err = o.processTags()
 	if err != nil {
 		return nil, err
 	}
@@ -135,31 +221,19 @@ func (insta *Instagram) Upload(o *UploadOptions) (*Item, error) {
 	}
 
 	// Single file uploads
-	// Read file into memory
-	buf, err := readFile(o.File)
-	if err != nil {
-		return nil, err
-	}
-	o.buf = buf
-
-	// Check file type
-	t := http.DetectContentType(buf.Bytes())
-	if t == "image/jpeg" {
-		err := o.uploadPhoto()
-		if err != nil {
-			return nil, err
-		}
-		return o.configureImage()
-	} else if t == "video/mp4" {
+	o.use(o.files[0])
+	if o.video != nil {
 		err := o.uploadVideo()
 		if err != nil {
 			return nil, err
 		}
 		return o.configureVideo()
 	}
-
-	insta.InfoHandler(fmt.Errorf("Unable to handle file upload with format %s", t))
-	return nil, ErrInvalidFormat
+	err = o.uploadPhoto()
+	if err != nil {
+		return nil, err
+	}
+	return o.configureImage()
 }
 
 func formatUserTags(tags []UserTag, isVideo bool) *postTags {
@@ -370,25 +444,44 @@ func (o *UploadOptions) postThumbnail() error {
 	return nil
 }
 
-func (o *UploadOptions) postVideo() error {
+// postVideo uploads the bytes of the entity o.name from offset on. The entity
+//   consists of length bytes of the video from start.
+func (o *UploadOptions) postVideo(start, length, offset int64) error {
 	insta := o.insta
 
-	// Upload video bytes
+	chunk := make([]byte, length-offset)
+	if n, err := o.src.ReadAt(chunk, start+offset); n < len(chunk) {
+		return err
+	}
+
+	headers := map[string]string{
+		"X-Entity-Name":              o.name,
+		"X-Entity-Type":              "video/mp4",
+		"X-Entity-Length":            toString(length),
+		"X-Instagram-Rupload-Params": o.ruploadParams,
+		"Offset":                     toString(offset),
+		"Content-type":               "application/octet-stream",
+		"X_fb_photo_waterfall_id":    o.waterfallID,
+	}
+	if o.segmentType != 0 {
+		headers["Segment-Start-Offset"] = toString(start)
+		headers["Segment-Type"] = toString(o.segmentType)
+	}
+	if o.streamID != "" {
+		headers["Stream-Id"] = o.streamID
+	}
+
+	// Upload video bytes. Failed transfers are resumed by uploadEntity, as
+	//   sending the bytes again fails if a part has been received.
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint:  fmt.Sprintf(urlUploadVideo, o.name),
-			OmitAPI:   true,
-			IsPost:    true,
-			DataBytes: o.buf,
-			ExtraHeaders: map[string]string{
-				"X-Entity-Name":              o.name,
-				"X-Entity-Type":              http.DetectContentType(o.buf.Bytes()),
-				"X-Entity-Length":            toString(o.buf.Len()),
-				"X-Instagram-Rupload-Params": o.ruploadParams,
-				"Offset":                     "0",
-				"Content-type":               "application/octet-stream",
-				"X_fb_photo_waterfall_id":    o.waterfallID,
-			},
+			Endpoint:     fmt.Sprintf(urlUploadVideo, o.name),
+			OmitAPI:      true,
+			IsPost:       true,
+			NoRetry:      true,
+			DataBytes:    bytes.NewBuffer(chunk),
+			ExtraHeaders: headers,
+			Context:      o.ctx,
 		},
 	)
 	if err != nil {
@@ -412,8 +505,10 @@ func (o *UploadOptions) postVideo() error {
 }
 
 // postVideoGET - every video upload is a sequence of a get request, followed
-//   by a post request to upload the bytes.
-func (o *UploadOptions) postVideoGET() error {
+//   by a post request to upload the bytes. The get request returns the offset
+//   of the bytes already received, which is 0 unless a transfer of the entity
+//   has been interrupted.
+func (o *UploadOptions) postVideoGET() (int64, error) {
 	insta := o.insta
 
 	headers := map[string]string{
@@ -426,14 +521,25 @@ func (o *UploadOptions) postVideoGET() error {
 		// Segment type = 1 for last segment, 2 for all others
 		headers["Segment-Type"] = toString(o.segmentType)
 	}
-	_, _, err := insta.sendRequest(
+	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint:     fmt.Sprintf(urlUploadVideo, o.name),
 			OmitAPI:      true,
 			ExtraHeaders: headers,
+			Context:      o.ctx,
 		},
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+
+	var res struct {
+		Offset int64 `json:"offset"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, err
+	}
+	return res.Offset, nil
 }
 
 func (o *UploadOptions) postPhoto() error {
@@ -442,10 +548,11 @@ func (o *UploadOptions) postPhoto() error {
 	// Upload Photo
 	body, _, err := insta.sendRequest(
 		&reqOptions{
-			Endpoint:  fmt.Sprintf(urlUploadPhoto, o.name),
-			OmitAPI:   true,
-			IsPost:    true,
-			DataBytes: o.buf,
+			Endpoint:   fmt.Sprintf(urlUploadPhoto, o.name),
+			OmitAPI:    true,
+			IsPost:     true,
+			Idempotent: true,
+			DataBytes:  o.buf,
 			ExtraHeaders: map[string]string{
 				"X-Entity-Name":              o.name,
 				"X-Entity-Type":              http.DetectContentType(o.buf.Bytes()),
@@ -455,6 +562,7 @@ func (o *UploadOptions) postPhoto() error {
 				"Content-type":               "application/octet-stream",
 				"X_fb_photo_waterfall_id":    o.waterfallID,
 			},
+			Context: o.ctx,
 		},
 	)
 
@@ -626,7 +734,7 @@ func (o *UploadOptions) createVideoConfig() error {
 			"source_width":  o.width,
 			"source_height": o.height,
 		},
-		"audio_muted":        o.MuteAudio,
+		"audio_muted":        o.MuteAudio || !o.video.HasAudio,
 		"poster_frame_index": 0, // TODO: look into this (testing to see if it matters which index is used)
 	}
 
@@ -700,27 +808,10 @@ func (o *UploadOptions) uploadAlbum() (*Item, error) {
 	o.isSidecar = true
 	o.waterfallID = generateUUID()
 
-	if len(o.Album) > 10 {
-		return nil, ErrCarouselMediaLimit
-	}
-
 	// Upload photos one by one
 	var metadata []map[string]interface{}
-	for index, media := range o.Album {
-
-		// Read media into memory
-		buf, err := readFile(media)
-		if err != nil {
-			return nil, err
-		}
-		o.buf = buf
-
-		// Validate file type
-		t := http.DetectContentType(buf.Bytes())
-		if !(t == "image/jpeg" || t == "video/mp4") {
-			insta.InfoHandler(fmt.Errorf("Unable to handle file upload with format %s", t))
-			return nil, ErrInvalidFormat
-		}
+	for index, f := range o.files {
+		o.use(f)
 
 		// Use album tags if available
 		if o.UserTags == nil && o.AlbumTags != nil && len(*o.AlbumTags) == len(o.Album) {
@@ -731,7 +822,7 @@ func (o *UploadOptions) uploadAlbum() (*Item, error) {
 		}
 
 		// Upload Media
-		if t == "image/jpeg" {
+		if o.video == nil {
 			// Create upload id & name
 			o.newUploadID()
 			rand := random(1000000000, 9999999999)
@@ -741,7 +832,7 @@ func (o *UploadOptions) uploadAlbum() (*Item, error) {
 			if err != nil {
 				return nil, err
 			}
-		} else if t == "video/mp4" {
+		} else {
 			err := o.uploadVideo()
 			if err != nil {
 				return nil, err
@@ -788,17 +879,29 @@ func (o *UploadOptions) configure() (*Item, error) {
 		return nil, err
 	}
 
+	if o.onConfigure != nil {
+		if err := o.onConfigure(o.uploadID); err != nil {
+			return nil, err
+		}
+	}
+	o.configured = o.uploadID
 	body, _, err := insta.sendRequest(
 		&reqOptions{
 			Endpoint: o.configURL,
 			IsPost:   true,
 			Query:    generateSignature(data),
+			NoRetry:  true,
 			ExtraHeaders: map[string]string{
 				"Retry_context": `{"num_reupload":0,"num_step_auto_retry":0,"num_step_manual_retry":0}`,
 			},
+			Context: o.ctx,
 		},
 	)
-	if err != nil {
+	if errors.Is(err, ErrTranscoding) {
+		return o.awaitTranscode()
+	} else if errors.Is(err, ErrMediaNeedsReupload) {
+		return o.reupload(err)
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -815,16 +918,9 @@ func (o *UploadOptions) configure() (*Item, error) {
 	if res.Status != "ok" {
 		switch res.Message {
 		case "Transcode not finished yet.":
-			insta.InfoHandler(fmt.Errorf("%s, %s. Please wait.", res.Status, res.Message))
-			time.Sleep(6 * time.Second)
-			return o.configure()
+			return o.awaitTranscode()
 		case "media_needs_reupload":
-			insta.InfoHandler(fmt.Errorf("Instagram asks for the video to be reuploaded, please wait."))
-			err := o.postVideo()
-			if err != nil {
-				return nil, err
-			}
-			return o.configure()
+			return o.reupload(ErrMediaNeedsReupload)
 		default:
 			return nil, fmt.Errorf("invalid status, result: %s, %s", res.Status, res.Message)
 		}
@@ -832,72 +928,86 @@ func (o *UploadOptions) configure() (*Item, error) {
 	return &res.Media, nil
 }
 
+// maxTranscodeWaits is how often configure is retried, while Instagram is
+//   processing a video
+const maxTranscodeWaits = 10
+
+// awaitTranscode waits for Instagram to process the uploaded video, before
+//   calling configure again. ErrTranscoding is returned, if the video has not
+//   been processed after maxTranscodeWaits.
+func (o *UploadOptions) awaitTranscode() (*Item, error) {
+	if o.transcodeWaits >= maxTranscodeWaits {
+		return nil, fmt.Errorf("%w after %d attempts", ErrTranscoding, o.transcodeWaits+1)
+	}
+	o.transcodeWaits++
+	o.insta.InfoHandler(fmt.Errorf("%s. Please wait.", ErrTranscoding))
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	wait := o.transcodeWait
+	if wait == 0 {
+		wait = 6 * time.Second
+	}
+	if err := sleep(ctx, wait); err != nil {
+		return nil, err
+	}
+	return o.configure()
+}
+
+// reupload transfers the video again, if Instagram asks for it. Photos are
+//   not reuploaded, and videos only once, otherwise err is returned.
+func (o *UploadOptions) reupload(err error) (*Item, error) {
+	if o.video == nil || o.reuploaded {
+		return nil, err
+	}
+	o.reuploaded = true
+	o.insta.InfoHandler(fmt.Errorf("Instagram asks for the video to be reuploaded, please wait."))
+	if err := o.transferVideo(); err != nil {
+		return nil, err
+	}
+	return o.configure()
+}
+
 func (o *UploadOptions) uploadMultiStory() (*Item, error) {
 	o.videoGroupID = generateUUID()
 	o.segmentType = 3
 	o.mediaType = 2
 
-	// Validate videos
-	for _, vid := range o.Album {
-		buf, err := readFile(vid)
-		if err != nil {
-			return nil, err
-		}
-		o.bufAlbum = append(o.bufAlbum, buf)
-		t := http.DetectContentType(buf.Bytes())
-		if t != "video/mp4" {
-			return nil, ErrStoryBadMediaType
-		}
-
-		// Get video info
-		width, height, duration, err := getVideoInfo(buf.Bytes())
-		if err != nil {
-			return nil, err
-		}
-		o.width, o.height, o.duration = width, height, duration
-
-		if duration > 20000 {
-			return nil, ErrStoryMediaTooLong
-		}
-	}
-
 	s := make([]byte, 6)
 	cryptRand.Read(s)
 	suffix := fmt.Sprintf("_%X_Mixed_0", s)
 
 	// Upload Media
 	var item *Item
-	for i, buf := range o.bufAlbum {
+	for i, f := range o.files {
 		o.index = i
-		o.buf = buf
+		o.use(f)
 
-		width, height, duration, err := getVideoInfo(o.buf.Bytes())
-		if err != nil {
-			return nil, err
-		}
-		o.width, o.height, o.duration = width, height, duration
-
-		size := float64(len(o.buf.Bytes())) / 1000000.0
+		size := float64(o.size) / 1000000.0
 		o.insta.InfoHandler(
 			fmt.Sprintf(
 				"Uploading story video %d: duration: %ds, Size: %dx%d, %.2f Mb",
-				i+1, duration/1000, width, height, size,
+				i+1, o.duration/1000, o.width, o.height, size,
 			),
 		)
 
 		o.newUploadID()
 		o.waterfallID = o.uploadID + suffix
-		o.newSegmentName(buf.Len())
+		o.newSegmentName(o.size)
 
-		err = o.createRUploadParams()
+		err := o.createRUploadParams()
 		if err != nil {
 			return nil, err
 		}
 
-		err = o.segmentTransfer(buf.Bytes())
+		o.offset, o.segment, o.segments = 0, 1, 1
+		o.progress(UploadStarted, 0, nil)
+		err = o.uploadEntity(0, o.size)
 		if err != nil {
 			return nil, err
 		}
+		o.progress(UploadFinished, o.size, nil)
 
 		err = o.createVideoConfig()
 		if err != nil {
@@ -923,51 +1033,23 @@ func (o *UploadOptions) uploadVideo() error {
 	o.useXSharingIDs = true
 	o.newUploadID()
 
-	// Get video info
-	width, height, duration, err := getVideoInfo(o.buf.Bytes())
-	if err != nil {
-		return err
-	}
-	o.width, o.height, o.duration = width, height, duration
-
-	size := float64(len(o.buf.Bytes())) / 1000000.0
+	size := float64(o.size) / 1000000.0
 	o.insta.InfoHandler(
 		fmt.Sprintf(
-			"Upload video: duration: %ds, Size: %dx%d, %.2f Mb",
-			duration/1000, width, height, size,
+			"Upload video: duration: %ds, Size: %dx%d, %s %.2f fps, %.2f Mb",
+			o.duration/1000, o.width, o.height, o.video.Codec, o.video.FrameRate, size,
 		),
 	)
 
 	// Create Rupload header params
-	err = o.createRUploadParams()
+	err := o.createRUploadParams()
 	if err != nil {
 		return err
 	}
 
-	// If video size greater than twice the threshold, use segments
-	t := 1 << 22
-	if o.buf.Len() > 2*t {
-		err := o.segmentVideo(t)
-		if err != nil {
-			return err
-		}
-	} else {
-		// If not segmented, upload video directly
-		// Create unique upload id and name
-		rand := random(1000000000, 9999999999)
-		o.name = fmt.Sprintf("%s_0_%d", o.uploadID, rand)
-		o.waterfallID = generateUUID()
-
-		// Initialize the upload with a get request
-		err = o.postVideoGET()
-		if err != nil {
-			return err
-		}
-
-		err = o.postVideo()
-		if err != nil {
-			return err
-		}
+	err = o.transferVideo()
+	if err != nil {
+		return err
 	}
 
 	if o.Thumbnail != nil {
@@ -981,7 +1063,34 @@ func (o *UploadOptions) uploadVideo() error {
 	return err
 }
 
-func (o *UploadOptions) segmentVideo(t int) error {
+// transferVideo uploads the bytes of the video
+func (o *UploadOptions) transferVideo() error {
+	o.offset = 0
+
+	// If video size greater than twice the threshold, use segments
+	t := int64(1 << 22)
+	if o.size > 2*t {
+		return o.segmentVideo(t)
+	}
+
+	// If not segmented, upload video directly
+	// Create unique upload id and name
+	rand := random(1000000000, 9999999999)
+	o.name = fmt.Sprintf("%s_0_%d", o.uploadID, rand)
+	o.waterfallID = generateUUID()
+	o.streamID, o.segmentType = "", 0
+	o.segment, o.segments = 1, 1
+
+	o.progress(UploadStarted, 0, nil)
+	err := o.uploadEntity(0, o.size)
+	if err != nil {
+		return err
+	}
+	o.progress(UploadFinished, o.size, nil)
+	return nil
+}
+
+func (o *UploadOptions) segmentVideo(t int64) error {
 	o.waterfallID = toString(time.Now().Unix())
 
 	err := o.segmentPhase("start")
@@ -990,87 +1099,88 @@ func (o *UploadOptions) segmentVideo(t int) error {
 	}
 
 	segments := o.createSegments(t)
-	length := len(*segments)
+	o.segments = len(segments)
 	o.segmentType = 2
-	for i, segment := range *segments {
-		if i == length-1 {
+	o.progress(UploadStarted, 0, nil)
+	for i, length := range segments {
+		if i == o.segments-1 {
 			o.segmentType = 1
 		}
+		o.segment = i + 1
 
 		// Create new name for each request
-		o.newSegmentName(len(segment))
-
-		err = o.postVideoGET()
-		if err != nil {
-			return err
-		}
+		o.newSegmentName(length)
 
-		err = o.segmentTransfer(segment)
+		err = o.uploadEntity(o.offset, length)
 		if err != nil {
 			return err
 		}
-		o.offset += len(segment)
-		o.insta.InfoHandler(fmt.Sprintf("Uploaded video segment [%d/%d]", i+1, length))
-
+		o.offset += length
+		o.insta.InfoHandler(fmt.Sprintf("Uploaded video segment [%d/%d]", o.segment, o.segments))
+		o.progress(UploadTransferring, o.offset, nil)
 	}
 
 	err = o.segmentPhase("end")
-	return err
+	if err != nil {
+		return err
+	}
+	o.progress(UploadFinished, o.size, nil)
+	return nil
 }
 
-func (o *UploadOptions) newSegmentName(l int) {
+func (o *UploadOptions) newSegmentName(l int64) {
 	id := strings.ReplaceAll(generateUUID(), "-", "")
 	t := time.Now().Unix()
 	t = t - (t % 1000)
 	o.name = fmt.Sprintf("%s-0-%d-%d-%d", id, l, t, t)
 }
 
-func (o *UploadOptions) segmentTransfer(segment []byte) error {
-	insta := o.insta
-
-	headers := map[string]string{
-		"X-Entity-Name":              o.name,
-		"X-Entity-Type":              http.DetectContentType(o.buf.Bytes()),
-		"X-Entity-Length":            toString(len(segment)),
-		"X-Instagram-Rupload-Params": o.ruploadParams,
-		"Offset":                     "0",
-		"Segment-Start-Offset":       toString(o.offset),
-		"Content-type":               "application/octet-stream",
-		"Segment-Type":               toString(o.segmentType),
-		"X_fb_photo_waterfall_id":    o.waterfallID,
-	}
-	if o.streamID != "" {
-		headers["Stream-Id"] = o.streamID
-	}
-
-	// Upload video bytes
-	body, _, err := insta.sendRequest(
-		&reqOptions{
-			Endpoint:     fmt.Sprintf(urlUploadVideo, o.name),
-			OmitAPI:      true,
-			IsPost:       true,
-			DataBytes:    bytes.NewBuffer(segment),
-			ExtraHeaders: headers,
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	var result struct {
-		UploadID       string      `json:"upload_id"`
-		XsharingNonces interface{} `json:"xsharing_nonces"`
-		Status         string      `json:"status"`
-	}
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return err
-	}
-	if result.Status != "ok" {
-		return fmt.Errorf("unknown error, status: %s", result.Status)
+// uploadEntity uploads length bytes of the video from start, as the entity
+//   o.name. If the transfer is interrupted, it is resumed from the offset
+//   Instagram has acknowledged, up to ResumeAttempts times.
+func (o *UploadOptions) uploadEntity(start, length int64) error {
+	attempts := o.ResumeAttempts
+	if attempts == 0 {
+		attempts = 3
+	}
+	policy := o.insta.retryPolicy
+	if policy == nil {
+		policy = &DefaultRetryPolicy
+	}
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var interrupted error
+	for attempt := 0; ; attempt++ {
+		offset, err := o.postVideoGET()
+		if err == nil {
+			if offset < 0 || offset > length {
+				return fmt.Errorf("Invalid upload offset %d of %d bytes", offset, length)
+			}
+			if offset == length && length > 0 {
+				// the interrupted transfer has been received completely
+				return nil
+			}
+			if interrupted != nil {
+				o.insta.InfoHandler(fmt.Sprintf("Resuming video upload at %d/%d bytes: %s", start+offset, o.size, interrupted))
+				o.progress(UploadResuming, start+offset, interrupted)
+			}
+			err = o.postVideo(start, length, offset)
+		}
+		if err == nil || attempt >= attempts || !(IsRetryable(err) || isTransientErr(err)) {
+			return err
+		}
+		interrupted = err
+		wait, ok := policy.delay(attempt+1, RetryAfter(err))
+		if !ok {
+			return err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
 	}
-
-	return nil
 }
 
 func (o *UploadOptions) segmentPhase(phase string) error {
@@ -1089,6 +1199,7 @@ func (o *UploadOptions) segmentPhase(phase string) error {
 			OmitAPI:      true,
 			IsPost:       true,
 			ExtraHeaders: headers,
+			Context:      o.ctx,
 		},
 	)
 	if err != nil {
@@ -1111,19 +1222,69 @@ func (o *UploadOptions) segmentPhase(phase string) error {
 	return nil
 }
 
-func (o *UploadOptions) createSegments(t int) *[][]byte {
-	var segments [][]byte
-	for o.buf.Len() != 0 {
+func (o *UploadOptions) createSegments(t int64) []int64 {
+	var segments []int64
+	for rest := o.size; rest > 0; {
 		// For some reason the byte lengths the insta app uploads are never
 		//   the same, so adding a random difference to the segment sizes.
-		r := random(0, 10000)
+		r := int64(random(0, 10000))
+		n := t + r
 		if rand.Float64() < 0.65 {
-			segments = append(segments, o.buf.Next(t-r))
-		} else {
-			segments = append(segments, o.buf.Next(t+r))
+			n = t - r
+		}
+		if n > rest {
+			n = rest
+		}
+		segments = append(segments, n)
+		rest -= n
+	}
+	return segments
+}
+
+// progress reports the progress of a video upload to o.Progress
+func (o *UploadOptions) progress(phase UploadPhase, sent int64, err error) {
+	if o.Progress == nil {
+		return
+	}
+	o.Progress(UploadProgress{
+		Phase:    phase,
+		Sent:     sent,
+		Total:    o.size,
+		Segment:  o.segment,
+		Segments: o.segments,
+		Err:      err,
+	})
+}
+
+// use sets the file to upload next
+func (o *UploadOptions) use(f *uploadFile) {
+	o.buf, o.src, o.size = f.buf, f.src, f.size
+	o.video = f.video
+	o.width, o.height = f.width, f.height
+	o.duration = 0
+	if f.video != nil {
+		o.duration = int(f.video.Duration / time.Millisecond)
+	}
+}
+
+// readerAtSize returns f as io.ReaderAt, if it supports random access and its
+//   size is known.
+func readerAtSize(f io.Reader) (io.ReaderAt, int64, bool) {
+	r, ok := f.(io.ReaderAt)
+	if !ok {
+		return nil, 0, false
+	}
+	switch s := f.(type) {
+	case interface{ Size() int64 }:
+		return r, s.Size(), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := s.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, 0, false
 		}
+		return r, info.Size(), true
 	}
-	return &segments
+	return nil, 0, false
 }
 
 func readFile(f io.Reader) (*bytes.Buffer, error) {
@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -44,6 +45,11 @@ func (users *Users) SetInstagram(insta *Instagram) {
 //
 // returns false when list reach the end.
 func (users *Users) Next() bool {
+	return users.NextCtx(context.Background())
+}
+
+// NextCtx is like Next, but the request can be canceled with ctx.
+func (users *Users) NextCtx(ctx context.Context) bool {
 	if users.err != nil {
 		return false
 	}
@@ -59,6 +65,7 @@ func (users *Users) Next() bool {
 				"ig_sig_key_version": instaSigKeyVersion,
 				"rank_token":         insta.rankToken,
 			},
+			Context: ctx,
 		},
 	)
 	if err == nil {
@@ -515,6 +522,8 @@ func (user *User) Follow() error {
 			Endpoint: fmt.Sprintf(urlUserFollow, user.ID),
 			Query:    generateSignature(data),
 			IsPost:   true,
+			NoRetry:  true,
+			Action:   ActionFollow,
 		},
 	)
 	if err != nil {
@@ -555,6 +564,8 @@ func (user *User) Unfollow() error {
 			Endpoint: fmt.Sprintf(urlUserUnfollow, user.ID),
 			Query:    generateSignature(data),
 			IsPost:   true,
+			NoRetry:  true,
+			Action:   ActionFollow,
 		},
 	)
 	if err != nil {
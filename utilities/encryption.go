@@ -106,3 +106,20 @@ func EncryptPassword(password, pubKeyEncoded string, pubKeyVersion int, t string
 
 	return r, nil
 }
+
+// AESGCMDecrypt decrypts data encrypted by AESGCMEncrypt. encrypted is the
+//   encrypted data, followed by the authentication tag.
+func AESGCMDecrypt(key, iv, encrypted, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating cipher: %s", err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating gcm: %s", err)
+	}
+	if len(iv) != aesgcm.NonceSize() {
+		return nil, errors.New("invalid iv size")
+	}
+	return aesgcm.Open(nil, iv, encrypted, additionalData)
+}
@@ -3,10 +3,8 @@ package goinsta
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
 	"image"
-	"math"
 
 	// Required for getImageDimensionFromReader in jpg and png format
 	"fmt"
@@ -94,39 +92,6 @@ func getImageSize(b []byte) (int, int, error) {
 	return image.Width, image.Height, nil
 }
 
-func getVideoInfo(b []byte) (height, width, duration int, err error) {
-	keys := []string{"moov", "trak", "stbl", "avc1"}
-	height, err = read16(b, keys, 24)
-	if err != nil {
-		return
-	}
-	width, err = read16(b, keys, 26)
-	if err != nil {
-		return
-	}
-
-	duration, err = getMP4Duration(b)
-	if err != nil {
-		return
-	}
-
-	return
-}
-
-func getMP4Duration(b []byte) (int, error) {
-	keys := []string{"moov", "mvhd"}
-	timescale, err := read32(b, keys, 12)
-	if err != nil {
-		return -1, err
-	}
-	length, err := read32(b, keys, 12+4)
-	if err != nil {
-		return -1, err
-	}
-
-	return int(math.Floor(float64(length) / float64(timescale) * 1000)), nil
-}
-
 func getTimeOffset() string {
 	_, offset := time.Now().Zone()
 	return strconv.Itoa(offset)
@@ -141,12 +106,12 @@ func jazoest(str string) string {
 	return "2" + strconv.Itoa(s)
 }
 
-func createUserAgent(device Device) string {
+func createUserAgent(device Device, app AppVersion) string {
 	// Instagram 195.0.0.31.123 Android (28/9; 560dpi; 1440x2698; LGE/lge; LG-H870DS; lucye; lucye; en_GB; 302733750)
 	// Instagram 195.0.0.31.123 Android (28/9; 560dpi; 1440x2872; Genymotion/Android; Samsung Galaxy S10; vbox86p; vbox86; en_US; 302733773)  # version_code: 302733773
 	// Instagram 195.0.0.31.123 Android (30/11; 560dpi; 1440x2898; samsung; SM-G975F; beyond2; exynos9820; en_US; 302733750)
 	return fmt.Sprintf("Instagram %s Android (%d/%d; %s; %s; %s; %s; %s; %s; %s; %s)",
-		appVersion,
+		app.Version,
 		device.AndroidVersion,
 		device.AndroidRelease,
 		device.ScreenDpi,
@@ -156,7 +121,7 @@ func createUserAgent(device Device) string {
 		device.CodeName,
 		device.Chipset,
 		locale,
-		appVersionCode,
+		app.Code,
 	)
 }
 
@@ -218,36 +183,6 @@ func MergeMapS(one map[string]string, extra ...map[string]string) map[string]str
 	return one
 }
 
-func read16(b []byte, keys []string, offset int) (int, error) {
-	start, err := getStartByte(b, keys, offset)
-	if err != nil {
-		return -1, nil
-	}
-	r := binary.BigEndian.Uint16(b[start+offset:])
-	return int(r), nil
-}
-
-func read32(b []byte, keys []string, offset int) (int, error) {
-	start, err := getStartByte(b, keys, offset)
-	if err != nil {
-		return -1, nil
-	}
-	r := binary.BigEndian.Uint32(b[start+offset:])
-	return int(r), nil
-}
-
-func getStartByte(b []byte, keys []string, offset int) (int, error) {
-	start := 0
-	for _, key := range keys {
-		n := bytes.Index(b[start:], []byte(key))
-		if n == -1 {
-			return -1, ErrByteIndexNotFound
-		}
-		start += n + len(key)
-	}
-	return start, nil
-}
-
 func getSupCap() (string, error) {
 	query := []trayRequest{
 		{"SUPPORTED_SDK_VERSIONS", supportedSdkVersions},
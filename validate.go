@@ -0,0 +1,391 @@
+package goinsta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of uploads, checked by UploadOptions.Validate
+const (
+	MaxCaptionLength = 2200
+	MaxHashtags      = 30
+	MaxMentions      = 20
+	// MaxUserTags is the limit of tagged users per photo or video
+	MaxUserTags      = 20
+	MinCarouselItems = 2
+	MaxCarouselItems = 10
+
+	MaxImageFileSize = 8 << 20
+	MaxVideoFileSize = 650 << 20
+	MaxIGTVFileSize  = 3600 << 20
+
+	MinVideoDuration     = 3 * time.Second
+	MaxFeedVideoDuration = 60 * time.Second
+	// MaxStoryVideoDuration is the limit of story videos. Instagram shows 15
+	//   seconds per story, and accepts videos which are slightly longer.
+	MaxStoryVideoDuration = 20 * time.Second
+	MinIGTVDuration       = time.Minute
+	MaxIGTVDuration       = time.Hour
+)
+
+// IGTV videos can be vertical or horizontal
+const (
+	MinIGTVAspectRatio = 9.0 / 16.0
+	MaxIGTVAspectRatio = 16.0 / 9.0
+)
+
+// aspectRatioTolerance allows for rounding of the image and video sizes
+const aspectRatioTolerance = 0.01
+
+var (
+	hashtagRegex = regexp.MustCompile(`#[\pL\pN_]+`)
+	mentionRegex = regexp.MustCompile(`@[\pL\pN_.]+`)
+)
+
+// Violation is a limit of Instagram, which an upload does not meet
+type Violation struct {
+	// Field is the option violating the limit, e.g. "Caption" or "Album[1]"
+	Field string
+	// Err describes the violation, and wraps one of the upload errors of this
+	//   package, e.g. ErrTooManyHashtags.
+	Err error
+}
+
+func (v Violation) Error() string {
+	return v.Field + ": " + v.Err.Error()
+}
+
+// ValidationError is returned by UploadOptions.Validate, and lists all
+//   violations of an upload. It matches the errors of all violations with
+//   errors.Is, e.g.:
+//
+//   if errors.Is(err, goinsta.ErrTooManyHashtags) {
+//     // shorten the caption
+//   }
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return "Invalid upload: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether one of the violations matches target
+func (e *ValidationError) Is(target error) bool {
+	for _, v := range e.Violations {
+		if errors.Is(v.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadFile is a file of an upload, read by UploadOptions.load
+type uploadFile struct {
+	// raw is the original file, if it has been read into memory. buf is the
+	//   file to upload, after preprocessing.
+	raw         []byte
+	buf         *bytes.Buffer
+	src         io.ReaderAt
+	size        int64
+	contentType string
+	width       int
+	height      int
+	// video is set for mp4 videos, or err if it could not be read
+	video *VideoInfo
+	err   error
+}
+
+// Validate checks the upload against the limits of Instagram, before any byte
+//   is sent. It is called by Upload, and can be called before to check the
+//   options. The files are read once, and kept for the upload. Images are
+//   processed again, if Preprocess has been changed since the last call.
+//
+// If the upload does not meet the limits, a *ValidationError with all
+//   violations is returned. Errors reading the files are returned as is.
+func (o *UploadOptions) Validate() error {
+	if err := o.load(); err != nil {
+		return err
+	}
+
+	var violations []Violation
+	add := func(field string, err error) {
+		violations = append(violations, Violation{Field: field, Err: err})
+	}
+
+	isCarousel := len(o.Album) > 0 && !o.IsStory
+	switch {
+	case len(o.files) == 0:
+		add("File", ErrNoUploadFile)
+	case o.IsIGTV && (o.IsStory || len(o.Album) > 0):
+		add("IsIGTV", errors.New("IGTV videos can not be posted as story or carousel"))
+	case isCarousel && len(o.Album) > MaxCarouselItems:
+		add("Album", ErrCarouselMediaLimit)
+	case isCarousel && len(o.Album) < MinCarouselItems:
+		add("Album", fmt.Errorf("%w, got %d", ErrCarouselTooFewItems, len(o.Album)))
+	}
+	if o.IsIGTV && o.Title == "" {
+		add("Title", errors.New("IGTV videos need a title"))
+	}
+
+	for i, f := range o.files {
+		field := "File"
+		if len(o.Album) > 0 {
+			field = fmt.Sprintf("Album[%d]", i)
+		}
+		for _, err := range o.validateFile(f) {
+			add(field, err)
+		}
+	}
+
+	if n := utf8.RuneCountInString(o.Caption); n > MaxCaptionLength {
+		add("Caption", fmt.Errorf("%w, got %d", ErrCaptionTooLong, n))
+	}
+	if n := len(hashtagRegex.FindAllString(o.Caption, -1)); n > MaxHashtags {
+		add("Caption", fmt.Errorf("%w, got %d", ErrTooManyHashtags, n))
+	}
+	if n := len(mentionRegex.FindAllString(o.Caption, -1)); n > MaxMentions {
+		add("Caption", fmt.Errorf("%w, got %d", ErrTooManyMentions, n))
+	}
+
+	if o.UserTags != nil {
+		for _, err := range validateUserTags(*o.UserTags) {
+			add("UserTags", err)
+		}
+	}
+	if o.AlbumTags != nil {
+		if len(*o.AlbumTags) != len(o.Album) {
+			add("AlbumTags", fmt.Errorf("%w: one list of tags per album item is needed, got %d for %d items",
+				ErrInvalidUserTag, len(*o.AlbumTags), len(o.Album)))
+		}
+		for i, tags := range *o.AlbumTags {
+			for _, err := range validateUserTags(tags) {
+				add(fmt.Sprintf("AlbumTags[%d]", i), err)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// validateFile checks a file against the limits of the destination
+func (o *UploadOptions) validateFile(f *uploadFile) []error {
+	var errs []error
+	isVideo := f.contentType == "video/mp4"
+	switch {
+	case !isVideo && f.contentType != "image/jpeg":
+		return append(errs, fmt.Errorf("%w, got %s", ErrInvalidFormat, f.contentType))
+	case !isVideo && o.IsStory && len(o.Album) > 0:
+		return append(errs, ErrStoryBadMediaType)
+	case !isVideo && o.IsIGTV:
+		return append(errs, fmt.Errorf("%w: IGTV uploads have to be videos", ErrInvalidFormat))
+	case f.err != nil:
+		return append(errs, f.err)
+	}
+
+	maxSize := int64(MaxImageFileSize)
+	if o.IsIGTV {
+		maxSize = MaxIGTVFileSize
+	} else if isVideo {
+		maxSize = MaxVideoFileSize
+	}
+	if f.size > maxSize {
+		errs = append(errs, fmt.Errorf("%w: %.1f MB, at most %d MB", ErrFileTooLarge, float64(f.size)/(1<<20), maxSize>>20))
+	}
+
+	if f.width > 0 && f.height > 0 && !o.IsStory {
+		minRatio, maxRatio := MinFeedAspectRatio, MaxFeedAspectRatio
+		if o.IsIGTV {
+			minRatio, maxRatio = MinIGTVAspectRatio, MaxIGTVAspectRatio
+		}
+		ratio := float64(f.width) / float64(f.height)
+		if ratio < minRatio-aspectRatioTolerance || ratio > maxRatio+aspectRatioTolerance {
+			errs = append(errs, fmt.Errorf("%w: %dx%d is not between %.2f and %.2f, set Preprocess to fit images",
+				ErrAspectRatio, f.width, f.height, minRatio, maxRatio))
+		}
+	}
+
+	if !isVideo {
+		return errs
+	}
+	if err := f.video.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	minDuration, maxDuration := MinVideoDuration, MaxFeedVideoDuration
+	switch {
+	case o.IsIGTV:
+		minDuration, maxDuration = MinIGTVDuration, MaxIGTVDuration
+	case o.IsStory:
+		minDuration, maxDuration = 0, MaxStoryVideoDuration
+	}
+	d := f.video.Duration
+	switch {
+	case o.IsStory && d > maxDuration:
+		errs = append(errs, fmt.Errorf("%w, got %s", ErrStoryMediaTooLong, d))
+	case d > maxDuration:
+		errs = append(errs, fmt.Errorf("%w: %s, at most %s", ErrVideoTooLong, d, maxDuration))
+	case d < minDuration:
+		errs = append(errs, fmt.Errorf("%w: %s, at least %s", ErrVideoTooShort, d, minDuration))
+	}
+	return errs
+}
+
+func validateUserTags(tags []UserTag) []error {
+	var errs []error
+	if len(tags) > MaxUserTags {
+		errs = append(errs, fmt.Errorf("%w, got %d", ErrTooManyUserTags, len(tags)))
+	}
+	for i, tag := range tags {
+		if tag.User == nil {
+			errs = append(errs, fmt.Errorf("%w: tag %d has no user", ErrInvalidUserTag, i))
+		}
+	}
+	return errs
+}
+
+// load reads the files of the upload, and preprocesses images, if enabled.
+//   The readers are only read once, the original bytes of the images are
+//   kept, so that they are processed again if the options have been changed
+//   since the last call, e.g. Preprocess has been set after Validate failed.
+func (o *UploadOptions) load() error {
+	opts := loadOptions{isStory: o.IsStory, isAlbum: len(o.Album) > 0}
+	if o.Preprocess != nil {
+		p := *o.Preprocess
+		opts.preprocess = &p
+	}
+	if o.files != nil && reflect.DeepEqual(opts, o.loaded) {
+		return nil
+	}
+
+	if o.sources == nil {
+		readers := o.Album
+		if len(readers) == 0 && o.File != nil {
+			readers = []io.Reader{o.File}
+		}
+		sources := make([]*uploadFile, 0, len(readers))
+		for _, r := range readers {
+			f, err := readUploadFile(r)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, f)
+		}
+		o.sources = sources
+	}
+
+	// Images are fit into the aspect ratio of the destination, and the images
+	//   of a carousel into the aspect ratio of the first one
+	minRatio, maxRatio := MinFeedAspectRatio, MaxFeedAspectRatio
+	if o.IsStory {
+		minRatio, maxRatio = StoryAspectRatio, StoryAspectRatio
+	}
+
+	files := make([]*uploadFile, 0, len(o.sources))
+	for _, src := range o.sources {
+		f, err := o.processFile(src, minRatio, maxRatio)
+		if err != nil {
+			return err
+		}
+		if o.Preprocess != nil && f.contentType == "image/jpeg" && len(o.Album) > 0 && f.height > 0 {
+			minRatio = float64(f.width) / float64(f.height)
+			maxRatio = minRatio
+		}
+		files = append(files, f)
+	}
+	o.files = files
+	o.loaded = opts
+	return nil
+}
+
+// loadOptions are the options the files of an upload have been processed with
+type loadOptions struct {
+	preprocess *ImageOptions
+	isStory    bool
+	isAlbum    bool
+}
+
+// detectContentType detects the content type of a file by its first bytes.
+//   Videos are detected by their ftyp box, as http.DetectContentType only
+//   knows some of the brands of MP4 files, and no QuickTime (MOV) files.
+//   Their content type is always video/mp4.
+func detectContentType(head []byte) string {
+	if brand, ok := ftypBrand(head); ok && !isHEIF(brand) {
+		return "video/mp4"
+	}
+	return http.DetectContentType(head)
+}
+
+// readUploadFile reads a file. Videos are streamed from files with random
+//   access, everything else is read into memory, and kept as raw bytes.
+func readUploadFile(r io.Reader) (*uploadFile, error) {
+	f := &uploadFile{}
+	if src, size, ok := readerAtSize(r); ok {
+		head := make([]byte, 512)
+		n, err := src.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if t := detectContentType(head[:n]); t == "video/mp4" {
+			f.src, f.size, f.contentType = src, size, t
+			return f, nil
+		}
+	}
+
+	buf, err := readFile(r)
+	if err != nil {
+		return nil, err
+	}
+	f.raw = buf.Bytes()
+	f.contentType = detectContentType(f.raw)
+	return f, nil
+}
+
+// processFile preprocesses a file read by readUploadFile, if enabled, and
+//   reads its size and video info.
+func (o *UploadOptions) processFile(src *uploadFile, minRatio, maxRatio float64) (*uploadFile, error) {
+	f := &uploadFile{src: src.src, size: src.size, contentType: src.contentType}
+	if src.raw != nil {
+		b := src.raw
+		if o.Preprocess != nil && f.contentType != "video/mp4" {
+			var err error
+			b, err = ProcessImage(b, *o.Preprocess, minRatio, maxRatio)
+			if err != nil {
+				return nil, err
+			}
+			f.contentType = "image/jpeg"
+		}
+		// the buffer is drained by the upload, it must not share the raw bytes
+		buf := bytes.NewBuffer(append([]byte{}, b...))
+		f.buf, f.src, f.size = buf, bytes.NewReader(buf.Bytes()), int64(buf.Len())
+	}
+
+	switch f.contentType {
+	case "image/jpeg":
+		w, h, err := getImageSize(f.buf.Bytes())
+		if err != nil {
+			f.err = fmt.Errorf("%w: %s", ErrInvalidFormat, err)
+		}
+		f.width, f.height = w, h
+	case "video/mp4":
+		f.video, f.err = ReadVideoInfo(f.src, f.size)
+		if f.err == nil {
+			f.width, f.height = f.video.Width, f.video.Height
+		}
+	}
+	return f, nil
+}